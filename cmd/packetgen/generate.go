@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/rotisserie/eris"
+	"go/format"
+	"sort"
+	"strings"
+)
+
+type (
+	// primitive is a spec type that maps onto one of the packet package's field types
+	primitive struct {
+		goType string
+		// wrapper is the packet type with ReadFrom and WriteTo, empty when goType has them itself
+		wrapper string
+		pktType string
+	}
+
+	generator struct {
+		spec    *Spec
+		buf     bytes.Buffer
+		imports map[string]bool
+	}
+
+	// packetUse is everywhere a generated struct is registered
+	packetUse struct {
+		State     string
+		Direction string
+		ID        string
+	}
+)
+
+var primitives = map[string]primitive{
+	"bool":     {goType: "bool", wrapper: "Boolean"},
+	"i8":       {goType: "int8", wrapper: "Byte"},
+	"u8":       {goType: "uint8", wrapper: "UnsignedByte"},
+	"i16":      {goType: "int16", wrapper: "Short"},
+	"u16":      {goType: "uint16", wrapper: "UnsignedShort"},
+	"i32":      {goType: "int32", wrapper: "Int"},
+	"i64":      {goType: "int64", wrapper: "Long"},
+	"f32":      {goType: "float32", wrapper: "Float"},
+	"f64":      {goType: "float64", wrapper: "Double"},
+	"varint":   {goType: "int32", wrapper: "VarInt", pktType: "VarInt"},
+	"varlong":  {goType: "int64", wrapper: "VarLong", pktType: "VarLong"},
+	"string":   {goType: "string", wrapper: "String"},
+	"UUID":     {goType: "uuid.UUID", wrapper: "UUID"},
+	"position": {goType: "Position"},
+	"angle":    {goType: "Angle"},
+}
+
+// Generate writes the Go source for a spec, source is the spec's file name for the header
+func Generate(spec *Spec, pkg, source string) ([]byte, error) {
+	g := &generator{
+		spec:    spec,
+		imports: map[string]bool{"io": true},
+	}
+	structs, uses := g.structs()
+
+	g.constants()
+	g.types(structs, uses)
+	g.registers()
+	for _, s := range structs {
+		g.marshal(s)
+		g.unmarshal(s)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by packetgen from %v; DO NOT EDIT.\n\n", source)
+	fmt.Fprintf(&out, "package %v\n\n", pkg)
+	out.WriteString("import (\n")
+	imports := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		imports = append(imports, path)
+	}
+	sort.Strings(imports)
+	for _, path := range imports {
+		fmt.Fprintf(&out, "%q\n", path)
+	}
+	out.WriteString(")\n\n")
+	out.Write(g.buf.Bytes())
+
+	formatted, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, eris.Wrap(err, "generated code doesn't compile")
+	}
+	return formatted, nil
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// structs lists every struct to generate once, packets in spec order then the shared types
+func (g *generator) structs() ([]StructSpec, map[string][]packetUse) {
+	var structs []StructSpec
+	uses := map[string][]packetUse{}
+	for _, state := range g.spec.states() {
+		for _, direction := range directions(state.Spec) {
+			for _, packet := range direction.packets {
+				if _, ok := uses[packet.Name]; !ok {
+					structs = append(structs, packet.StructSpec)
+				}
+				uses[packet.Name] = append(uses[packet.Name], packetUse{
+					State:     state.State,
+					Direction: direction.name,
+					ID:        packet.ID,
+				})
+			}
+		}
+	}
+	return append(structs, g.spec.Types...), uses
+}
+
+type directionPackets struct {
+	name    string
+	packets []PacketSpec
+}
+
+func directions(state StateSpec) []directionPackets {
+	return []directionPackets{
+		{name: "Serverbound", packets: state.ToServer},
+		{name: "Clientbound", packets: state.ToClient},
+	}
+}
+
+func (g *generator) constants() {
+	g.printf("// Packet IDs of protocol %v (%v)\n", g.spec.Protocol, g.spec.Version)
+	g.printf("const (\n")
+	for _, state := range g.spec.states() {
+		for _, direction := range directions(state.Spec) {
+			for _, packet := range direction.packets {
+				g.printf("%v%vID VarInt = %v\n", state.State, packet.Name, packet.ID)
+			}
+		}
+	}
+	g.printf(")\n\n")
+}
+
+func (g *generator) types(structs []StructSpec, uses map[string][]packetUse) {
+	g.printf("type (\n")
+	for i, s := range structs {
+		if i > 0 {
+			g.printf("\n")
+		}
+		if packetUses, ok := uses[s.Name]; ok {
+			var where []string
+			for _, use := range packetUses {
+				where = append(where, fmt.Sprintf("%v %v", use.Direction, use.State))
+			}
+			g.printf("// %v is a %v packet\n", s.Name, strings.Join(where, " and "))
+		}
+		if len(s.Fields) == 0 {
+			g.printf("%v struct{}\n", s.Name)
+			continue
+		}
+		g.printf("%v struct {\n", s.Name)
+		for _, field := range s.Fields {
+			if field.Doc != "" {
+				g.printf("// %v\n", field.Doc)
+			}
+			g.printf("%v %v%v\n", field.Name, g.goType(field), fieldTags(field))
+		}
+		g.printf("}\n")
+	}
+	g.printf(")\n\n")
+}
+
+func (g *generator) goType(field FieldSpec) string {
+	switch field.Type {
+	case "buffer":
+		return "[]byte"
+	case "array":
+		return "[]" + g.elementType(field.Of)
+	case "nbt":
+		g.imports["minecraftServer/nbt"] = true
+		return field.GoType
+	}
+	return g.elementType(field.Type)
+}
+
+func (g *generator) elementType(typ string) string {
+	if p, ok := primitives[typ]; ok {
+		if p.goType == "uuid.UUID" {
+			g.imports["github.com/google/uuid"] = true
+		}
+		return p.goType
+	}
+	return typ
+}
+
+func fieldTags(field FieldSpec) string {
+	var tags []string
+	pktType := primitives[field.Type].pktType
+	if field.Type == "array" {
+		pktType = primitives[field.Of].pktType
+	}
+	if field.Type == "nbt" {
+		pktType = "nbt"
+	}
+	if pktType != "" {
+		tags = append(tags, fmt.Sprintf(`pkt_type:"%v"`, pktType))
+	}
+	if field.CountField != "" {
+		tags = append(tags, fmt.Sprintf(`pkt_len:"%v"`, field.CountField))
+	}
+	if field.Optional != "" {
+		tags = append(tags, fmt.Sprintf(`pkt_opt:"%v"`, field.Optional))
+	}
+	if len(tags) == 0 {
+		return ""
+	}
+	return " `" + strings.Join(tags, " ") + "`"
+}
+
+func (g *generator) registers() {
+	for _, state := range g.spec.states() {
+		g.printf("// register%v adds the %v packets of protocol %v\n", state.State, state.State, g.spec.Protocol)
+		g.printf("func register%v(r *Registry) {\n", state.State)
+		for _, direction := range directions(state.Spec) {
+			for _, packet := range direction.packets {
+				g.printf("r.Register(%v, %v, %v%vID, %v{})\n", state.State, direction.name, state.State, packet.Name, packet.Name)
+			}
+		}
+		g.printf("}\n\n")
+	}
+}
+
+func (g *generator) marshal(s StructSpec) {
+	g.printf("// MarshalPacket writes the fields in order without reflection\n")
+	g.printf("func (p *%v) MarshalPacket(w io.Writer) error {\n", s.Name)
+	for _, field := range s.Fields {
+		if field.Optional != "" {
+			g.printf("if p.%v {\n", field.Optional)
+		}
+		expr := "p." + field.Name
+		switch field.Type {
+		case "buffer":
+			g.printf("if _, err := w.Write(%v); err != nil {\nreturn err\n}\n", expr)
+		case "array":
+			g.printf("for i := range %v {\n", expr)
+			g.writeValue(field.Of, expr+"[i]")
+			g.printf("}\n")
+		case "nbt":
+			g.printf("if err := nbt.NewEncoder(w).Encode(&%v); err != nil {\nreturn err\n}\n", expr)
+		default:
+			g.writeValue(field.Type, expr)
+		}
+		if field.Optional != "" {
+			g.printf("}\n")
+		}
+	}
+	g.printf("return nil\n}\n\n")
+}
+
+func (g *generator) writeValue(typ, expr string) {
+	p, ok := primitives[typ]
+	switch {
+	case !ok:
+		g.printf("if err := %v.MarshalPacket(w); err != nil {\nreturn err\n}\n", expr)
+	case p.wrapper == "":
+		g.printf("if _, err := %v.WriteTo(w); err != nil {\nreturn err\n}\n", expr)
+	default:
+		g.printf("if _, err := %v(%v).WriteTo(w); err != nil {\nreturn err\n}\n", p.wrapper, expr)
+	}
+}
+
+func (g *generator) unmarshal(s StructSpec) {
+	g.printf("// UnmarshalPacket reads the fields in order without reflection\n")
+	g.printf("func (p *%v) UnmarshalPacket(r io.Reader) error {\n", s.Name)
+	for _, field := range s.Fields {
+		if field.Optional != "" {
+			g.printf("if p.%v {\n", field.Optional)
+		}
+		expr := "p." + field.Name
+		switch field.Type {
+		case "buffer":
+			if field.CountField == "" {
+				g.printf("data, err := io.ReadAll(r)\nif err != nil {\nreturn err\n}\n%v = data\n", expr)
+				break
+			}
+			g.checkCount(s, field)
+			g.printf("if err := readBuffer(r, &%v, int64(p.%v)); err != nil {\nreturn err\n}\n", expr, field.CountField)
+		case "array":
+			g.checkCount(s, field)
+			// The count is only known to fit in a frame, so the slice grows as elements are actually read
+			elementType := g.elementType(field.Of)
+			g.printf("%v = make([]%v, 0, preallocated(int64(p.%v)))\n", expr, elementType, field.CountField)
+			g.printf("for i := 0; i < int(p.%v); i++ {\nvar element %v\n", field.CountField, elementType)
+			g.readValue(field.Of, "element")
+			g.printf("%v = append(%v, element)\n}\n", expr, expr)
+		case "nbt":
+			g.printf("if err := nbt.NewDecoder(r).Decode(&%v); err != nil {\nreturn err\n}\n", expr)
+		default:
+			g.readValue(field.Type, expr)
+			if field.MaxLength != "" {
+				g.printf("if err := checkStringLength(\"%v.%v\", %v, %v); err != nil {\nreturn err\n}\n",
+					s.Name, field.Name, expr, field.MaxLength)
+			}
+		}
+		if field.Optional != "" {
+			g.printf("}\n")
+		}
+	}
+	g.printf("return nil\n}\n\n")
+}
+
+// checkCount rejects counts that are negative or couldn't fit in a packet before anything is allocated for them
+func (g *generator) checkCount(s StructSpec, field FieldSpec) {
+	g.printf("if err := checkLength(\"%v.%v\", int64(p.%v), MaxFrameSize); err != nil {\nreturn err\n}\n",
+		s.Name, field.Name, field.CountField)
+}
+
+func (g *generator) readValue(typ, expr string) {
+	p, ok := primitives[typ]
+	switch {
+	case !ok:
+		g.printf("if err := %v.UnmarshalPacket(r); err != nil {\nreturn err\n}\n", expr)
+	case p.wrapper == "":
+		g.printf("if _, err := %v.ReadFrom(r); err != nil {\nreturn err\n}\n", expr)
+	default:
+		// The field's type has the same underlying type as the wrapper, so it can be read in place
+		g.printf("if _, err := (*%v)(&%v).ReadFrom(r); err != nil {\nreturn err\n}\n", p.wrapper, expr)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGeneratedCodeIsUpToDate(t *testing.T) {
+	file, err := os.Open("../../packet/protocol.json")
+	assert.NoError(t, err)
+	defer file.Close()
+	spec, err := ReadSpec(file)
+	assert.NoError(t, err)
+
+	generated, err := Generate(spec, "packet", "protocol.json")
+	assert.NoError(t, err)
+	committed, err := os.ReadFile("../../packet/packets_gen.go")
+	assert.NoError(t, err)
+	assert.Equal(t, string(committed), string(generated), "run go generate in packet")
+}
+
+func TestGenerate(t *testing.T) {
+	spec, err := ReadSpec(strings.NewReader(`{
+		"protocol": 1,
+		"version": "test",
+		"play": {"toClient": [{"id": "0x0A", "name": "Example", "fields": [
+			{"name": "Count", "type": "varint"},
+			{"name": "Names", "type": "array", "of": "string", "countField": "Count"},
+			{"name": "Title", "type": "string", "maxLength": "32"},
+			{"name": "HasData", "type": "bool"},
+			{"name": "Data", "type": "buffer", "optional": "HasData", "doc": "Data runs to the end of the packet"}
+		]}]}
+	}`))
+	assert.NoError(t, err)
+	generated, err := Generate(spec, "example", "example.json")
+	assert.NoError(t, err)
+
+	source := string(generated)
+	assert.True(t, strings.HasPrefix(source, "// Code generated by packetgen from example.json; DO NOT EDIT."))
+	assert.Contains(t, source, "PlayExampleID VarInt = 0x0A")
+	assert.Contains(t, source, "[]string `pkt_len:\"Count\"`")
+	assert.Contains(t, source, "// Data runs to the end of the packet\n\t\tData []byte `pkt_opt:\"HasData\"`")
+	assert.Contains(t, source, "r.Register(Play, Clientbound, PlayExampleID, Example{})")
+	assert.Contains(t, source, "if p.HasData {\n\t\tdata, err := io.ReadAll(r)")
+	assert.Contains(t, source, "if err := checkLength(\"Example.Names\", int64(p.Count), MaxFrameSize); err != nil {")
+	assert.Contains(t, source, "p.Names = make([]string, 0, preallocated(int64(p.Count)))")
+	assert.Contains(t, source, "if err := checkStringLength(\"Example.Title\", p.Title, 32); err != nil {")
+	assert.NotContains(t, source, "github.com/google/uuid")
+}
+
+func TestReadSpecRejects(t *testing.T) {
+	tests := []struct {
+		spec string
+		err  string
+	}{
+		{
+			spec: `{"play": {"toClient": [{"id": "0x00", "name": "A", "fields": []}, {"id": "0x00", "name": "B", "fields": []}]}}`,
+			err:  "A and B share ID 0x00 in Play",
+		},
+		{
+			spec: `{"play": {"toClient": [{"id": "zero", "name": "A", "fields": []}]}}`,
+			err:  "invalid ID 'zero' for A",
+		},
+		{
+			spec: `{"play": {"toClient": [{"id": "0x00", "name": "A", "fields": [{"name": "X", "type": "i128"}]}]}}`,
+			err:  "invalid field A.X: unknown type 'i128'",
+		},
+		{
+			spec: `{"play": {"toClient": [{"id": "0x00", "name": "A", "fields": [{"name": "X", "type": "array", "of": "string"}]}]}}`,
+			err:  "invalid field A.X: arrays need a countField",
+		},
+		{
+			spec: `{"play": {"toClient": [{"id": "0x00", "name": "A", "fields": [{"name": "X", "type": "buffer", "countField": "Len"}, {"name": "Len", "type": "varint"}]}]}}`,
+			err:  "invalid field A.X: countField 'Len' has to be an earlier varint or i32",
+		},
+		{
+			spec: `{"play": {"toClient": [{"id": "0x00", "name": "A", "fields": [{"name": "X", "type": "string", "optional": "Y"}]}]}}`,
+			err:  "invalid field A.X: optional 'Y' has to be an earlier bool",
+		},
+		{
+			spec: `{"login": {"toClient": [{"id": "0x00", "name": "A", "fields": []}]}, "play": {"toClient": [{"id": "0x00", "name": "A", "fields": [{"name": "X", "type": "bool"}]}]}}`,
+			err:  "packet 'A' is declared twice with different fields",
+		},
+		{
+			spec: `{"play": {"toClient": [{"id": "0x00", "name": "A", "fields": [{"name": "X", "type": "nbt"}]}]}}`,
+			err:  "invalid field A.X: nbt fields need a goType",
+		},
+		{
+			spec: `{"play": {"toClient": [{"id": "0x00", "name": "A", "fields": [{"name": "X", "type": "varint", "maxLength": "16"}]}]}}`,
+			err:  "invalid field A.X: only strings have a maxLength",
+		},
+		{
+			spec: `{"play": {"toClient": [{"id": "0x00", "name": "A", "fields": [{"name": "X", "type": "string", "maxLength": "0"}]}]}}`,
+			err:  "invalid field A.X: maxLength '0' has to be a positive number or a constant",
+		},
+		{
+			spec: `{"play": {"toClient": [{"id": "0x00", "name": "A", "fields": [{"name": "X", "type": "string", "maxLength": "16 + 1"}]}]}}`,
+			err:  "invalid field A.X: maxLength '16 + 1' has to be a positive number or a constant",
+		},
+	}
+	for _, test := range tests {
+		_, err := ReadSpec(strings.NewReader(test.spec))
+		if assert.Error(t, err, test.spec) {
+			assert.Contains(t, err.Error(), test.err)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+// Command packetgen generates the packet structs, their IDs, registry and reflection free marshalling from a
+// protocol spec. It's run by go generate in the packet package:
+//
+//	go run ../cmd/packetgen -spec protocol.json -out packets_gen.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	specPath := flag.String("spec", "protocol.json", "protocol spec to generate from")
+	out := flag.String("out", "packets_gen.go", "file to write the generated code to")
+	pkg := flag.String("package", "packet", "package of the generated code")
+	flag.Parse()
+
+	if err := run(*specPath, *out, *pkg); err != nil {
+		fmt.Fprintln(os.Stderr, "packetgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(specPath, out, pkg string) error {
+	file, err := os.Open(specPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	spec, err := ReadSpec(file)
+	if err != nil {
+		return err
+	}
+	source, err := Generate(spec, pkg, filepath.Base(specPath))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(out, source, 0644)
+}
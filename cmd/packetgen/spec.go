@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/rotisserie/eris"
+	"go/token"
+	"io"
+	"strconv"
+)
+
+type (
+	// Spec describes one protocol version, laid out like minecraft-data's protocol.json: packets are grouped by
+	// state, then by direction
+	Spec struct {
+		Protocol    int32        `json:"protocol"`
+		Version     string       `json:"version"`
+		Types       []StructSpec `json:"types"`
+		Handshaking StateSpec    `json:"handshaking"`
+		Status      StateSpec    `json:"status"`
+		Login       StateSpec    `json:"login"`
+		Play        StateSpec    `json:"play"`
+	}
+
+	StateSpec struct {
+		ToServer []PacketSpec `json:"toServer"`
+		ToClient []PacketSpec `json:"toClient"`
+	}
+
+	PacketSpec struct {
+		ID string `json:"id"`
+		StructSpec
+	}
+
+	// StructSpec is a packet's fields, or those of a struct packets are made of
+	StructSpec struct {
+		Name   string      `json:"name"`
+		Fields []FieldSpec `json:"fields"`
+	}
+
+	FieldSpec struct {
+		Name string `json:"name"`
+		// Type is a primitive like varint or string, buffer, array, nbt, or the name of one of Spec.Types
+		Type string `json:"type"`
+		// Of is the element type of an array
+		Of string `json:"of,omitempty"`
+		// CountField holds the length of a buffer or array, a buffer without one runs to the end of the packet
+		CountField string `json:"countField,omitempty"`
+		// Optional names a bool field that says whether this one is sent
+		Optional string `json:"optional,omitempty"`
+		// GoType is the struct an nbt field decodes into
+		GoType string `json:"goType,omitempty"`
+		// MaxLength caps the characters in a string, a number or a constant of the generated package
+		MaxLength string `json:"maxLength,omitempty"`
+		Doc       string `json:"doc,omitempty"`
+	}
+
+	// statePackets pairs a state's name in Go with its packets, in the order they're generated
+	statePackets struct {
+		State string
+		Spec  StateSpec
+	}
+)
+
+// ReadSpec parses and validates a spec
+func ReadSpec(reader io.Reader) (*Spec, error) {
+	decoder := json.NewDecoder(reader)
+	decoder.DisallowUnknownFields()
+	var spec Spec
+	if err := decoder.Decode(&spec); err != nil {
+		return nil, eris.Wrap(err, "failed to decode spec")
+	}
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+func (s *Spec) states() []statePackets {
+	return []statePackets{
+		{State: "Handshaking", Spec: s.Handshaking},
+		{State: "Status", Spec: s.Status},
+		{State: "Login", Spec: s.Login},
+		{State: "Play", Spec: s.Play},
+	}
+}
+
+func (s *Spec) validate() error {
+	types := map[string]bool{}
+	for _, typ := range s.Types {
+		if types[typ.Name] {
+			return eris.Errorf("type '%v' is declared twice", typ.Name)
+		}
+		types[typ.Name] = true
+	}
+	for _, typ := range s.Types {
+		if err := typ.validate(types); err != nil {
+			return err
+		}
+	}
+
+	// The same packet can appear in several states, like Disconnect, as long as it has the same fields
+	packets := map[string]StructSpec{}
+	for _, state := range s.states() {
+		for _, direction := range [][]PacketSpec{state.Spec.ToServer, state.Spec.ToClient} {
+			ids := map[int64]string{}
+			for _, packet := range direction {
+				id, err := packet.id()
+				if err != nil {
+					return err
+				}
+				if existing, ok := ids[id]; ok {
+					return eris.Errorf("%v and %v share ID %v in %v", existing, packet.Name, packet.ID, state.State)
+				}
+				ids[id] = packet.Name
+				if types[packet.Name] {
+					return eris.Errorf("packet '%v' has the same name as a type", packet.Name)
+				}
+				if existing, ok := packets[packet.Name]; ok {
+					if !existing.sameFields(packet.StructSpec) {
+						return eris.Errorf("packet '%v' is declared twice with different fields", packet.Name)
+					}
+					continue
+				}
+				if err = packet.validate(types); err != nil {
+					return err
+				}
+				packets[packet.Name] = packet.StructSpec
+			}
+		}
+	}
+	return nil
+}
+
+func (p PacketSpec) id() (int64, error) {
+	id, err := strconv.ParseInt(p.ID, 0, 32)
+	if err != nil {
+		return 0, eris.Wrapf(err, "invalid ID '%v' for %v", p.ID, p.Name)
+	}
+	return id, nil
+}
+
+func (s StructSpec) validate(types map[string]bool) error {
+	if s.Name == "" {
+		return eris.New("struct without a name")
+	}
+	fields := map[string]FieldSpec{}
+	for _, field := range s.Fields {
+		if _, ok := fields[field.Name]; ok {
+			return eris.Errorf("%v.%v is declared twice", s.Name, field.Name)
+		}
+		if err := field.validate(types, fields); err != nil {
+			return eris.Wrapf(err, "invalid field %v.%v", s.Name, field.Name)
+		}
+		fields[field.Name] = field
+	}
+	return nil
+}
+
+func (s StructSpec) sameFields(other StructSpec) bool {
+	if len(s.Fields) != len(other.Fields) {
+		return false
+	}
+	for i := range s.Fields {
+		if s.Fields[i] != other.Fields[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// validate checks a field against the ones before it, since counts and optional flags have to be read first
+func (f FieldSpec) validate(types map[string]bool, earlier map[string]FieldSpec) error {
+	if f.Name == "" {
+		return eris.New("field without a name")
+	}
+	switch f.Type {
+	case "buffer":
+	case "array":
+		if _, ok := primitives[f.Of]; !ok && !types[f.Of] {
+			return eris.Errorf("unknown element type '%v'", f.Of)
+		}
+		if f.CountField == "" {
+			return eris.New("arrays need a countField")
+		}
+	case "nbt":
+		if f.GoType == "" {
+			return eris.New("nbt fields need a goType")
+		}
+	default:
+		if _, ok := primitives[f.Type]; !ok && !types[f.Type] {
+			return eris.Errorf("unknown type '%v'", f.Type)
+		}
+	}
+	if f.CountField != "" {
+		if f.Type != "buffer" && f.Type != "array" {
+			return eris.New("only buffers and arrays have a countField")
+		}
+		if count, ok := earlier[f.CountField]; !ok || primitives[count.Type].goType != "int32" {
+			return eris.Errorf("countField '%v' has to be an earlier varint or i32", f.CountField)
+		}
+	}
+	if f.MaxLength != "" {
+		if f.Type != "string" {
+			return eris.New("only strings have a maxLength")
+		}
+		n, err := strconv.Atoi(f.MaxLength)
+		if err == nil && n <= 0 || err != nil && !token.IsIdentifier(f.MaxLength) {
+			return eris.Errorf("maxLength '%v' has to be a positive number or a constant", f.MaxLength)
+		}
+	}
+	if f.Optional != "" {
+		if flag, ok := earlier[f.Optional]; !ok || flag.Type != "bool" {
+			return eris.Errorf("optional '%v' has to be an earlier bool", f.Optional)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"flag"
+	"github.com/rotisserie/eris"
+	"minecraftServer/packet"
+	"minecraftServer/player"
+	"minecraftServer/throttle"
+	"os"
+	"time"
+)
+
+type (
+	Config struct {
+		Address  string
+		AuthMode player.AuthMode
+		// CompressionThreshold is the smallest packet that's compressed once a player logs in, -1 disables compression
+		CompressionThreshold int
+
+		// Motd is shown under the server's name in the server list
+		Motd       string
+		MaxPlayers int
+		// Favicon is the server list icon as a data URI, empty if there isn't one
+		Favicon string
+
+		// SendQueueSize is how many packets can wait to be written to each connection, SendQueuePolicy says what
+		// happens to the next one
+		SendQueueSize   int
+		SendQueuePolicy packet.QueuePolicy
+
+		// HandshakeTimeout and LoginTimeout are how long a client has to get through Handshaking and Status, and
+		// through Login
+		HandshakeTimeout time.Duration
+		LoginTimeout     time.Duration
+		// KeepAliveInterval is how often players are sent a KeepAlive, they're disconnected once one has gone
+		// unanswered for KeepAliveTimeout
+		KeepAliveInterval time.Duration
+		KeepAliveTimeout  time.Duration
+
+		// Throttle limits each client IP. With ProxyForwarding every connection comes from the proxy, so the limits
+		// count against the client IP it forwards instead, from the handshake on
+		Throttle throttle.Config
+	}
+)
+
+// ParseConfig reads the server settings from command line flags
+func ParseConfig(args []string) (*Config, error) {
+	flags := flag.NewFlagSet("minecraftServer", flag.ContinueOnError)
+	cfg := &Config{}
+	flags.StringVar(&cfg.Address, "address", "127.0.0.1:25565", "address to listen on")
+	authMode := flags.String("auth", player.Online.String(), "where player identities come from: offline, online or proxy")
+	flags.IntVar(&cfg.CompressionThreshold, "compression-threshold", 256, "smallest packet to compress, -1 disables compression")
+	flags.StringVar(&cfg.Motd, "motd", "A Minecraft Server", "message shown in the server list")
+	flags.IntVar(&cfg.MaxPlayers, "max-players", 20, "player count shown in the server list")
+	faviconPath := flags.String("favicon", "", "path to a 64x64 PNG shown in the server list")
+	flags.IntVar(&cfg.SendQueueSize, "send-queue", 256, "packets that can wait to be sent to each connection")
+	queuePolicy := flags.String("send-queue-policy", packet.DropWhenFull.String(), "what to do when a connection's send queue is full: block, drop or kick")
+	flags.DurationVar(&cfg.HandshakeTimeout, "handshake-timeout", 10*time.Second, "time a client has to finish the handshake and server list ping")
+	flags.DurationVar(&cfg.LoginTimeout, "login-timeout", 30*time.Second, "time a client has to log in")
+	flags.DurationVar(&cfg.KeepAliveInterval, "keepalive-interval", 15*time.Second, "how often players are sent a KeepAlive")
+	flags.DurationVar(&cfg.KeepAliveTimeout, "keepalive-timeout", 30*time.Second, "how long a KeepAlive can go unanswered")
+	flags.IntVar(&cfg.Throttle.MaxConnectionsPerIP, "max-connections-per-ip", 5, "connections an IP can hold open at once, 0 for no limit, counted from the handshake with -auth proxy")
+	flags.Float64Var(&cfg.Throttle.LoginRate, "login-rate", 0.25, "logins per second an IP earns, 0 for no limit")
+	flags.IntVar(&cfg.Throttle.LoginBurst, "login-burst", 3, "logins an IP can save up")
+	flags.IntVar(&cfg.Throttle.FailureLimit, "handshake-failures", 5, "failed handshakes within -handshake-failure-window that ban an IP, 0 to never ban")
+	flags.DurationVar(&cfg.Throttle.FailureWindow, "handshake-failure-window", time.Minute, "how long a failed handshake counts towards a ban")
+	flags.DurationVar(&cfg.Throttle.BanDuration, "ban-duration", 5*time.Minute, "how long an IP is banned for failing handshakes")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if cfg.AuthMode, err = player.ParseAuthMode(*authMode); err != nil {
+		return nil, err
+	}
+	if cfg.SendQueuePolicy, err = packet.ParseQueuePolicy(*queuePolicy); err != nil {
+		return nil, err
+	}
+	if cfg.SendQueueSize < 1 {
+		return nil, eris.Errorf("invalid send queue size %v", cfg.SendQueueSize)
+	}
+	if cfg.Throttle.LoginRate > 0 && cfg.Throttle.LoginBurst < 1 {
+		return nil, eris.Errorf("invalid login burst %v", cfg.Throttle.LoginBurst)
+	}
+	for name, timeout := range map[string]time.Duration{
+		"handshake timeout":  cfg.HandshakeTimeout,
+		"login timeout":      cfg.LoginTimeout,
+		"keepalive interval": cfg.KeepAliveInterval,
+		"keepalive timeout":  cfg.KeepAliveTimeout,
+	} {
+		if timeout <= 0 {
+			return nil, eris.Errorf("invalid %v %v", name, timeout)
+		}
+	}
+	if len(*faviconPath) > 0 {
+		data, err := os.ReadFile(*faviconPath)
+		if err != nil {
+			return nil, eris.Wrap(err, "failed to read favicon")
+		}
+		if cfg.Favicon, err = packet.EncodeFavicon(data); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
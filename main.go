@@ -1,85 +1,69 @@
 package main
 
 import (
-	"encoding/json"
-	"fmt"
-	"github.com/google/uuid"
+	"errors"
 	"github.com/rotisserie/eris"
 	"io"
+	"log"
+	"minecraftServer/mojang"
 	"minecraftServer/packet"
+	"minecraftServer/player"
+	"minecraftServer/throttle"
 	"net"
 	"os"
 	"os/signal"
 	"syscall"
 )
 
-type (
-	LoginData struct {
-		Payload string `json:"payload"`
-	}
-
-	HandshakeData struct {
-		ProtocolVersion int32  `json:"protocol_version" pkt_type:"VarInt"`
-		ServerAddress   string `json:"server_address"`
-		ServerPort      uint16 `json:"server_port"`
-		NextState       int32  `json:"next_state" pkt_type:"VarInt"`
-	}
-
-	State byte
-)
-
 const (
-	Handshaking State = iota
-	Status
-	Login
-	Play
+	// statusSampleSize is how many players are listed when hovering the player count
+	statusSampleSize = 12
 )
 
-func StateFromVarInt(varInt packet.VarInt) State {
-	return []State{Handshaking, Status, Login, Play}[varInt]
-}
-
-func (s State) String() string {
-	return []string{"Handshaking", "Status", "Login", "Play"}[s]
-}
-
-func (s State) MarshalJSON() ([]byte, error) {
-	return json.Marshal(s.String())
-}
-
-func (d HandshakeData) String() string {
-	b, _ := json.Marshal(d)
-	return fmt.Sprintf("Handshake %v", string(b))
-}
-
-func (d LoginData) String() string {
-	b, _ := json.Marshal(d)
-	return fmt.Sprintf("LoginData %v", string(b))
-}
+var (
+	players         = player.NewList()
+	config          *Config
+	serverKey       *packet.ServerKey
+	sessionVerifier mojang.SessionVerifier = mojang.NewApiClient(mojang.DefaultTimeout)
+	throttler       *throttle.Throttle
+)
 
-func ReadHandshakeData(pkt packet.Packet) (*HandshakeData, error) {
-	var handshake HandshakeData
-	err := packet.Unmarshal(pkt, &handshake)
-	if err != nil {
-		return nil, eris.Wrap(err, "failed to unmarshal HandshakeData")
+// serverStatus builds the server list entry from the config and who's online, advertising the client's version when
+// it's one the server speaks
+func serverStatus(version *packet.Version) *packet.ServerStatus {
+	status := &packet.ServerStatus{
+		Version: packet.StatusVersion{
+			Name:     version.Name,
+			Protocol: version.Protocol,
+		},
+		Players: packet.StatusPlayers{
+			Max:    config.MaxPlayers,
+			Online: players.Count(),
+		},
+		Description: packet.TextComponent(config.Motd),
+		Favicon:     config.Favicon,
 	}
-
-	return &handshake, nil
-}
-
-func ReadLoginData(pkt packet.Packet) (*LoginData, error) {
-	var loginData LoginData
-	if err := packet.Unmarshal(pkt, &loginData); err != nil {
-		return nil, eris.Wrap(err, "failed to unmarshal LoginData")
+	for _, pl := range players.Sample(statusSampleSize) {
+		status.Players.Sample = append(status.Players.Sample, packet.StatusPlayerSample{
+			Name: pl.Username,
+			Id:   pl.UUID.String(),
+		})
 	}
-
-	return &loginData, nil
+	return status
 }
 
 func main() {
-	sigs := make(chan os.Signal)
+	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT)
-	tcpAddr, err := net.ResolveTCPAddr("tcp4", "127.0.0.1:25565")
+	var err error
+	config, err = ParseConfig(os.Args[1:])
+	p(err)
+	throttler = throttle.New(config.Throttle)
+	if config.AuthMode == player.Online {
+		serverKey, err = packet.GenerateServerKey()
+		p(err)
+	}
+	tcpAddr, err := net.ResolveTCPAddr("tcp4", config.Address)
 	p(err)
 	listener, err := net.ListenTCP("tcp", tcpAddr)
 	p(err)
@@ -88,48 +72,26 @@ func main() {
 	go func() {
 		for {
 			conn, err := listener.AcceptTCP()
-			p(err)
-			go func(conn *net.TCPConn) {
-				defer conn.Close()
-				fmt.Println("OPENING CONNECTION")
-				defer func() {
-					fmt.Println("CLOSING CONNECTION")
-				}()
-				state := int32(0)
-				for {
-					pkt, err := packet.MakeUncompressedPacket(conn)
-					if IsConnectionClosedErr(err) {
-						break
-					}
-					p(err)
-					if state == 0 && pkt.ID() == 0 {
-						h, err := ReadHandshakeData(pkt)
-						if IsConnectionClosedErr(err) {
-							break
-						}
-						p(err)
-						state = h.NextState
-						fmt.Println(h)
-					} else if state == 2 {
-						loginData, err := ReadLoginData(pkt)
-						if IsConnectionClosedErr(err) {
-							break
-						}
-						p(err)
-						fmt.Println(loginData)
-
-						// Login success testing -> We get 'Joining world' from this
-						loginSuccess := &packet.LoginSuccess{
-							UUID:     uuid.MustParse("e52d49e2f2244a7380cfcacf6aecbcae"),
-							Username: loginData.Payload,
-						}
-						newPkt, err := packet.MakePacketWithData(0x02, loginSuccess)
-						p(err)
-						_, err = packet.WriteTo(newPkt, conn)
-						p(err)
-					}
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			if err != nil {
+				log.Printf("failed to accept connection: %v", err)
+				continue
+			}
+			release := func() {}
+			// Behind a proxy every connection comes from it, sessions throttle and count the forwarded client IPs instead
+			if config.AuthMode != player.ProxyForwarding {
+				// Turned away before anything is read, a flood shouldn't cost a goroutine per connection
+				if release, err = throttler.Accept(conn.RemoteAddr()); err != nil {
+					_ = conn.Close()
+					continue
 				}
-			}(conn)
+			}
+			go func() {
+				defer release()
+				newSession(packet.NewConn(conn)).run()
+			}()
 		}
 	}()
 	<-sigs
@@ -6,12 +6,18 @@ import (
 	"github.com/rotisserie/eris"
 	"io"
 	"net/http"
+	"time"
 )
 
+// DefaultTimeout bounds each request to Mojang, a hung API would otherwise hold whoever's waiting on it forever
+const DefaultTimeout = 10 * time.Second
+
 // https://wiki.vg/Mojang_AP
 type (
 	ApiClient struct {
 		Client http.Client
+		// SessionServerURL overrides DefaultSessionServerURL, so tests can point it at a stand-in
+		SessionServerURL string
 	}
 
 	PlayerUuidRequest  []string
@@ -21,6 +27,11 @@ type (
 	}
 )
 
+// NewApiClient makes a client whose requests give up after timeout, the zero ApiClient never does
+func NewApiClient(timeout time.Duration) *ApiClient {
+	return &ApiClient{Client: http.Client{Timeout: timeout}}
+}
+
 func (c *ApiClient) GetPlayerUuid(request PlayerUuidRequest) ([]PlayerUuidResponse, error) {
 	req, err := json.Marshal(request)
 	if err != nil {
@@ -0,0 +1,104 @@
+package mojang
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/rotisserie/eris"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// https://wiki.vg/Protocol_Encryption#Authentication
+
+// DefaultSessionServerURL is used when ApiClient.SessionServerURL is empty
+const DefaultSessionServerURL = "https://sessionserver.mojang.com"
+
+// ErrNotAuthenticated is returned by HasJoined when the session server has no record of the player joining
+var ErrNotAuthenticated = eris.New("player has not joined through the session server")
+
+type (
+	// SessionVerifier checks a player authenticated with Mojang before joining, ApiClient talks to the real session server
+	SessionVerifier interface {
+		HasJoined(username, serverHash, ip string) (*Profile, error)
+	}
+
+	Profile struct {
+		Id         string            `json:"id"`
+		Name       string            `json:"name"`
+		Properties []ProfileProperty `json:"properties"`
+	}
+
+	// ProfileProperty holds skin and cape textures, Signature is signed by Mojang
+	ProfileProperty struct {
+		Name      string `json:"name"`
+		Value     string `json:"value"`
+		Signature string `json:"signature,omitempty"`
+	}
+)
+
+// ServerHash computes Mojang's hex digest of the server ID, shared secret and DER public key, which is the SHA1
+// printed as a signed two's complement number like Java's BigInteger.toString(16)
+func ServerHash(serverID string, sharedSecret, publicKey []byte) string {
+	hash := sha1.New()
+	hash.Write([]byte(serverID))
+	hash.Write(sharedSecret)
+	hash.Write(publicKey)
+	digest := hash.Sum(nil)
+
+	n := new(big.Int).SetBytes(digest)
+	if digest[0]&0x80 != 0 {
+		// Undo the two's complement to print the magnitude with a minus sign
+		n.Sub(new(big.Int).Lsh(big.NewInt(1), uint(len(digest)*8)), n)
+		return "-" + n.Text(16)
+	}
+	return n.Text(16)
+}
+
+// HasJoined asks the session server whether username authenticated with serverHash, ip is optional
+func (c *ApiClient) HasJoined(username, serverHash, ip string) (*Profile, error) {
+	query := url.Values{}
+	query.Set("username", username)
+	query.Set("serverId", serverHash)
+	if len(ip) > 0 {
+		query.Set("ip", ip)
+	}
+	baseURL := c.SessionServerURL
+	if len(baseURL) == 0 {
+		baseURL = DefaultSessionServerURL
+	}
+
+	resp, err := c.Client.Get(strings.TrimSuffix(baseURL, "/") + "/session/minecraft/hasJoined?" + query.Encode())
+	if err != nil {
+		return nil, eris.Wrap(err, "failed to hit Mojang hasJoined endpoint")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, ErrNotAuthenticated
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, eris.Errorf("unexpected status from hasJoined: %v", resp.Status)
+	}
+	respBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, eris.Wrap(err, "failed to read response body")
+	}
+
+	var profile Profile
+	if err = json.Unmarshal(respBytes, &profile); err != nil {
+		return nil, eris.Wrap(err, "failed to unmarshal profile")
+	}
+	return &profile, nil
+}
+
+// UUID parses the undashed ID the session server returns
+func (p Profile) UUID() (uuid.UUID, error) {
+	id, err := uuid.Parse(p.Id)
+	if err != nil {
+		return uuid.Nil, eris.Wrapf(err, "invalid profile id '%v'", p.Id)
+	}
+	return id, nil
+}
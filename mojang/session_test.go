@@ -0,0 +1,66 @@
+package mojang
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServerHash(t *testing.T) {
+	tests := map[string]string{
+		"Notch": "4ed1f46bbe04bc756bcb17c0c7ce3e4632f06a48",
+		"jeb_":  "-7c9d5b0044c130109a5d7b5fb5c317c02b4e28c1",
+		"simon": "88e16a1019277b15d58faf0541e11910eb756f6",
+	}
+	for name, expected := range tests {
+		assert.Equal(t, expected, ServerHash(name, nil, nil), name)
+	}
+}
+
+func TestHasJoined(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/session/minecraft/hasJoined", r.URL.Path)
+		if r.URL.Query().Get("username") != "Notch" || r.URL.Query().Get("serverId") != "hash" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		assert.Equal(t, "127.0.0.1", r.URL.Query().Get("ip"))
+		_, _ = w.Write([]byte(`{
+			"id": "069a79f444e94726a5befca90e38aaf5",
+			"name": "Notch",
+			"properties": [{"name": "textures", "value": "e30=", "signature": "c2ln"}]
+		}`))
+	}))
+	defer server.Close()
+	client := &ApiClient{SessionServerURL: server.URL}
+
+	profile, err := client.HasJoined("Notch", "hash", "127.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(t, "Notch", profile.Name)
+	assert.Equal(t, []ProfileProperty{{Name: "textures", Value: "e30=", Signature: "c2ln"}}, profile.Properties)
+	id, err := profile.UUID()
+	assert.NoError(t, err)
+	assert.Equal(t, uuid.MustParse("069a79f4-44e9-4726-a5be-fca90e38aaf5"), id)
+
+	_, err = client.HasJoined("Notch", "wrong", "")
+	assert.Equal(t, ErrNotAuthenticated, err)
+}
+
+func TestHasJoinedTimesOut(t *testing.T) {
+	hung := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hung
+	}))
+	defer server.Close()
+	defer close(hung)
+	client := NewApiClient(50 * time.Millisecond)
+	client.SessionServerURL = server.URL
+
+	start := time.Now()
+	_, err := client.HasJoined("Notch", "hash", "")
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
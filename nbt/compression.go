@@ -11,6 +11,7 @@ type (
 )
 
 const (
+	Uncompressed   = byte(0x00)
 	GzipCompressed = byte(0x1F)
 	ZLibCompressed = byte(0x78)
 )
@@ -0,0 +1,35 @@
+package nbt
+
+import (
+	"encoding/binary"
+)
+
+// Encoding describes how numbers, lengths and strings are laid out. Java Edition uses big-endian with modified
+// UTF-8 strings, Bedrock files like level.dat and .mcstructure are little-endian, and the Bedrock network protocol
+// also writes Ints, Longs and lengths as varints
+type Encoding struct {
+	// ByteOrder is used for Shorts, Floats and Doubles, and for Ints, Longs and lengths unless VarInt is set
+	ByteOrder binary.ByteOrder
+	// VarInt writes Ints, Longs, List and array lengths as zig-zag varints, and String lengths as unsigned varints
+	VarInt bool
+	// ModifiedUTF8 writes Strings as Java's modified UTF-8 rather than plain UTF-8
+	ModifiedUTF8 bool
+}
+
+var (
+	// JavaEncoding is the default, used by Java Edition files and packets
+	JavaEncoding = Encoding{ByteOrder: binary.BigEndian, ModifiedUTF8: true}
+	// BedrockEncoding is used by Bedrock Edition files
+	BedrockEncoding = Encoding{ByteOrder: binary.LittleEndian}
+	// BedrockNetworkEncoding is used by the Bedrock Edition network protocol
+	BedrockNetworkEncoding = Encoding{ByteOrder: binary.LittleEndian, VarInt: true}
+)
+
+// zigZag maps signed integers to unsigned so small negative numbers stay short as varints
+func zigZag(i int64) uint64 {
+	return uint64(i<<1) ^ uint64(i>>63)
+}
+
+func unZigZag(u uint64) int64 {
+	return int64(u>>1) ^ -int64(u&1)
+}
@@ -0,0 +1,157 @@
+package nbt
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type encodingTestDoc struct {
+	Root  string
+	Short int16   `nbt:"s"`
+	Int   int32   `nbt:"i"`
+	Long  int64   `nbt:"l"`
+	Float float32 `nbt:"f"`
+	Name  string  `nbt:"n"`
+	Ints  []int32 `nbt:"ia"`
+	List  []int32 `nbt:"li" nbt_type:"List"`
+}
+
+var (
+	encodingTestValue = encodingTestDoc{
+		Root:  "hi",
+		Short: 1,
+		Int:   -2,
+		Long:  300,
+		Float: 1.5,
+		Name:  "a\x00",
+		Ints:  []int32{-1, 1},
+		List:  []int32{2},
+	}
+
+	encodingTestCases = []struct {
+		name     string
+		encoding Encoding
+		fixture  []byte
+	}{
+		{
+			name:     "Java",
+			encoding: JavaEncoding,
+			fixture: []byte{
+				0x0a, 0x00, 0x02, 'h', 'i',
+				0x02, 0x00, 0x01, 's', 0x00, 0x01,
+				0x03, 0x00, 0x01, 'i', 0xff, 0xff, 0xff, 0xfe,
+				0x04, 0x00, 0x01, 'l', 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x2c,
+				0x05, 0x00, 0x01, 'f', 0x3f, 0xc0, 0x00, 0x00,
+				0x08, 0x00, 0x01, 'n', 0x00, 0x03, 'a', 0xc0, 0x80,
+				0x0b, 0x00, 0x02, 'i', 'a', 0x00, 0x00, 0x00, 0x02, 0xff, 0xff, 0xff, 0xff, 0x00, 0x00, 0x00, 0x01,
+				0x09, 0x00, 0x02, 'l', 'i', 0x03, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02,
+				0x00,
+			},
+		},
+		{
+			name:     "Bedrock",
+			encoding: BedrockEncoding,
+			fixture: []byte{
+				0x0a, 0x02, 0x00, 'h', 'i',
+				0x02, 0x01, 0x00, 's', 0x01, 0x00,
+				0x03, 0x01, 0x00, 'i', 0xfe, 0xff, 0xff, 0xff,
+				0x04, 0x01, 0x00, 'l', 0x2c, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				0x05, 0x01, 0x00, 'f', 0x00, 0x00, 0xc0, 0x3f,
+				0x08, 0x01, 0x00, 'n', 0x02, 0x00, 'a', 0x00,
+				0x0b, 0x02, 0x00, 'i', 'a', 0x02, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff, 0x01, 0x00, 0x00, 0x00,
+				0x09, 0x02, 0x00, 'l', 'i', 0x03, 0x01, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00,
+				0x00,
+			},
+		},
+		{
+			name:     "BedrockNetwork",
+			encoding: BedrockNetworkEncoding,
+			fixture: []byte{
+				0x0a, 0x02, 'h', 'i',
+				0x02, 0x01, 's', 0x01, 0x00,
+				0x03, 0x01, 'i', 0x03,
+				0x04, 0x01, 'l', 0xd8, 0x04,
+				0x05, 0x01, 'f', 0x00, 0x00, 0xc0, 0x3f,
+				0x08, 0x01, 'n', 0x02, 'a', 0x00,
+				0x0b, 0x02, 'i', 'a', 0x04, 0x01, 0x02,
+				0x09, 0x02, 'l', 'i', 0x03, 0x02, 0x04,
+				0x00,
+			},
+		},
+	}
+)
+
+func TestEncodingEncode(t *testing.T) {
+	for _, testCase := range encodingTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			buf := bytes.NewBuffer(nil)
+			enc := NewEncoder(buf)
+			enc.SetEncoding(testCase.encoding)
+			assert.NoError(t, enc.Encode(&encodingTestValue))
+			assert.Equal(t, testCase.fixture, buf.Bytes())
+		})
+	}
+}
+
+func TestEncodingDecode(t *testing.T) {
+	for _, testCase := range encodingTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			dec := NewDecoder(bytes.NewReader(testCase.fixture))
+			dec.SetEncoding(testCase.encoding)
+			var doc encodingTestDoc
+			assert.NoError(t, dec.Decode(&doc))
+			assert.Equal(t, encodingTestValue, doc)
+		})
+	}
+}
+
+func TestEncodingSkip(t *testing.T) {
+	for _, testCase := range encodingTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// Decoding a single field skips over every other one
+			dec := NewDecoder(bytes.NewReader(testCase.fixture))
+			dec.SetEncoding(testCase.encoding)
+			var doc struct {
+				Name string  `nbt:"n"`
+				List []int32 `nbt:"li" nbt_type:"List"`
+			}
+			assert.NoError(t, dec.Decode(&doc))
+			assert.Equal(t, "a\x00", doc.Name)
+			assert.Equal(t, []int32{2}, doc.List)
+		})
+	}
+}
+
+func TestEncodingTree(t *testing.T) {
+	for _, testCase := range encodingTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			dec := NewDecoder(bytes.NewReader(testCase.fixture))
+			dec.SetEncoding(testCase.encoding)
+			var compound Compound
+			assert.NoError(t, dec.Decode(&compound))
+
+			buf := bytes.NewBuffer(nil)
+			enc := NewEncoder(buf)
+			enc.SetEncoding(testCase.encoding)
+			assert.NoError(t, enc.Encode(&compound))
+			// The root name isn't kept in the tree, so only the payload after the empty root name should match
+			rootHeaderLen := 3
+			if testCase.encoding.VarInt {
+				rootHeaderLen = 2
+			}
+			assert.True(t, bytes.HasSuffix(testCase.fixture, buf.Bytes()[rootHeaderLen:]))
+		})
+	}
+}
+
+func TestDecoderReadsConsecutiveValues(t *testing.T) {
+	fixture := encodingTestCases[2].fixture
+	dec := NewDecoder(bytes.NewReader(append(append([]byte{}, fixture...), fixture...)))
+	dec.SetEncoding(BedrockNetworkEncoding)
+	for i := 0; i < 2; i++ {
+		var doc encodingTestDoc
+		assert.NoError(t, dec.Decode(&doc))
+		assert.Equal(t, encodingTestValue, doc)
+	}
+}
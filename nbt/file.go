@@ -0,0 +1,90 @@
+package nbt
+
+import (
+	"bufio"
+	"github.com/rotisserie/eris"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ReadFile decodes a gzip, zlib or uncompressed NBT file such as level.dat or playerdata/<uuid>.dat into a struct or *Compound
+func ReadFile(path string, v interface{}) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return eris.Wrapf(err, "failed to open '%v'", path)
+	}
+	defer file.Close()
+
+	if err = Read(file, v); err != nil {
+		return eris.Wrapf(err, "failed to read '%v'", path)
+	}
+	return nil
+}
+
+// Read decodes NBT into a struct or *Compound, detecting the compression from the first byte
+func Read(reader io.Reader, v interface{}) error {
+	decompressed, err := DecompressReader(reader)
+	if err != nil {
+		return err
+	}
+	defer decompressed.Close()
+
+	return UnmarshalFrom(decompressed, v)
+}
+
+// DecompressReader sniffs the gzip or zlib magic byte and wraps the reader to match, anything else is read as is
+func DecompressReader(reader io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(reader)
+	magic, err := buffered.Peek(1)
+	if err != nil {
+		return nil, eris.Wrap(err, "failed to detect compression")
+	}
+	return CompressWrapReader(magic[0], buffered)
+}
+
+// WriteFile encodes a struct or *Compound to path with the given compression, replacing the file once fully written
+func WriteFile(path string, v interface{}, compression byte) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return eris.Wrapf(err, "failed to create temporary file for '%v'", path)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	if err = tmp.Chmod(0644); err != nil {
+		return err
+	}
+
+	if err = Write(tmp, v, compression); err != nil {
+		return eris.Wrapf(err, "failed to write '%v'", path)
+	}
+	if err = tmp.Close(); err != nil {
+		return eris.Wrapf(err, "failed to close '%v'", tmp.Name())
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return eris.Wrapf(err, "failed to replace '%v'", path)
+	}
+	return nil
+}
+
+// Write encodes a struct or *Compound with the given compression
+func Write(writer io.Writer, v interface{}, compression byte) error {
+	switch compression {
+	case Uncompressed, GzipCompressed, ZLibCompressed:
+	default:
+		return eris.Errorf("unknown compression 0x%02x", compression)
+	}
+	bs, err := MarshalToNBT(v)
+	if err != nil {
+		return err
+	}
+	compressed := CompressWrapWriter(compression, writer)
+	if _, err = compressed.Write(bs); err != nil {
+		return err
+	}
+	return compressed.Close()
+}
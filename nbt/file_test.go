@@ -0,0 +1,66 @@
+package nbt
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type levelDat struct {
+	Data struct {
+		DataVersion int32  `nbt:"DataVersion"`
+		LevelName   string `nbt:"LevelName"`
+		RandomSeed  int64  `nbt:"RandomSeed"`
+		SpawnX      int32  `nbt:"SpawnX"`
+	} `nbt:"Data"`
+}
+
+func TestWriteFileReadFile(t *testing.T) {
+	var level levelDat
+	level.Data.DataVersion = 2586
+	level.Data.LevelName = "world"
+	level.Data.RandomSeed = -4172144997902289642
+	level.Data.SpawnX = -240
+
+	for _, compression := range []byte{GzipCompressed, ZLibCompressed, Uncompressed} {
+		path := filepath.Join(t.TempDir(), "level.dat")
+		assert.NoError(t, WriteFile(path, &level, compression))
+
+		bs, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		if compression == Uncompressed {
+			assert.Equal(t, byte(0x0a), bs[0])
+		} else {
+			assert.Equal(t, compression, bs[0])
+		}
+
+		var decoded levelDat
+		assert.NoError(t, ReadFile(path, &decoded))
+		assert.Equal(t, level, decoded)
+
+		var tree Compound
+		assert.NoError(t, ReadFile(path, &tree))
+		name, err := tree.GetPath("Data.LevelName")
+		assert.NoError(t, err)
+		assert.Equal(t, String("world"), *name.(*String))
+
+		// Trees can be written back out
+		assert.NoError(t, WriteFile(path, &tree, compression))
+		assert.NoError(t, ReadFile(path, &decoded))
+		assert.Equal(t, level, decoded)
+	}
+}
+
+func TestReadFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	assert.Error(t, ReadFile(filepath.Join(dir, "missing.dat"), &levelDat{}))
+
+	empty := filepath.Join(dir, "empty.dat")
+	assert.NoError(t, os.WriteFile(empty, nil, 0644))
+	assert.Error(t, ReadFile(empty, &levelDat{}))
+
+	assert.Error(t, WriteFile(filepath.Join(dir, "unknown.dat"), &levelDat{}, 0x42))
+	_, err := os.Stat(filepath.Join(dir, "unknown.dat"))
+	assert.True(t, os.IsNotExist(err), "nothing is left behind")
+}
@@ -1,16 +1,25 @@
 package nbt
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/binary"
 	"github.com/rotisserie/eris"
+	"io"
+	"math"
 	"minecraftServer/nbt/tags"
 	"reflect"
+	"sort"
 	"strings"
+	"sync"
 )
 
 type (
-	encoder struct {
-		buf *bytes.Buffer
+	// Encoder writes NBT straight to an io.Writer through a fixed size buffer, so memory use doesn't grow with the document
+	Encoder struct {
+		writer   *bufio.Writer
+		encoding Encoding
+		scratch  [binary.MaxVarintLen64]byte
 	}
 
 	nbtTags struct {
@@ -18,188 +27,427 @@ type (
 		Name     string
 		Optional string
 	}
+
+	// structPlan is the per-type work of walking struct tags, worked out once and cached
+	structPlan struct {
+		Fields []fieldPlan
+		// byName and byFoldedName index Fields for decoding
+		byName       map[string]int
+		byFoldedName map[string]int
+	}
+
+	fieldPlan struct {
+		Index    int
+		Name     string
+		Optional bool
+		// Tag is End when it depends on the value in an interface{}
+		Tag  tags.Tag
+		Tags nbtTags
+		// Err is returned when a field of this type is encoded
+		Err error
+	}
 )
 
-// TODO: Reader output instead?
-func MarshalToNBT(i interface{}) ([]byte, error) {
-	enc := &encoder{
-		buf: bytes.NewBuffer(nil),
+var structPlans sync.Map
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{
+		writer:   bufio.NewWriter(w),
+		encoding: JavaEncoding,
 	}
-	if err := enc.Encode(i); err != nil {
+}
+
+// SetEncoding changes the byte order and integer encoding used from the next value on, the default is JavaEncoding
+func (e *Encoder) SetEncoding(encoding Encoding) {
+	e.encoding = encoding
+}
+
+func MarshalToNBT(i interface{}) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	if err := NewEncoder(buf).Encode(i); err != nil {
 		return nil, err
 	}
-	return enc.buf.Bytes(), nil
+	return buf.Bytes(), nil
 }
 
 func MarshalValueToNBT(v reflect.Value) ([]byte, error) {
-	enc := &encoder{
-		buf: bytes.NewBuffer(nil),
-	}
-	if err := enc.EncodeValue(v); err != nil {
+	buf := bytes.NewBuffer(nil)
+	if err := NewEncoder(buf).EncodeValue(v); err != nil {
 		return nil, err
 	}
-	return enc.buf.Bytes(), nil
+	return buf.Bytes(), nil
 }
 
-func (e *encoder) Encode(i interface{}) error {
+// Encode writes a struct or *Compound as a root compound
+func (e *Encoder) Encode(i interface{}) error {
+	if compound, ok := i.(*Compound); ok {
+		if err := e.encodeTree("", compound); err != nil {
+			return err
+		}
+		return e.writer.Flush()
+	}
 	return e.EncodeValue(reflect.ValueOf(i))
 }
 
-func (e *encoder) EncodeValue(v reflect.Value) (err error) {
+// EncodeValue writes a struct or map[string]T as a root compound, named by its Root field if it has one
+func (e *Encoder) EncodeValue(v reflect.Value) error {
 	if v.Kind() == reflect.Ptr {
 		v = v.Elem()
 	}
+	if v.Kind() == reflect.Map {
+		if err := e.writeNamedTag(tags.Compound, ""); err != nil {
+			return err
+		}
+		if err := e.encodeMap(v); err != nil {
+			return err
+		}
+		return e.writer.Flush()
+	}
+	if v.Kind() != reflect.Struct {
+		return eris.Errorf("cannot encode '%v' as a root compound", v.Kind())
+	}
 
 	rootField := v.FieldByName("Root")
 	rootName := ""
 	if rootField.IsValid() && rootField.Kind() == reflect.String {
 		rootName = rootField.String()
 	}
-	namedTag := NamedTag{
-		Tag:  tags.Compound,
-		Name: rootName,
+	if err := e.writeNamedTag(tags.Compound, rootName); err != nil {
+		return err
 	}
-	if _, err = namedTag.WriteTo(e.buf); err != nil {
-		return
+	if err := e.encodeStruct(v); err != nil {
+		return err
 	}
-
-	return e.EncodeInternalStruct(v)
+	return e.writer.Flush()
 }
 
-func (e *encoder) EncodeInternalStruct(v reflect.Value) (err error) {
-	typ := v.Type()
-
-	for i := 0; i < v.NumField(); i++ {
-		if i == 0 && typ.Field(i).Name == "Root" {
+func (e *Encoder) encodeStruct(v reflect.Value) error {
+	plan := planFor(v.Type())
+	for _, field := range plan.Fields {
+		// TODO: This makes any optional field missing if it's 0 or false, will need to use pointers if this is not expected
+		if field.Optional && v.Field(field.Index).IsZero() {
 			continue
 		}
-		if err = e.EncodeField(typ, i, v.Field(i)); err != nil {
-			return
+		if field.Err != nil {
+			return field.Err
+		}
+		value, ok := resolve(v.Field(field.Index))
+		if !ok {
+			// NBT has no null, so nil pointers and interfaces are left out
+			continue
 		}
 
+		tag := field.Tag
+		if tag == tags.End {
+			var err error
+			if tag, err = tagFor(value.Type(), field.Tags); err != nil {
+				return eris.Wrapf(err, "failed to encode '%v'", field.Name)
+			}
+		}
+		if err := e.writeNamedTag(tag, field.Name); err != nil {
+			return err
+		}
+		if err := e.encodePayload(tag, value); err != nil {
+			return eris.Wrapf(err, "failed to encode '%v'", field.Name)
+		}
 	}
 	return e.writeTag(tags.End)
 }
 
-func (e *encoder) EncodeField(typ reflect.Type, i int, field reflect.Value) (err error) {
-	typeField := typ.Field(i)
-	fieldTags := makeTags(typeField.Tag)
-
-	if fieldTags.isOptional() {
-		// TODO: This makes any optional field missing if it's 0 or false, will need to use pointers if this is not expected
-		if field.IsZero() {
-			return
+// encodeMap writes a map[string]T as a compound, sorting the keys so the output is deterministic
+func (e *Encoder) encodeMap(v reflect.Value) error {
+	elemTag, err := tagFor(v.Type().Elem(), nbtTags{})
+	if err != nil {
+		return err
+	}
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	for _, key := range keys {
+		value, ok := resolve(v.MapIndex(key))
+		if !ok {
+			continue
 		}
-		if field.Kind() == reflect.Ptr {
-			// Pull out from pointer
-			field = field.Elem()
+		tag := elemTag
+		if tag == tags.End {
+			if tag, err = tagFor(value.Type(), nbtTags{}); err != nil {
+				return eris.Wrapf(err, "failed to encode '%v'", key.String())
+			}
 		}
-	}
-
-	fieldName := strings.ToLower(typeField.Name)
-
-	makeNamedField := func(field Field) NamedField {
-		return NamedField{
-			Field:   field,
-			Name:    fieldName,
-			NameTag: fieldTags.Name,
+		if err = e.writeNamedTag(tag, key.String()); err != nil {
+			return err
 		}
-	}
-
-	makeNamedTag := func(tag tags.Tag) NamedTag {
-		return NamedTag{
-			Tag:     tag,
-			Name:    fieldName,
-			NameTag: fieldTags.Name,
+		if err = e.encodePayload(tag, value); err != nil {
+			return eris.Wrapf(err, "failed to encode '%v'", key.String())
 		}
 	}
+	return e.writeTag(tags.End)
+}
 
-	var fieldEncoder FieldEncoder
-	if fieldFunc, ok := fieldMap[field.Kind()]; ok {
-		fieldEncoder = NamedField{
-			Field:   fieldFunc(field),
-			Name:    fieldName,
-			NameTag: fieldTags.Name,
+func (e *Encoder) encodePayload(tag tags.Tag, v reflect.Value) error {
+	v, ok := resolve(v)
+	if !ok {
+		return eris.Errorf("cannot encode nil as %v", tag)
+	}
+	switch tag {
+	case tags.Byte:
+		if v.Kind() == reflect.Bool {
+			if v.Bool() {
+				return e.writeByte(0x01)
+			}
+			return e.writeByte(0x00)
 		}
-	} else {
-		switch field.Kind() {
-		case reflect.Slice:
-			sliceType := field.Type().Elem().Kind()
-			if isList(sliceType, fieldTags) {
-				l := field.Len()
-				if _, err = writeAll(e.buf, makeNamedTag(tags.List), tags.TagMap[sliceType], Int(l)); err != nil {
-					return
-				}
-
-				if fieldFunc, ok := fieldMap[sliceType]; ok {
-					for i := 0; i < l; i++ {
-						// TODO: Try to make this recursive if possible?
-						if _, err = writeAll(e.buf, fieldFunc(field.Index(i))); err != nil {
-							return
-						}
-					}
-				} else {
-					switch sliceType {
-					// Compound List
-					case reflect.Struct:
-						for i := 0; i < field.Len(); i++ {
-							// Compound lists just have an END tag between each
-							if err = e.EncodeInternalStruct(field.Index(i)); err != nil {
-								return
-							}
-						}
-					}
-				}
+		return e.writeByte(byte(v.Uint()))
+	case tags.Short:
+		return e.writeShort(uint16(intOrUint(v)))
+	case tags.Int:
+		return e.writeInt(uint32(intOrUint(v)))
+	case tags.Long:
+		return e.writeLong(uint64(intOrUint(v)))
+	case tags.Float:
+		return e.writeFixed32(math.Float32bits(float32(v.Float())))
+	case tags.Double:
+		return e.writeFixed64(math.Float64bits(v.Float()))
+	case tags.String:
+		return e.writeString(v.String())
+	case tags.ByteArray:
+		if err := e.writeLength(v.Len()); err != nil {
+			return err
+		}
+		_, err := e.writer.Write(v.Bytes())
+		return err
+	case tags.IntArray, tags.LongArray:
+		if err := e.writeLength(v.Len()); err != nil {
+			return err
+		}
+		for i := 0; i < v.Len(); i++ {
+			var err error
+			if tag == tags.IntArray {
+				err = e.writeInt(uint32(v.Index(i).Int()))
 			} else {
-				switch sliceType {
-				// []byte
-				case reflect.Uint8:
-					ba := ByteArray(field.Bytes())
-					fieldEncoder = makeNamedField(&ba)
-				// IntArray
-				case reflect.Int32:
-					intArr := field.Interface().([]int32)
-					if _, err = writeAll(e.buf, makeNamedTag(tags.IntArray), Int(len(intArr))); err != nil {
-						return
-					}
-					for _, i := range intArr {
-						if _, err = Int(i).WriteTo(e.buf); err != nil {
-							return
-						}
-					}
-				// LongArray
-				case reflect.Int64:
-					longArr := field.Interface().([]int64)
-					if _, err = writeAll(e.buf, makeNamedTag(tags.LongArray), Int(len(longArr))); err != nil {
-						return
-					}
-					for _, l := range longArr {
-						if _, err = Long(l).WriteTo(e.buf); err != nil {
-							return
-						}
-					}
-				default:
-					return eris.Errorf("unknown slice type %v", sliceType)
+				err = e.writeLong(uint64(v.Index(i).Int()))
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case tags.List:
+		elemTag, err := tagFor(v.Type().Elem(), nbtTags{})
+		if err != nil {
+			return err
+		}
+		if elemTag == tags.End && v.Len() > 0 {
+			// Lists can only hold one tag, so every element has to match the first
+			if elemTag, err = dynamicTag(v.Index(0)); err != nil {
+				return err
+			}
+		}
+		if err = e.writeTag(elemTag); err != nil {
+			return err
+		}
+		if err = e.writeLength(v.Len()); err != nil {
+			return err
+		}
+		for i := 0; i < v.Len(); i++ {
+			if v.Index(i).Kind() == reflect.Interface {
+				if tag, err := dynamicTag(v.Index(i)); err != nil || tag != elemTag {
+					return eris.Errorf("element %v is not a %v", i, elemTag)
 				}
 			}
-		// Compound
-		case reflect.Struct:
-			if _, err = makeNamedTag(tags.Compound).WriteTo(e.buf); err != nil {
-				return
+			// Compound lists just have an END tag between each
+			if err = e.encodePayload(elemTag, v.Index(i)); err != nil {
+				return eris.Wrapf(err, "failed to encode element %v", i)
 			}
-			if err = e.EncodeValue(field); err != nil {
+		}
+		return nil
+	case tags.Compound:
+		if v.Kind() == reflect.Map {
+			return e.encodeMap(v)
+		}
+		return e.encodeStruct(v)
+	}
+	return eris.Errorf("unknown tag %v", byte(tag))
+}
+
+func (e *Encoder) encodeTree(name string, compound *Compound) error {
+	if err := e.writeNamedTag(tags.Compound, name); err != nil {
+		return err
+	}
+	return e.encodeField(compound)
+}
+
+// encodeField writes the payload of a tree node
+func (e *Encoder) encodeField(field Field) error {
+	switch f := field.(type) {
+	case *Compound:
+		for _, entry := range f.Entries {
+			if err := e.writeNamedTag(entry.Value.Tag(), entry.Name); err != nil {
 				return err
 			}
-		default:
-			return eris.Errorf("unknown type '%v'", field.Kind())
+			if err := e.encodeField(entry.Value); err != nil {
+				return eris.Wrapf(err, "failed to encode '%v'", entry.Name)
+			}
+		}
+		return e.writeTag(tags.End)
+	case *List:
+		if err := e.writeTag(f.ElemType); err != nil {
+			return err
+		}
+		if err := e.writeLength(len(f.Values)); err != nil {
+			return err
+		}
+		for i, value := range f.Values {
+			if err := e.encodeField(value); err != nil {
+				return eris.Wrapf(err, "failed to encode element %v", i)
+			}
 		}
+		return nil
+	case *Byte:
+		return e.writeByte(byte(*f))
+	case *Boolean:
+		return e.encodePayload(tags.Byte, reflect.ValueOf(bool(*f)))
+	case *Short:
+		return e.writeShort(uint16(*f))
+	case *UShort:
+		return e.writeShort(uint16(*f))
+	case *Int:
+		return e.writeInt(uint32(*f))
+	case *UInt:
+		return e.writeInt(uint32(*f))
+	case *Long:
+		return e.writeLong(uint64(*f))
+	case *ULong:
+		return e.writeLong(uint64(*f))
+	case *Float:
+		return e.writeFixed32(math.Float32bits(float32(*f)))
+	case *Double:
+		return e.writeFixed64(math.Float64bits(float64(*f)))
+	case *String:
+		return e.writeString(string(*f))
+	case *ByteArray:
+		return e.encodePayload(tags.ByteArray, reflect.ValueOf([]byte(*f)))
+	case *IntArray:
+		return e.encodePayload(tags.IntArray, reflect.ValueOf([]int32(*f)))
+	case *LongArray:
+		return e.encodePayload(tags.LongArray, reflect.ValueOf([]int64(*f)))
 	}
-	if fieldEncoder != nil {
-		if _, err = fieldEncoder.WriteTo(e.buf); err != nil {
-			return
+	return eris.Errorf("unknown field type %T", field)
+}
+
+// planFor returns the cached encoding and decoding plan for a struct type
+func planFor(typ reflect.Type) *structPlan {
+	if plan, ok := structPlans.Load(typ); ok {
+		return plan.(*structPlan)
+	}
+
+	plan := &structPlan{
+		byName:       map[string]int{},
+		byFoldedName: map[string]int{},
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		typeField := typ.Field(i)
+		if i == 0 && typeField.Name == "Root" {
+			continue
+		}
+		fieldTags := makeTags(typeField.Tag)
+		name := fieldTags.Name
+		if len(name) == 0 {
+			name = strings.ToLower(typeField.Name)
+		}
+
+		tag, err := tagFor(typeField.Type, fieldTags)
+		if _, ok := plan.byName[name]; !ok {
+			plan.byName[name] = len(plan.Fields)
 		}
+		if _, ok := plan.byFoldedName[strings.ToLower(name)]; !ok {
+			plan.byFoldedName[strings.ToLower(name)] = len(plan.Fields)
+		}
+		plan.Fields = append(plan.Fields, fieldPlan{
+			Index:    i,
+			Name:     name,
+			Optional: fieldTags.isOptional(),
+			Tag:      tag,
+			Tags:     fieldTags,
+			Err:      err,
+		})
 	}
-	return
+
+	actual, _ := structPlans.LoadOrStore(typ, plan)
+	return actual.(*structPlan)
+}
+
+// field looks up the field the encoder would have written under name, falling back to a case-insensitive match
+func (p *structPlan) field(name string) (fieldPlan, bool) {
+	if i, ok := p.byName[name]; ok {
+		return p.Fields[i], true
+	}
+	if i, ok := p.byFoldedName[strings.ToLower(name)]; ok {
+		return p.Fields[i], true
+	}
+	return fieldPlan{}, false
+}
+
+// tagFor picks the NBT tag a Go type is written as, End for interfaces whose tag depends on their value
+func tagFor(typ reflect.Type, fieldTags nbtTags) (tags.Tag, error) {
+	switch typ.Kind() {
+	case reflect.Ptr:
+		return tagFor(typ.Elem(), fieldTags)
+	case reflect.Interface:
+		return tags.End, nil
+	case reflect.Map:
+		if typ.Key().Kind() != reflect.String {
+			return tags.End, eris.Errorf("map keys must be strings, got '%v'", typ.Key().Kind())
+		}
+		return tags.Compound, nil
+	}
+	if typ.Kind() == reflect.Slice {
+		elemKind := typ.Elem().Kind()
+		if isList(elemKind, fieldTags) {
+			return tags.List, nil
+		}
+		switch elemKind {
+		case reflect.Uint8:
+			return tags.ByteArray, nil
+		case reflect.Int32:
+			return tags.IntArray, nil
+		case reflect.Int64:
+			return tags.LongArray, nil
+		}
+	}
+	if tag, ok := tags.TagMap[typ.Kind()]; ok {
+		return tag, nil
+	}
+	return tags.End, eris.Errorf("unknown type '%v'", typ.Kind())
+}
+
+// dynamicTag picks the tag for the value currently held in an interface
+func dynamicTag(v reflect.Value) (tags.Tag, error) {
+	value, ok := resolve(v)
+	if !ok {
+		return tags.End, eris.New("cannot encode nil")
+	}
+	return tagFor(value.Type(), nbtTags{})
+}
+
+// resolve follows pointers and interfaces to the value they hold, false if one of them is nil
+func resolve(v reflect.Value) (reflect.Value, bool) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return v, false
+		}
+		v = v.Elem()
+	}
+	return v, true
+}
+
+func intOrUint(v reflect.Value) int64 {
+	switch v.Kind() {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint())
+	}
+	return v.Int()
 }
 
 func isList(kind reflect.Kind, nbtTags nbtTags) bool {
@@ -218,7 +466,90 @@ func (tags nbtTags) isOptional() bool {
 	return tags.Optional == "true"
 }
 
-func (e *encoder) writeTag(tag tags.Tag) error {
-	_, err := tag.WriteTo(e.buf)
+func (e *Encoder) writeNamedTag(tag tags.Tag, name string) error {
+	if err := e.writeTag(tag); err != nil {
+		return err
+	}
+	return e.writeString(name)
+}
+
+func (e *Encoder) writeTag(tag tags.Tag) error {
+	return e.writer.WriteByte(byte(tag))
+}
+
+func (e *Encoder) writeByte(b byte) error {
+	return e.writer.WriteByte(b)
+}
+
+func (e *Encoder) writeShort(s uint16) error {
+	e.encoding.ByteOrder.PutUint16(e.scratch[:2], s)
+	_, err := e.writer.Write(e.scratch[:2])
+	return err
+}
+
+func (e *Encoder) writeInt(i uint32) error {
+	if e.encoding.VarInt {
+		return e.writeUvarint(zigZag(int64(int32(i))))
+	}
+	return e.writeFixed32(i)
+}
+
+func (e *Encoder) writeLong(l uint64) error {
+	if e.encoding.VarInt {
+		return e.writeUvarint(zigZag(int64(l)))
+	}
+	return e.writeFixed64(l)
+}
+
+// writeLength writes a List or array length
+func (e *Encoder) writeLength(n int) error {
+	return e.writeInt(uint32(n))
+}
+
+func (e *Encoder) writeStringLength(n int) error {
+	if e.encoding.VarInt {
+		return e.writeUvarint(uint64(n))
+	}
+	return e.writeShort(uint16(n))
+}
+
+func (e *Encoder) writeFixed32(i uint32) error {
+	e.encoding.ByteOrder.PutUint32(e.scratch[:4], i)
+	_, err := e.writer.Write(e.scratch[:4])
+	return err
+}
+
+func (e *Encoder) writeFixed64(l uint64) error {
+	e.encoding.ByteOrder.PutUint64(e.scratch[:8], l)
+	_, err := e.writer.Write(e.scratch[:8])
+	return err
+}
+
+func (e *Encoder) writeUvarint(u uint64) error {
+	n := binary.PutUvarint(e.scratch[:], u)
+	_, err := e.writer.Write(e.scratch[:n])
+	return err
+}
+
+func (e *Encoder) writeString(s string) error {
+	if isPlainASCII(s) || !e.encoding.ModifiedUTF8 {
+		if len(s) > MaxStringLength {
+			return &StringTooLongError{Length: len(s)}
+		}
+		if err := e.writeStringLength(len(s)); err != nil {
+			return err
+		}
+		_, err := e.writer.WriteString(s)
+		return err
+	}
+
+	bs := EncodeModifiedUTF8(s)
+	if len(bs) > MaxStringLength {
+		return &StringTooLongError{Length: len(bs)}
+	}
+	if err := e.writeStringLength(len(bs)); err != nil {
+		return err
+	}
+	_, err := e.writer.Write(bs)
 	return err
 }
@@ -2,132 +2,187 @@ package nbt
 
 import (
 	"github.com/stretchr/testify/assert"
+	"io"
 	"testing"
 )
 
-func TestMarshalToNBTTable(t *testing.T) {
-	type testCase struct {
-		Name           string
-		InputStruct    interface{}
-		ExpectedOutput []byte
-		Skip           bool
-	}
-	cases := []testCase{
-		{
-			Name: "Simple",
-			InputStruct: struct {
-				Root string
-				Name string `nbt:"name"`
-			}{
-				Root: "hello world",
-				Name: "Bananrama",
-			},
-			ExpectedOutput: []byte{
-				0x0a,       // Compound
-				0x00, 0x0b, // 11 Len
-				0x68, 0x65, 0x6c, 0x6c, 0x6f, 0x20, 0x77, 0x6f, 0x72, 0x6c, 0x64, // name: hello world
-				0x08,       // String
-				0x00, 0x04, // 4 Len
-				0x6e, 0x61, 0x6d, 0x65, // name: name
-				0x00, 0x09, // 9 Len
-				0x42, 0x61, 0x6e, 0x61, 0x6e, 0x72, 0x61, 0x6d, 0x61, // text: Bananrama
-				0x00, // Tag End
-			},
+type marshalTestCase struct {
+	Name           string
+	InputStruct    interface{}
+	ExpectedOutput []byte
+	Skip           bool
+}
+
+var marshalTestCases = []marshalTestCase{
+	{
+		Name: "Simple",
+		InputStruct: struct {
+			Root string
+			Name string `nbt:"name"`
+		}{
+			Root: "hello world",
+			Name: "Bananrama",
 		},
-		{
-			Name: "Long List",
-			InputStruct: struct {
-				Root string
-				List []int64 `nbt:"listTest (long)" nbt_type:"List"`
-			}{
-				List: []int64{11, 12, 13, 14, 15},
-			},
-			ExpectedOutput: []byte{
-				0x0a,       // Compound
-				0x00, 0x00, // 0 Len
-				0x09,       // List
-				0x00, 0x0f, // 15 Len
-				0x6c, 0x69, 0x73, 0x74, 0x54, 0x65, 0x73, 0x74, 0x20, 0x28, 0x6c, 0x6f, 0x6e, 0x67, 0x29, // listTest (long)
-				0x04,                   // Long
-				0x00, 0x00, 0x00, 0x05, // 5 Len
-				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0b, // 11
-				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0c, // 12
-				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0d, // 13
-				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0e, // 14
-				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0f, // 15
-				0x00, // Tag End
-			},
+		ExpectedOutput: []byte{
+			0x0a,       // Compound
+			0x00, 0x0b, // 11 Len
+			0x68, 0x65, 0x6c, 0x6c, 0x6f, 0x20, 0x77, 0x6f, 0x72, 0x6c, 0x64, // name: hello world
+			0x08,       // String
+			0x00, 0x04, // 4 Len
+			0x6e, 0x61, 0x6d, 0x65, // name: name
+			0x00, 0x09, // 9 Len
+			0x42, 0x61, 0x6e, 0x61, 0x6e, 0x72, 0x61, 0x6d, 0x61, // text: Bananrama
+			0x00, // Tag End
 		},
-		{
-			Name: "String List",
-			InputStruct: struct {
-				Strings []string
-			}{
-				Strings: []string{"hello", "world"},
-			},
-			ExpectedOutput: []byte{
-				0x0a,       // Compound
-				0x00, 0x00, // 0 Len
-				0x09,       // List
-				0x00, 0x07, // 7 Len
-				0x73, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x73, // strings
-				0x08,                   // String
-				0x00, 0x00, 0x00, 0x02, // 2 Len List
-				0x00, 0x05, // 5 Len Str
-				0x68, 0x65, 0x6c, 0x6c, 0x6f, // hello
-				0x00, 0x05, // 5 Len Str
-				0x77, 0x6f, 0x72, 0x6c, 0x64, // world
-				0x00, // Tag End
-			},
+	},
+	{
+		Name: "Long List",
+		InputStruct: struct {
+			Root string
+			List []int64 `nbt:"listTest (long)" nbt_type:"List"`
+		}{
+			List: []int64{11, 12, 13, 14, 15},
 		},
-		{
-			Name: "Compound List",
-			InputStruct: struct {
-				Compounds []struct {
-					Test uint8
-				}
-			}{
-				Compounds: []struct{ Test uint8 }{
-					{
-						Test: 1,
-					},
-					{
-						Test: 2,
-					},
-					{
-						Test: 3,
-					},
+		ExpectedOutput: []byte{
+			0x0a,       // Compound
+			0x00, 0x00, // 0 Len
+			0x09,       // List
+			0x00, 0x0f, // 15 Len
+			0x6c, 0x69, 0x73, 0x74, 0x54, 0x65, 0x73, 0x74, 0x20, 0x28, 0x6c, 0x6f, 0x6e, 0x67, 0x29, // listTest (long)
+			0x04,                   // Long
+			0x00, 0x00, 0x00, 0x05, // 5 Len
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0b, // 11
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0c, // 12
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0d, // 13
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0e, // 14
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0f, // 15
+			0x00, // Tag End
+		},
+	},
+	{
+		Name: "String List",
+		InputStruct: struct {
+			Strings []string
+		}{
+			Strings: []string{"hello", "world"},
+		},
+		ExpectedOutput: []byte{
+			0x0a,       // Compound
+			0x00, 0x00, // 0 Len
+			0x09,       // List
+			0x00, 0x07, // 7 Len
+			0x73, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x73, // strings
+			0x08,                   // String
+			0x00, 0x00, 0x00, 0x02, // 2 Len List
+			0x00, 0x05, // 5 Len Str
+			0x68, 0x65, 0x6c, 0x6c, 0x6f, // hello
+			0x00, 0x05, // 5 Len Str
+			0x77, 0x6f, 0x72, 0x6c, 0x64, // world
+			0x00, // Tag End
+		},
+	},
+	{
+		Name: "Compound List",
+		InputStruct: struct {
+			Compounds []struct {
+				Test uint8
+			}
+		}{
+			Compounds: []struct{ Test uint8 }{
+				{
+					Test: 1,
+				},
+				{
+					Test: 2,
+				},
+				{
+					Test: 3,
 				},
-			},
-			ExpectedOutput: []byte{
-				0x0a,       // Compound
-				0x00, 0x00, // 0 Len
-				0x09,       // List
-				0x00, 0x09, // 9 Len
-				0x63, 0x6f, 0x6d, 0x70, 0x6f, 0x75, 0x6e, 0x64, 0x73, // compounds
-				0x0a,                   // Compound
-				0x00, 0x00, 0x00, 0x03, // 3 Len List
-				0x01,       // Byte
-				0x00, 0x04, // 4 Len
-				0x74, 0x65, 0x73, 0x74, // test
-				0x01,       // 1
-				0x00,       // Tag End
-				0x01,       // Byte
-				0x00, 0x04, // 4 Len
-				0x74, 0x65, 0x73, 0x74, // test
-				0x02,       // 2
-				0x00,       // Tag End
-				0x01,       // Byte
-				0x00, 0x04, // 4 Len
-				0x74, 0x65, 0x73, 0x74, // test
-				0x03, // 3
-				0x00, // Tag End
-				0x00, // Tag End
 			},
 		},
-	}
+		ExpectedOutput: []byte{
+			0x0a,       // Compound
+			0x00, 0x00, // 0 Len
+			0x09,       // List
+			0x00, 0x09, // 9 Len
+			0x63, 0x6f, 0x6d, 0x70, 0x6f, 0x75, 0x6e, 0x64, 0x73, // compounds
+			0x0a,                   // Compound
+			0x00, 0x00, 0x00, 0x03, // 3 Len List
+			0x01,       // Byte
+			0x00, 0x04, // 4 Len
+			0x74, 0x65, 0x73, 0x74, // test
+			0x01,       // 1
+			0x00,       // Tag End
+			0x01,       // Byte
+			0x00, 0x04, // 4 Len
+			0x74, 0x65, 0x73, 0x74, // test
+			0x02,       // 2
+			0x00,       // Tag End
+			0x01,       // Byte
+			0x00, 0x04, // 4 Len
+			0x74, 0x65, 0x73, 0x74, // test
+			0x03, // 3
+			0x00, // Tag End
+			0x00, // Tag End
+		},
+	},
+	{
+		Name: "Map",
+		InputStruct: struct {
+			Counts map[string]int32 `nbt:"counts"`
+		}{
+			Counts: map[string]int32{"b": 2, "a": 1},
+		},
+		ExpectedOutput: []byte{
+			0x0a,       // Compound
+			0x00, 0x00, // 0 Len
+			0x0a,       // Compound
+			0x00, 0x06, // 6 Len
+			0x63, 0x6f, 0x75, 0x6e, 0x74, 0x73, // name: counts
+			0x03,       // Int
+			0x00, 0x01, // 1 Len
+			0x61,                   // name: a
+			0x00, 0x00, 0x00, 0x01, // 1
+			0x03,       // Int
+			0x00, 0x01, // 1 Len
+			0x62,                   // name: b
+			0x00, 0x00, 0x00, 0x02, // 2
+			0x00, // Tag End
+			0x00, // Tag End
+		},
+	},
+	{
+		Name: "Pointer",
+		InputStruct: struct {
+			Inner *struct {
+				X int16 `nbt:"x"`
+			} `nbt:"inner"`
+			Missing *struct {
+				X int16 `nbt:"x"`
+			} `nbt:"missing"`
+		}{
+			Inner: &struct {
+				X int16 `nbt:"x"`
+			}{X: 7},
+		},
+		ExpectedOutput: []byte{
+			0x0a,       // Compound
+			0x00, 0x00, // 0 Len
+			0x0a,       // Compound
+			0x00, 0x05, // 5 Len
+			0x69, 0x6e, 0x6e, 0x65, 0x72, // name: inner
+			0x02,       // Short
+			0x00, 0x01, // 1 Len
+			0x78,       // name: x
+			0x00, 0x07, // 7
+			0x00, // Tag End
+			0x00, // Tag End
+		},
+	},
+}
 
-	for _, test := range cases {
+func TestMarshalToNBTTable(t *testing.T) {
+	for _, test := range marshalTestCases {
 		if test.Skip {
 			continue
 		}
@@ -187,5 +242,115 @@ func TestMarshalToNBT2(t *testing.T) {
 	n := BiomeRegistryNBT{}
 	bs, err := MarshalToNBT(&n)
 	assert.NoError(t, err)
-	assert.Equal(t, []byte{0xa, 0x0, 0x0, 0x8, 0x0, 0x4, 0x74, 0x79, 0x70, 0x65, 0x0, 0x0, 0xa, 0x0, 0x5, 0x76, 0x61, 0x6c, 0x75, 0x65, 0xa, 0x0, 0x0, 0x8, 0x0, 0x4, 0x6e, 0x61, 0x6d, 0x65, 0x0, 0x0, 0x3, 0x0, 0x2, 0x69, 0x64, 0x0, 0x0, 0x0, 0x0, 0xa, 0x0, 0x7, 0x65, 0x6c, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0xa, 0x0, 0x0, 0x8, 0x0, 0xd, 0x70, 0x72, 0x65, 0x63, 0x69, 0x70, 0x69, 0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x0, 0x0, 0x5, 0x0, 0x5, 0x64, 0x65, 0x70, 0x74, 0x68, 0x0, 0x0, 0x0, 0x0, 0x5, 0x0, 0xb, 0x74, 0x65, 0x6d, 0x70, 0x65, 0x72, 0x61, 0x74, 0x75, 0x72, 0x65, 0x0, 0x0, 0x0, 0x0, 0x5, 0x0, 0x5, 0x73, 0x63, 0x61, 0x6c, 0x65, 0x0, 0x0, 0x0, 0x0, 0x5, 0x0, 0x8, 0x64, 0x6f, 0x77, 0x6e, 0x66, 0x61, 0x6c, 0x6c, 0x0, 0x0, 0x0, 0x0, 0x8, 0x0, 0x8, 0x63, 0x61, 0x74, 0x65, 0x67, 0x6f, 0x72, 0x79, 0x0, 0x0, 0xa, 0x0, 0x7, 0x65, 0x66, 0x66, 0x65, 0x63, 0x74, 0x73, 0xa, 0x0, 0x0, 0x3, 0x0, 0x9, 0x73, 0x6b, 0x79, 0x5f, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x0, 0x0, 0x0, 0x0, 0x3, 0x0, 0xf, 0x77, 0x61, 0x74, 0x65, 0x72, 0x5f, 0x66, 0x6f, 0x67, 0x5f, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x0, 0x0, 0x0, 0x0, 0x3, 0x0, 0x9, 0x66, 0x6f, 0x67, 0x5f, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x0, 0x0, 0x0, 0x0, 0x3, 0x0, 0xb, 0x77, 0x61, 0x74, 0x65, 0x72, 0x5f, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x0, 0x0, 0x0, 0x0, 0xa, 0x0, 0x5, 0x6d, 0x75, 0x73, 0x69, 0x63, 0xa, 0x0, 0x0, 0x1, 0x0, 0x15, 0x72, 0x65, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x5f, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x5f, 0x6d, 0x75, 0x73, 0x69, 0x63, 0x0, 0x8, 0x0, 0x5, 0x73, 0x6f, 0x75, 0x6e, 0x64, 0x0, 0x0, 0x3, 0x0, 0x9, 0x6d, 0x61, 0x78, 0x5f, 0x64, 0x65, 0x6c, 0x61, 0x79, 0x0, 0x0, 0x0, 0x0, 0x3, 0x0, 0x9, 0x6d, 0x69, 0x6e, 0x5f, 0x64, 0x65, 0x6c, 0x61, 0x79, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0}, bs)
+	assert.Equal(t, []byte{0xa, 0x0, 0x0, 0x8, 0x0, 0x4, 0x74, 0x79, 0x70, 0x65, 0x0, 0x0, 0xa, 0x0, 0x5, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x8, 0x0, 0x4, 0x6e, 0x61, 0x6d, 0x65, 0x0, 0x0, 0x3, 0x0, 0x2, 0x69, 0x64, 0x0, 0x0, 0x0, 0x0, 0xa, 0x0, 0x7, 0x65, 0x6c, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x8, 0x0, 0xd, 0x70, 0x72, 0x65, 0x63, 0x69, 0x70, 0x69, 0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x0, 0x0, 0x5, 0x0, 0x5, 0x64, 0x65, 0x70, 0x74, 0x68, 0x0, 0x0, 0x0, 0x0, 0x5, 0x0, 0xb, 0x74, 0x65, 0x6d, 0x70, 0x65, 0x72, 0x61, 0x74, 0x75, 0x72, 0x65, 0x0, 0x0, 0x0, 0x0, 0x5, 0x0, 0x5, 0x73, 0x63, 0x61, 0x6c, 0x65, 0x0, 0x0, 0x0, 0x0, 0x5, 0x0, 0x8, 0x64, 0x6f, 0x77, 0x6e, 0x66, 0x61, 0x6c, 0x6c, 0x0, 0x0, 0x0, 0x0, 0x8, 0x0, 0x8, 0x63, 0x61, 0x74, 0x65, 0x67, 0x6f, 0x72, 0x79, 0x0, 0x0, 0xa, 0x0, 0x7, 0x65, 0x66, 0x66, 0x65, 0x63, 0x74, 0x73, 0x3, 0x0, 0x9, 0x73, 0x6b, 0x79, 0x5f, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x0, 0x0, 0x0, 0x0, 0x3, 0x0, 0xf, 0x77, 0x61, 0x74, 0x65, 0x72, 0x5f, 0x66, 0x6f, 0x67, 0x5f, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x0, 0x0, 0x0, 0x0, 0x3, 0x0, 0x9, 0x66, 0x6f, 0x67, 0x5f, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x0, 0x0, 0x0, 0x0, 0x3, 0x0, 0xb, 0x77, 0x61, 0x74, 0x65, 0x72, 0x5f, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x0, 0x0, 0x0, 0x0, 0xa, 0x0, 0x5, 0x6d, 0x75, 0x73, 0x69, 0x63, 0x1, 0x0, 0x15, 0x72, 0x65, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x5f, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x5f, 0x6d, 0x75, 0x73, 0x69, 0x63, 0x0, 0x8, 0x0, 0x5, 0x73, 0x6f, 0x75, 0x6e, 0x64, 0x0, 0x0, 0x3, 0x0, 0x9, 0x6d, 0x61, 0x78, 0x5f, 0x64, 0x65, 0x6c, 0x61, 0x79, 0x0, 0x0, 0x0, 0x0, 0x3, 0x0, 0x9, 0x6d, 0x69, 0x6e, 0x5f, 0x64, 0x65, 0x6c, 0x61, 0x79, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0}, bs)
+}
+
+func TestMarshalInterfaceFields(t *testing.T) {
+	type testStruct struct {
+		Value interface{}   `nbt:"value"`
+		List  []interface{} `nbt:"list"`
+		Nil   interface{}   `nbt:"nil"`
+	}
+	bs, err := MarshalToNBT(testStruct{
+		Value: int32(5),
+		List:  []interface{}{"a", "b"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x0a, 0x00, 0x00,
+		0x03, 0x00, 0x05, 'v', 'a', 'l', 'u', 'e', 0x00, 0x00, 0x00, 0x05,
+		0x09, 0x00, 0x04, 'l', 'i', 's', 't', 0x08, 0x00, 0x00, 0x00, 0x02, 0x00, 0x01, 'a', 0x00, 0x01, 'b',
+		0x00,
+	}, bs)
+
+	_, err = MarshalToNBT(testStruct{List: []interface{}{"a", int32(1)}})
+	assert.Error(t, err, "list elements must share a tag")
+}
+
+func TestMarshalMapSortsKeys(t *testing.T) {
+	values := map[string]interface{}{}
+	for _, name := range []string{"minecraft:plains", "minecraft:desert", "custom:biome", "minecraft:ocean"} {
+		values[name] = name
+	}
+	first, err := MarshalToNBT(values)
+	assert.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		bs, err := MarshalToNBT(values)
+		assert.NoError(t, err)
+		assert.Equal(t, first, bs)
+	}
+
+	var tree Compound
+	assert.NoError(t, Unmarshal(first, &tree))
+	var names []string
+	for _, entry := range tree.Entries {
+		names = append(names, entry.Name)
+	}
+	assert.Equal(t, []string{"custom:biome", "minecraft:desert", "minecraft:ocean", "minecraft:plains"}, names)
+
+	_, err = MarshalToNBT(struct {
+		Values map[int]int32
+	}{})
+	assert.Error(t, err, "map keys must be strings")
+}
+
+type benchmarkElement struct {
+	Name    string
+	Id      int32
+	Element struct {
+		Precipitation string
+		Temperature   float32
+		Downfall      float32
+		Effects       struct {
+			SkyColor   int32 `nbt:"sky_color"`
+			WaterColor int32 `nbt:"water_color"`
+			Sound      string
+			TickChance float64 `nbt:"tick_chance"`
+		}
+	}
+}
+
+type benchmarkCodec struct {
+	Type    string
+	Value   []benchmarkElement
+	Heights []int64
+}
+
+func makeBenchmarkCodec() *benchmarkCodec {
+	codec := &benchmarkCodec{Type: "minecraft:worldgen/biome"}
+	for i := 0; i < 1000; i++ {
+		var element benchmarkElement
+		element.Name = "minecraft:biome"
+		element.Id = int32(i)
+		element.Element.Precipitation = "rain"
+		element.Element.Effects.Sound = "minecraft:ambient.cave"
+		codec.Value = append(codec.Value, element)
+	}
+	codec.Heights = make([]int64, 4096)
+	return codec
+}
+
+// BenchmarkMarshalToNBT buffers the whole document before writing it on, BenchmarkEncoder streams the same value
+func BenchmarkMarshalToNBT(b *testing.B) {
+	codec := makeBenchmarkCodec()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		bs, err := MarshalToNBT(codec)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err = io.Discard.Write(bs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncoder(b *testing.B) {
+	codec := makeBenchmarkCodec()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := NewEncoder(io.Discard).Encode(codec); err != nil {
+			b.Fatal(err)
+		}
+	}
 }
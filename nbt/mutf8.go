@@ -0,0 +1,111 @@
+package nbt
+
+import (
+	"fmt"
+	"github.com/rotisserie/eris"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// https://docs.oracle.com/javase/8/docs/api/java/io/DataInput.html#modified-utf-8
+
+// MaxStringLength is the most encoded bytes the UShort length prefix of a String can describe
+const MaxStringLength = 65535
+
+// StringTooLongError is returned when a String encodes to more than MaxStringLength bytes
+type StringTooLongError struct {
+	Length int
+}
+
+func (e *StringTooLongError) Error() string {
+	return fmt.Sprintf("nbt: string encodes to %v bytes, more than the maximum of %v", e.Length, MaxStringLength)
+}
+
+// EncodeModifiedUTF8 converts s to Java's modified UTF-8, where NUL is 0xC0 0x80 and
+// supplementary characters are written as a 3 byte encoded surrogate pair
+func EncodeModifiedUTF8(s string) []byte {
+	if isPlainASCII(s) {
+		return []byte(s)
+	}
+
+	bs := make([]byte, 0, len(s)+len(s)/2)
+	for _, r := range s {
+		switch {
+		case r == 0:
+			bs = append(bs, 0xC0, 0x80)
+		case r < 0x80:
+			bs = append(bs, byte(r))
+		case r < 0x800:
+			bs = append(bs, 0xC0|byte(r>>6), 0x80|byte(r&0x3F))
+		case r < 0x10000:
+			bs = appendThreeBytes(bs, r)
+		default:
+			high, low := utf16.EncodeRune(r)
+			bs = appendThreeBytes(appendThreeBytes(bs, high), low)
+		}
+	}
+	return bs
+}
+
+func appendThreeBytes(bs []byte, r rune) []byte {
+	return append(bs, 0xE0|byte(r>>12), 0x80|byte(r>>6&0x3F), 0x80|byte(r&0x3F))
+}
+
+// DecodeModifiedUTF8 converts Java's modified UTF-8 back into a Go string, unpaired surrogates become utf8.RuneError
+func DecodeModifiedUTF8(bs []byte) (string, error) {
+	if isPlainASCII(string(bs)) {
+		return string(bs), nil
+	}
+
+	runes := make([]rune, 0, len(bs))
+	for i := 0; i < len(bs); {
+		b := bs[i]
+		switch {
+		case b < 0x80:
+			runes = append(runes, rune(b))
+			i++
+		case b&0xE0 == 0xC0:
+			if i+1 >= len(bs) || bs[i+1]&0xC0 != 0x80 {
+				return "", eris.Errorf("invalid modified UTF-8 at byte %v", i)
+			}
+			runes = append(runes, rune(b&0x1F)<<6|rune(bs[i+1]&0x3F))
+			i += 2
+		case b&0xF0 == 0xE0:
+			if i+2 >= len(bs) || bs[i+1]&0xC0 != 0x80 || bs[i+2]&0xC0 != 0x80 {
+				return "", eris.Errorf("invalid modified UTF-8 at byte %v", i)
+			}
+			runes = append(runes, rune(b&0x0F)<<12|rune(bs[i+1]&0x3F)<<6|rune(bs[i+2]&0x3F))
+			i += 3
+		default:
+			return "", eris.Errorf("invalid modified UTF-8 at byte %v", i)
+		}
+	}
+
+	// Combine surrogate pairs back into supplementary characters
+	decoded := make([]rune, 0, len(runes))
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if utf16.IsSurrogate(r) {
+			if i+1 < len(runes) {
+				if combined := utf16.DecodeRune(r, runes[i+1]); combined != utf8.RuneError {
+					decoded = append(decoded, combined)
+					i++
+					continue
+				}
+			}
+			r = utf8.RuneError
+		}
+		decoded = append(decoded, r)
+	}
+	return string(decoded), nil
+}
+
+// isPlainASCII reports whether s has the same bytes in UTF-8 and modified UTF-8
+func isPlainASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] == 0 || s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,69 @@
+package nbt
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestModifiedUTF8(t *testing.T) {
+	type testCase struct {
+		Name    string
+		Value   string
+		Encoded []byte
+	}
+	cases := []testCase{
+		{Name: "ASCII", Value: "hello", Encoded: []byte("hello")},
+		{Name: "NUL", Value: "a\x00b", Encoded: []byte{'a', 0xC0, 0x80, 'b'}},
+		{Name: "Two byte", Value: "é", Encoded: []byte{0xC3, 0xA9}},
+		{Name: "Three byte", Value: "€", Encoded: []byte{0xE2, 0x82, 0xAC}},
+		{Name: "Emoji", Value: "😀", Encoded: []byte{0xED, 0xA0, 0xBD, 0xED, 0xB8, 0x80}},
+	}
+
+	for _, test := range cases {
+		assert.Equal(t, test.Encoded, EncodeModifiedUTF8(test.Value), test.Name)
+		decoded, err := DecodeModifiedUTF8(test.Encoded)
+		assert.NoError(t, err, test.Name)
+		assert.Equal(t, test.Value, decoded, test.Name)
+
+		buf := bytes.NewBuffer(nil)
+		_, err = String(test.Value).WriteTo(buf)
+		assert.NoError(t, err, test.Name)
+		assert.Equal(t, append([]byte{0, byte(len(test.Encoded))}, test.Encoded...), buf.Bytes(), test.Name)
+
+		var s String
+		_, err = s.ReadFrom(buf)
+		assert.NoError(t, err, test.Name)
+		assert.Equal(t, String(test.Value), s, test.Name)
+	}
+}
+
+func TestModifiedUTF8Invalid(t *testing.T) {
+	for _, encoded := range [][]byte{{0xC3}, {0xE2, 0x82}, {0xFF}, {0xC3, 0x41}} {
+		_, err := DecodeModifiedUTF8(encoded)
+		assert.Error(t, err, "%x", encoded)
+	}
+
+	// An unpaired surrogate can't be held in a Go string
+	decoded, err := DecodeModifiedUTF8([]byte{0xED, 0xA0, 0xBD, 'a'})
+	assert.NoError(t, err)
+	assert.Equal(t, "�a", decoded)
+}
+
+func TestStringTooLong(t *testing.T) {
+	_, err := String(strings.Repeat("a", MaxStringLength)).WriteTo(bytes.NewBuffer(nil))
+	assert.NoError(t, err)
+
+	// Each NUL takes 2 bytes once encoded
+	_, err = String(strings.Repeat("\x00", MaxStringLength/2+1)).WriteTo(bytes.NewBuffer(nil))
+	var tooLong *StringTooLongError
+	assert.True(t, errors.As(err, &tooLong))
+	assert.Equal(t, MaxStringLength+1, tooLong.Length)
+
+	_, err = MarshalToNBT(struct {
+		Name string
+	}{Name: strings.Repeat("é", MaxStringLength)})
+	assert.Error(t, err)
+}
@@ -0,0 +1,662 @@
+package nbt
+
+import (
+	"encoding/binary"
+	"github.com/rotisserie/eris"
+	"io"
+	"math"
+	"minecraftServer/nbt/tags"
+)
+
+// maxPreallocated is the most elements allocated for an array or List before any of them have been read
+const maxPreallocated = 4096
+
+type (
+	// Reader walks an NBT document one tag at a time, so callers can pick out a few values and skip the rest
+	// without decoding the whole document. Next returns each tag's header, the value is then read with one of
+	// the Read methods or discarded with Skip. Compounds and Lists are entered by Next and finish with an End header.
+	Reader struct {
+		reader   io.Reader
+		encoding Encoding
+		scratch  [8]byte
+		discard  [512]byte
+
+		frames  []readerFrame
+		current pathElem
+		// pending is the tag whose payload hasn't been read yet, End if there isn't one
+		pending tags.Tag
+		// pendingLen is the element count of a pending array
+		pendingLen int
+		// entered is set when the last header opened a Compound or List
+		entered bool
+		started bool
+	}
+
+	// Header describes the next tag in the document
+	Header struct {
+		Tag tags.Tag
+		// Name is empty for list elements
+		Name string
+		// Index is the position in the enclosing List, or -1 inside a Compound
+		Index int
+		// ElemType is the element tag of a List
+		ElemType tags.Tag
+		// Len is the element count of a List or array
+		Len int
+	}
+
+	readerFrame struct {
+		Tag       tags.Tag
+		Elem      pathElem
+		ElemType  tags.Tag
+		Len       int
+		Remaining int
+	}
+)
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{
+		reader:   r,
+		encoding: JavaEncoding,
+	}
+}
+
+// SetEncoding changes the byte order and integer encoding used from the next read on, the default is JavaEncoding
+func (r *Reader) SetEncoding(encoding Encoding) {
+	r.encoding = encoding
+}
+
+// reset readies the reader for another document from the same stream
+func (r *Reader) reset() {
+	r.frames = r.frames[:0]
+	r.current = pathElem{}
+	r.pending, r.pendingLen = tags.End, 0
+	r.entered, r.started = false, false
+}
+
+// Next reads the next tag header, discarding any value left unread. It returns io.EOF once the root compound has ended
+func (r *Reader) Next() (Header, error) {
+	if r.pending != tags.End {
+		if err := r.Skip(); err != nil {
+			return Header{}, err
+		}
+	}
+	r.entered = false
+
+	if !r.started {
+		r.started = true
+		tag, err := r.readTag()
+		if err != nil {
+			return Header{}, err
+		}
+		name, err := r.readString()
+		if err != nil {
+			return Header{}, eris.Wrap(err, "failed to read root name")
+		}
+		return r.open(Header{Tag: tag, Name: name, Index: -1})
+	}
+
+	if len(r.frames) == 0 {
+		return Header{}, io.EOF
+	}
+
+	frame := &r.frames[len(r.frames)-1]
+	if frame.Tag == tags.List {
+		if frame.Remaining == 0 {
+			r.frames = r.frames[:len(r.frames)-1]
+			r.current = pathElem{}
+			return Header{Tag: tags.End, Index: -1}, nil
+		}
+		index := frame.Len - frame.Remaining
+		frame.Remaining--
+		return r.open(Header{Tag: frame.ElemType, Index: index})
+	}
+
+	tag, err := r.readTag()
+	if err != nil {
+		return Header{}, eris.Wrapf(err, "failed to read tag at '%v'", r.Path())
+	}
+	if tag == tags.End {
+		r.frames = r.frames[:len(r.frames)-1]
+		r.current = pathElem{}
+		return Header{Tag: tags.End, Index: -1}, nil
+	}
+	name, err := r.readString()
+	if err != nil {
+		return Header{}, eris.Wrapf(err, "failed to read name at '%v'", r.Path())
+	}
+	return r.open(Header{Tag: tag, Name: name, Index: -1})
+}
+
+// open records header as the current tag, entering it if it's a container
+func (r *Reader) open(header Header) (Header, error) {
+	if header.Index >= 0 {
+		r.current = pathElem{Index: header.Index, IsIndex: true}
+	} else {
+		r.current = pathElem{Name: header.Name}
+	}
+
+	switch header.Tag {
+	case tags.Compound:
+		r.push(readerFrame{Tag: tags.Compound})
+	case tags.List:
+		elemType, err := r.readTag()
+		if err != nil {
+			return header, err
+		}
+		length, err := r.readLength()
+		if err != nil {
+			return header, err
+		}
+		header.ElemType, header.Len = elemType, length
+		r.push(readerFrame{Tag: tags.List, ElemType: elemType, Len: length, Remaining: length})
+	case tags.ByteArray, tags.IntArray, tags.LongArray:
+		length, err := r.readLength()
+		if err != nil {
+			return header, err
+		}
+		header.Len = length
+		r.pending, r.pendingLen = header.Tag, length
+	case tags.Byte, tags.Short, tags.Int, tags.Long, tags.Float, tags.Double, tags.String:
+		r.pending = header.Tag
+	default:
+		return header, eris.Errorf("unknown tag %v at '%v'", byte(header.Tag), r.Path())
+	}
+	return header, nil
+}
+
+func (r *Reader) push(frame readerFrame) {
+	frame.Elem = r.current
+	r.frames = append(r.frames, frame)
+	r.current = pathElem{}
+	r.entered = true
+}
+
+// Path returns the location of the last header, like Level.Sections[3].BlockStates
+func (r *Reader) Path() string {
+	var elems []pathElem
+	// The root's own name isn't part of the path
+	for i, frame := range r.frames {
+		if i > 0 {
+			elems = append(elems, frame.Elem)
+		}
+	}
+	if r.current.IsIndex || len(r.current.Name) > 0 {
+		elems = append(elems, r.current)
+	}
+	return joinPath(elems)
+}
+
+// Depth returns how many containers the reader is inside, the root compound counts as 1
+func (r *Reader) Depth() int {
+	return len(r.frames)
+}
+
+// Skip discards the value of the last header, including the whole subtree if it was a Compound or List
+func (r *Reader) Skip() error {
+	if r.pending != tags.End {
+		return r.skipPending()
+	}
+	if !r.entered {
+		return eris.New("no value to skip")
+	}
+	r.entered = false
+
+	frame := r.frames[len(r.frames)-1]
+	var err error
+	if frame.Tag == tags.List {
+		for i := 0; i < frame.Remaining && err == nil; i++ {
+			err = r.skipPayload(frame.ElemType)
+		}
+	} else {
+		err = r.skipCompound()
+	}
+	r.frames = r.frames[:len(r.frames)-1]
+	return err
+}
+
+func (r *Reader) skipPending() error {
+	tag := r.pending
+	r.pending = tags.End
+	switch tag {
+	case tags.ByteArray, tags.IntArray, tags.LongArray:
+		return r.skipArray(tag, r.pendingLen)
+	}
+	return r.skipPayload(tag)
+}
+
+func (r *Reader) skipPayload(tag tags.Tag) error {
+	switch tag {
+	case tags.Byte:
+		return r.discardN(1)
+	case tags.Short:
+		return r.discardN(2)
+	case tags.Int, tags.Long:
+		if r.encoding.VarInt {
+			_, err := r.readUvarint()
+			return err
+		}
+		return r.discardN(arrayElemSize(tag))
+	case tags.Float:
+		return r.discardN(4)
+	case tags.Double:
+		return r.discardN(8)
+	case tags.String:
+		length, err := r.readStringLength()
+		if err != nil {
+			return err
+		}
+		return r.discardN(int64(length))
+	case tags.ByteArray, tags.IntArray, tags.LongArray:
+		length, err := r.readLength()
+		if err != nil {
+			return err
+		}
+		return r.skipArray(tag, length)
+	case tags.List:
+		elemType, err := r.readTag()
+		if err != nil {
+			return err
+		}
+		length, err := r.readLength()
+		if err != nil {
+			return err
+		}
+		for i := 0; i < length; i++ {
+			if err = r.skipPayload(elemType); err != nil {
+				return err
+			}
+		}
+		return nil
+	case tags.Compound:
+		return r.skipCompound()
+	}
+	return eris.Errorf("unknown tag %v", byte(tag))
+}
+
+func (r *Reader) skipCompound() error {
+	for {
+		tag, err := r.readTag()
+		if err != nil {
+			return err
+		}
+		if tag == tags.End {
+			return nil
+		}
+		if err = r.skipPayload(tags.String); err != nil {
+			return err
+		}
+		if err = r.skipPayload(tag); err != nil {
+			return err
+		}
+	}
+}
+
+func (r *Reader) skipArray(tag tags.Tag, length int) error {
+	if tag == tags.ByteArray || !r.encoding.VarInt {
+		return r.discardN(int64(length) * arrayElemSize(tag))
+	}
+	// Varint elements have to be read one at a time to find where they end
+	for i := 0; i < length; i++ {
+		if _, err := r.readUvarint(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func arrayElemSize(tag tags.Tag) int64 {
+	switch tag {
+	case tags.Int, tags.IntArray:
+		return 4
+	case tags.Long, tags.LongArray:
+		return 8
+	}
+	return 1
+}
+
+// discardN throws away n bytes through a fixed buffer so skipping never allocates
+func (r *Reader) discardN(n int64) error {
+	if discarder, ok := r.reader.(interface{ Discard(int) (int, error) }); ok && n <= math.MaxInt32 {
+		_, err := discarder.Discard(int(n))
+		return err
+	}
+	for n > 0 {
+		chunk := r.discard[:]
+		if n < int64(len(chunk)) {
+			chunk = chunk[:n]
+		}
+		nn, err := io.ReadFull(r.reader, chunk)
+		if err != nil {
+			return err
+		}
+		n -= int64(nn)
+	}
+	return nil
+}
+
+func (r *Reader) take(tag tags.Tag) error {
+	if r.pending != tag {
+		if r.pending == tags.End {
+			return eris.Errorf("no %v to read at '%v'", tag, r.Path())
+		}
+		return eris.Errorf("cannot read %v as %v at '%v'", r.pending, tag, r.Path())
+	}
+	r.pending = tags.End
+	return nil
+}
+
+func (r *Reader) takeArray(tag tags.Tag) (int, error) {
+	if err := r.take(tag); err != nil {
+		return 0, err
+	}
+	return r.pendingLen, nil
+}
+
+func (r *Reader) ReadByte() (byte, error) {
+	if err := r.take(tags.Byte); err != nil {
+		return 0, err
+	}
+	return r.readByte()
+}
+
+func (r *Reader) ReadShort() (int16, error) {
+	if err := r.take(tags.Short); err != nil {
+		return 0, err
+	}
+	s, err := r.readShort()
+	return int16(s), err
+}
+
+func (r *Reader) ReadInt() (int32, error) {
+	if err := r.take(tags.Int); err != nil {
+		return 0, err
+	}
+	i, err := r.readInt()
+	return int32(i), err
+}
+
+func (r *Reader) ReadLong() (int64, error) {
+	if err := r.take(tags.Long); err != nil {
+		return 0, err
+	}
+	l, err := r.readLong()
+	return int64(l), err
+}
+
+func (r *Reader) ReadFloat() (float32, error) {
+	if err := r.take(tags.Float); err != nil {
+		return 0, err
+	}
+	i, err := r.readFixed32()
+	return math.Float32frombits(i), err
+}
+
+func (r *Reader) ReadDouble() (float64, error) {
+	if err := r.take(tags.Double); err != nil {
+		return 0, err
+	}
+	l, err := r.readFixed64()
+	return math.Float64frombits(l), err
+}
+
+func (r *Reader) ReadString() (string, error) {
+	if err := r.take(tags.String); err != nil {
+		return "", err
+	}
+	return r.readString()
+}
+
+func (r *Reader) ReadByteArray() ([]byte, error) {
+	length, err := r.takeArray(tags.ByteArray)
+	if err != nil {
+		return nil, err
+	}
+	return readBytes(r.reader, length)
+}
+
+func (r *Reader) ReadIntArray() ([]int32, error) {
+	length, err := r.takeArray(tags.IntArray)
+	if err != nil {
+		return nil, err
+	}
+	arr := make([]int32, 0, preallocated(length))
+	for i := 0; i < length; i++ {
+		n, err := r.readInt()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, int32(n))
+	}
+	return arr, nil
+}
+
+func (r *Reader) ReadLongArray() ([]int64, error) {
+	length, err := r.takeArray(tags.LongArray)
+	if err != nil {
+		return nil, err
+	}
+	arr := make([]int64, 0, preallocated(length))
+	for i := 0; i < length; i++ {
+		n, err := r.readLong()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, int64(n))
+	}
+	return arr, nil
+}
+
+// ReadField reads the value of the last header as a tree node, including the whole subtree of a Compound or List
+func (r *Reader) ReadField() (Field, error) {
+	switch r.pending {
+	case tags.Byte:
+		b, err := r.ReadByte()
+		f := Byte(b)
+		return &f, err
+	case tags.Short:
+		s, err := r.ReadShort()
+		f := Short(s)
+		return &f, err
+	case tags.Int:
+		i, err := r.ReadInt()
+		f := Int(i)
+		return &f, err
+	case tags.Long:
+		l, err := r.ReadLong()
+		f := Long(l)
+		return &f, err
+	case tags.Float:
+		fl, err := r.ReadFloat()
+		f := Float(fl)
+		return &f, err
+	case tags.Double:
+		d, err := r.ReadDouble()
+		f := Double(d)
+		return &f, err
+	case tags.String:
+		s, err := r.ReadString()
+		f := String(s)
+		return &f, err
+	case tags.ByteArray:
+		ba, err := r.ReadByteArray()
+		f := ByteArray(ba)
+		return &f, err
+	case tags.IntArray:
+		ia, err := r.ReadIntArray()
+		f := IntArray(ia)
+		return &f, err
+	case tags.LongArray:
+		la, err := r.ReadLongArray()
+		f := LongArray(la)
+		return &f, err
+	}
+	if !r.entered {
+		return nil, eris.New("no value to read")
+	}
+
+	frame := r.frames[len(r.frames)-1]
+	if frame.Tag == tags.List {
+		list := &List{ElemType: frame.ElemType}
+		for {
+			header, err := r.Next()
+			if err != nil {
+				return nil, err
+			}
+			if header.Tag == tags.End {
+				return list, nil
+			}
+			value, err := r.ReadField()
+			if err != nil {
+				return nil, eris.Wrapf(err, "failed to read element %v", header.Index)
+			}
+			list.Values = append(list.Values, value)
+		}
+	}
+
+	compound := &Compound{}
+	for {
+		header, err := r.Next()
+		if err != nil {
+			return nil, err
+		}
+		if header.Tag == tags.End {
+			return compound, nil
+		}
+		value, err := r.ReadField()
+		if err != nil {
+			return nil, eris.Wrapf(err, "failed to read '%v'", header.Name)
+		}
+		compound.Entries = append(compound.Entries, Entry{Name: header.Name, Value: value})
+	}
+}
+
+func (r *Reader) readTag() (tags.Tag, error) {
+	b, err := r.readByte()
+	return tags.Tag(b), err
+}
+
+func (r *Reader) readByte() (byte, error) {
+	if byteReader, ok := r.reader.(io.ByteReader); ok {
+		return byteReader.ReadByte()
+	}
+	_, err := io.ReadFull(r.reader, r.scratch[:1])
+	return r.scratch[0], err
+}
+
+func (r *Reader) readShort() (uint16, error) {
+	if _, err := io.ReadFull(r.reader, r.scratch[:2]); err != nil {
+		return 0, err
+	}
+	return r.encoding.ByteOrder.Uint16(r.scratch[:2]), nil
+}
+
+func (r *Reader) readInt() (uint32, error) {
+	if r.encoding.VarInt {
+		u, err := r.readUvarint()
+		return uint32(unZigZag(u)), err
+	}
+	return r.readFixed32()
+}
+
+func (r *Reader) readLong() (uint64, error) {
+	if r.encoding.VarInt {
+		u, err := r.readUvarint()
+		return uint64(unZigZag(u)), err
+	}
+	return r.readFixed64()
+}
+
+// readLength reads a List or array length, treating negative lengths as empty like vanilla does
+func (r *Reader) readLength() (int, error) {
+	length, err := r.readInt()
+	if err != nil {
+		return 0, err
+	}
+	if int32(length) < 0 {
+		return 0, nil
+	}
+	return int(length), nil
+}
+
+// readBytes reads n bytes, allocating for them as they arrive
+func readBytes(reader io.Reader, n int) ([]byte, error) {
+	bs := make([]byte, 0, preallocated(n))
+	for len(bs) < n {
+		start := len(bs)
+		bs = append(bs, make([]byte, preallocated(n-start))...)
+		if _, err := io.ReadFull(reader, bs[start:]); err != nil {
+			return nil, err
+		}
+	}
+	return bs, nil
+}
+
+// preallocated caps the capacity allocated up front for a length read off the wire, a few bytes claiming billions of
+// elements shouldn't cost gigabytes before the input runs out
+func preallocated(length int) int {
+	if length > maxPreallocated {
+		return maxPreallocated
+	}
+	return length
+}
+
+func (r *Reader) readStringLength() (int, error) {
+	if !r.encoding.VarInt {
+		length, err := r.readShort()
+		return int(length), err
+	}
+	length, err := r.readUvarint()
+	if err != nil {
+		return 0, err
+	}
+	if length > MaxStringLength {
+		return 0, &StringTooLongError{Length: int(length)}
+	}
+	return int(length), nil
+}
+
+func (r *Reader) readFixed32() (uint32, error) {
+	if _, err := io.ReadFull(r.reader, r.scratch[:4]); err != nil {
+		return 0, err
+	}
+	return r.encoding.ByteOrder.Uint32(r.scratch[:4]), nil
+}
+
+func (r *Reader) readFixed64() (uint64, error) {
+	if _, err := io.ReadFull(r.reader, r.scratch[:8]); err != nil {
+		return 0, err
+	}
+	return r.encoding.ByteOrder.Uint64(r.scratch[:8]), nil
+}
+
+func (r *Reader) readUvarint() (uint64, error) {
+	var u uint64
+	for i := 0; i < binary.MaxVarintLen64; i++ {
+		b, err := r.readByte()
+		if err != nil {
+			return 0, err
+		}
+		u |= uint64(b&0x7F) << (7 * i)
+		if b&0x80 == 0 {
+			return u, nil
+		}
+	}
+	return 0, eris.New("varint is too long")
+}
+
+func (r *Reader) readString() (string, error) {
+	length, err := r.readStringLength()
+	if err != nil {
+		return "", err
+	}
+	// Already capped at MaxStringLength, so allocating the lot up front is fine
+	bs := make([]byte, length)
+	if _, err = io.ReadFull(r.reader, bs); err != nil {
+		return "", err
+	}
+	if !r.encoding.ModifiedUTF8 {
+		return string(bs), nil
+	}
+	return DecodeModifiedUTF8(bs)
+}
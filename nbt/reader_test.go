@@ -0,0 +1,207 @@
+package nbt
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"minecraftServer/nbt/tags"
+	"runtime"
+	"testing"
+)
+
+type readerTestChunk struct {
+	DataVersion int32 `nbt:"DataVersion"`
+	Level       struct {
+		Sections []struct {
+			Y           uint8
+			BlockStates []int64 `nbt:"BlockStates"`
+			Palette     []struct {
+				Name string `nbt:"Name"`
+			} `nbt:"Palette"`
+		} `nbt:"Sections"`
+		Status string `nbt:"Status"`
+	} `nbt:"Level"`
+}
+
+func makeReaderTestChunk(t testing.TB) []byte {
+	var chunk readerTestChunk
+	chunk.DataVersion = 2586
+	chunk.Level.Status = "full"
+	chunk.Level.Sections = make([]struct {
+		Y           uint8
+		BlockStates []int64 `nbt:"BlockStates"`
+		Palette     []struct {
+			Name string `nbt:"Name"`
+		} `nbt:"Palette"`
+	}, 16)
+	for i := range chunk.Level.Sections {
+		chunk.Level.Sections[i].Y = uint8(i)
+		chunk.Level.Sections[i].BlockStates = make([]int64, 256)
+		chunk.Level.Sections[i].Palette = []struct {
+			Name string `nbt:"Name"`
+		}{{Name: "minecraft:air"}, {Name: "minecraft:stone"}}
+	}
+	bs, err := MarshalToNBT(&chunk)
+	assert.NoError(t, err)
+	return bs
+}
+
+func TestReaderPicksFields(t *testing.T) {
+	r := NewReader(bytes.NewReader(makeReaderTestChunk(t)))
+
+	header, err := r.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, tags.Compound, header.Tag)
+	assert.Equal(t, 1, r.Depth())
+
+	header, err = r.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, Header{Tag: tags.Int, Name: "DataVersion", Index: -1}, header)
+	version, err := r.ReadInt()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2586), version)
+
+	header, err = r.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, "Level", header.Name)
+	assert.Equal(t, "Level", r.Path())
+
+	header, err = r.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, Header{Tag: tags.List, Name: "Sections", Index: -1, ElemType: tags.Compound, Len: 16}, header)
+	assert.NoError(t, r.Skip())
+
+	header, err = r.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, "Status", header.Name)
+	assert.Equal(t, "Level.Status", r.Path())
+	status, err := r.ReadString()
+	assert.NoError(t, err)
+	assert.Equal(t, "full", status)
+
+	for _, depth := range []int{1, 0} {
+		header, err = r.Next()
+		assert.NoError(t, err)
+		assert.Equal(t, tags.End, header.Tag)
+		assert.Equal(t, depth, r.Depth())
+	}
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReaderPath(t *testing.T) {
+	r := NewReader(bytes.NewReader(makeReaderTestChunk(t)))
+	var paths []string
+	for {
+		header, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		if header.Tag == tags.LongArray {
+			paths = append(paths, r.Path())
+		}
+	}
+	assert.Len(t, paths, 16)
+	assert.Equal(t, "Level.Sections[3].BlockStates", paths[3])
+}
+
+func TestReaderUnreadValuesAreSkipped(t *testing.T) {
+	r := NewReader(bytes.NewReader(makeReaderTestChunk(t)))
+	count := 0
+	for {
+		header, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		if header.Name == "Name" {
+			count++
+		}
+	}
+	assert.Equal(t, 32, count)
+}
+
+func TestReaderTypeMismatch(t *testing.T) {
+	r := NewReader(bytes.NewReader(makeReaderTestChunk(t)))
+	_, err := r.Next()
+	assert.NoError(t, err)
+	_, err = r.ReadInt()
+	assert.Error(t, err)
+
+	_, err = r.Next()
+	assert.NoError(t, err)
+	_, err = r.ReadString()
+	assert.Error(t, err)
+	_, err = r.ReadInt()
+	assert.NoError(t, err)
+	assert.Error(t, r.Skip())
+}
+
+func TestReaderSkipDoesNotAllocate(t *testing.T) {
+	bs := makeReaderTestChunk(t)
+	reader := bytes.NewReader(bs)
+	r := NewReader(reader)
+	allocs := testing.AllocsPerRun(10, func() {
+		reader.Reset(bs)
+		*r = Reader{reader: reader, encoding: JavaEncoding, frames: r.frames[:0]}
+		if _, err := r.Next(); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Skip(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	assert.Zero(t, allocs)
+}
+
+func BenchmarkReaderSkip(b *testing.B) {
+	bs := makeReaderTestChunk(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r := NewReader(bytes.NewReader(bs))
+		if _, err := r.Next(); err != nil {
+			b.Fatal(err)
+		}
+		if err := r.Skip(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestReaderHugeLengthsOnTruncatedInput(t *testing.T) {
+	// Each document claims a 2^31-1 element value under "a", then ends
+	tests := []struct {
+		name   string
+		header []byte
+		into   interface{}
+	}{
+		{name: "ByteArray", header: []byte{byte(tags.ByteArray)}, into: &struct{ A []byte }{}},
+		{name: "IntArray", header: []byte{byte(tags.IntArray)}, into: &struct{ A []int32 }{}},
+		{name: "LongArray", header: []byte{byte(tags.LongArray)}, into: &struct{ A []int64 }{}},
+		{name: "List", header: []byte{byte(tags.List), 0, 1, 'a', byte(tags.Long)}, into: &struct{ A []int64 }{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bs := []byte{byte(tags.Compound), 0, 0}
+			bs = append(bs, test.header...)
+			if test.header[0] != byte(tags.List) {
+				bs = append(bs, 0, 1, 'a')
+			}
+			bs = append(bs, 0x7F, 0xFF, 0xFF, 0xFF, 1, 2, 3)
+
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			err := Unmarshal(bs, test.into)
+			runtime.ReadMemStats(&after)
+			assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+			assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(1<<20))
+
+			runtime.ReadMemStats(&before)
+			_, _, err = ReadTree(bytes.NewReader(bs))
+			runtime.ReadMemStats(&after)
+			assert.Error(t, err)
+			assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(1<<20), "the tree reads them too")
+		})
+	}
+}
@@ -0,0 +1,543 @@
+package nbt
+
+import (
+	"bytes"
+	"github.com/rotisserie/eris"
+	"math"
+	"minecraftServer/nbt/tags"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// https://minecraft.fandom.com/wiki/NBT_format#SNBT_format
+type (
+	snbtTokenKind byte
+
+	snbtToken struct {
+		Kind  snbtTokenKind
+		Value string
+		Pos   int
+	}
+
+	snbtLexer struct {
+		input string
+		pos   int
+	}
+
+	snbtParser struct {
+		lexer *snbtLexer
+	}
+
+	snbtPrinter struct {
+		sb     strings.Builder
+		indent string
+	}
+)
+
+const (
+	snbtEOF snbtTokenKind = iota
+	snbtOpenCompound
+	snbtCloseCompound
+	snbtOpenList
+	snbtCloseList
+	snbtComma
+	snbtColon
+	snbtSemicolon
+	snbtQuoted
+	snbtUnquoted
+	snbtInvalid
+)
+
+var (
+	snbtPunctuation = map[byte]snbtTokenKind{
+		'{': snbtOpenCompound,
+		'}': snbtCloseCompound,
+		'[': snbtOpenList,
+		']': snbtCloseList,
+		',': snbtComma,
+		':': snbtColon,
+		';': snbtSemicolon,
+	}
+
+	snbtDoublePattern = regexp.MustCompile(`^[-+]?(?:[0-9]+\.|[0-9]*\.[0-9]+)(?:[eE][-+]?[0-9]+)?[dD]?$`)
+	snbtDoubleSuffix  = regexp.MustCompile(`^[-+]?(?:[0-9]+\.?|[0-9]*\.[0-9]+)(?:[eE][-+]?[0-9]+)?[dD]$`)
+	snbtFloatPattern  = regexp.MustCompile(`^[-+]?(?:[0-9]+\.?|[0-9]*\.[0-9]+)(?:[eE][-+]?[0-9]+)?[fF]$`)
+	snbtIntPattern    = regexp.MustCompile(`^[-+]?(?:0|[1-9][0-9]*)([bBsSlL]?)$`)
+	snbtPlainPattern  = regexp.MustCompile(`^[0-9A-Za-z_\-.+]+$`)
+)
+
+// ParseSNBT parses a single stringified NBT value such as {display:{Name:'"x"'},Count:1b}
+func ParseSNBT(input string) (Field, error) {
+	parser := &snbtParser{
+		lexer: &snbtLexer{input: input},
+	}
+	field, err := parser.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	if tok := parser.lexer.next(); tok.Kind != snbtEOF {
+		return nil, eris.Errorf("unexpected '%v' after value at %v", tok.Value, tok.Pos)
+	}
+	return field, nil
+}
+
+// SNBTToNBT converts a stringified compound into the binary form produced by MarshalToNBT
+func SNBTToNBT(input string) ([]byte, error) {
+	field, err := ParseSNBT(input)
+	if err != nil {
+		return nil, err
+	}
+	compound, ok := field.(*Compound)
+	if !ok {
+		return nil, eris.Errorf("expected root %v, got %v", tags.Compound, field.Tag())
+	}
+	buf := bytes.NewBuffer(nil)
+	if err = WriteTree(buf, "", compound); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// NBTToSNBT converts binary NBT into its compact stringified form, the root name is dropped
+func NBTToSNBT(data []byte) (string, error) {
+	_, compound, err := ReadTree(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	return FormatSNBT(compound), nil
+}
+
+// FormatSNBT prints a value in compact SNBT
+func FormatSNBT(field Field) string {
+	return FormatSNBTIndent(field, "")
+}
+
+// FormatSNBTIndent pretty-prints a value, putting each compound entry on its own line when indent is non-empty
+func FormatSNBTIndent(field Field, indent string) string {
+	printer := &snbtPrinter{
+		indent: indent,
+	}
+	printer.print(field, 0)
+	return printer.sb.String()
+}
+
+func (l *snbtLexer) next() snbtToken {
+	for l.pos < len(l.input) && isSNBTSpace(l.input[l.pos]) {
+		l.pos++
+	}
+	start := l.pos
+	if l.pos >= len(l.input) {
+		return snbtToken{Kind: snbtEOF, Pos: start}
+	}
+
+	c := l.input[l.pos]
+	if kind, ok := snbtPunctuation[c]; ok {
+		l.pos++
+		return snbtToken{Kind: kind, Value: string(c), Pos: start}
+	}
+
+	if c == '"' || c == '\'' {
+		return l.quoted(c)
+	}
+
+	for l.pos < len(l.input) && isSNBTPlain(l.input[l.pos]) {
+		l.pos++
+	}
+	if l.pos == start {
+		l.pos++
+		return snbtToken{Kind: snbtInvalid, Value: l.input[start:l.pos], Pos: start}
+	}
+	return snbtToken{Kind: snbtUnquoted, Value: l.input[start:l.pos], Pos: start}
+}
+
+func (l *snbtLexer) quoted(quote byte) snbtToken {
+	start := l.pos
+	l.pos++
+	var sb strings.Builder
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		l.pos++
+		switch c {
+		case '\\':
+			if l.pos < len(l.input) {
+				sb.WriteByte(l.input[l.pos])
+				l.pos++
+			}
+		case quote:
+			return snbtToken{Kind: snbtQuoted, Value: sb.String(), Pos: start}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	// Unterminated, reported by the parser as an unexpected EOF
+	return snbtToken{Kind: snbtEOF, Pos: start}
+}
+
+func (l *snbtLexer) peek() snbtToken {
+	pos := l.pos
+	tok := l.next()
+	l.pos = pos
+	return tok
+}
+
+func isSNBTSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isSNBTPlain(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' ||
+		c == '_' || c == '-' || c == '.' || c == '+'
+}
+
+func (p *snbtParser) expect(kind snbtTokenKind, what string) (snbtToken, error) {
+	tok := p.lexer.next()
+	if tok.Kind != kind {
+		return tok, unexpectedSNBT(tok, what)
+	}
+	return tok, nil
+}
+
+func unexpectedSNBT(tok snbtToken, what string) error {
+	if tok.Kind == snbtEOF {
+		return eris.Errorf("unexpected end of input at %v, expected %v", tok.Pos, what)
+	}
+	return eris.Errorf("unexpected '%v' at %v, expected %v", tok.Value, tok.Pos, what)
+}
+
+func (p *snbtParser) parseValue() (Field, error) {
+	tok := p.lexer.next()
+	switch tok.Kind {
+	case snbtOpenCompound:
+		return p.parseCompound()
+	case snbtOpenList:
+		return p.parseList()
+	case snbtQuoted:
+		s := String(tok.Value)
+		return &s, nil
+	case snbtUnquoted:
+		return parseSNBTScalar(tok.Value), nil
+	}
+	return nil, unexpectedSNBT(tok, "value")
+}
+
+func (p *snbtParser) parseCompound() (Field, error) {
+	compound := &Compound{}
+	if p.lexer.peek().Kind == snbtCloseCompound {
+		p.lexer.next()
+		return compound, nil
+	}
+	for {
+		key := p.lexer.next()
+		if key.Kind != snbtQuoted && key.Kind != snbtUnquoted {
+			return nil, unexpectedSNBT(key, "key")
+		}
+		if _, err := p.expect(snbtColon, "':'"); err != nil {
+			return nil, err
+		}
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, eris.Wrapf(err, "failed to parse '%v'", key.Value)
+		}
+		compound.Set(key.Value, value)
+
+		tok := p.lexer.next()
+		if tok.Kind == snbtCloseCompound {
+			return compound, nil
+		}
+		if tok.Kind != snbtComma {
+			return nil, unexpectedSNBT(tok, "',' or '}'")
+		}
+	}
+}
+
+func (p *snbtParser) parseList() (Field, error) {
+	if tok := p.lexer.peek(); tok.Kind == snbtUnquoted && len(tok.Value) == 1 && strings.Contains("BIL", tok.Value) {
+		pos := p.lexer.pos
+		p.lexer.next()
+		if p.lexer.peek().Kind == snbtSemicolon {
+			p.lexer.next()
+			return p.parseArray(tok.Value[0])
+		}
+		p.lexer.pos = pos
+	}
+
+	list := &List{}
+	if p.lexer.peek().Kind == snbtCloseList {
+		p.lexer.next()
+		return list, nil
+	}
+	for {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, eris.Wrapf(err, "failed to parse element %v", len(list.Values))
+		}
+		if err = list.Add(value); err != nil {
+			return nil, err
+		}
+
+		tok := p.lexer.next()
+		if tok.Kind == snbtCloseList {
+			return list, nil
+		}
+		if tok.Kind != snbtComma {
+			return nil, unexpectedSNBT(tok, "',' or ']'")
+		}
+	}
+}
+
+func (p *snbtParser) parseArray(arrayType byte) (Field, error) {
+	var values []Field
+	if p.lexer.peek().Kind == snbtCloseList {
+		p.lexer.next()
+	} else {
+		for {
+			tok, err := p.expect(snbtUnquoted, "number")
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, parseSNBTScalar(tok.Value))
+
+			tok = p.lexer.next()
+			if tok.Kind == snbtCloseList {
+				break
+			}
+			if tok.Kind != snbtComma {
+				return nil, unexpectedSNBT(tok, "',' or ']'")
+			}
+		}
+	}
+
+	switch arrayType {
+	case 'B':
+		ba := make(ByteArray, len(values))
+		for i, value := range values {
+			b, ok := value.(*Byte)
+			if !ok {
+				return nil, eris.Errorf("cannot add %v to %v", value.Tag(), tags.ByteArray)
+			}
+			ba[i] = byte(*b)
+		}
+		return &ba, nil
+	case 'I':
+		ia := make(IntArray, len(values))
+		for i, value := range values {
+			n, ok := value.(*Int)
+			if !ok {
+				return nil, eris.Errorf("cannot add %v to %v", value.Tag(), tags.IntArray)
+			}
+			ia[i] = int32(*n)
+		}
+		return &ia, nil
+	default:
+		la := make(LongArray, len(values))
+		for i, value := range values {
+			n, ok := value.(*Long)
+			if !ok {
+				return nil, eris.Errorf("cannot add %v to %v", value.Tag(), tags.LongArray)
+			}
+			la[i] = int64(*n)
+		}
+		return &la, nil
+	}
+}
+
+// parseSNBTScalar types an unquoted value by its suffix, falling back to a string like vanilla does
+func parseSNBTScalar(value string) Field {
+	switch {
+	case value == "true":
+		b := Byte(1)
+		return &b
+	case value == "false":
+		b := Byte(0)
+		return &b
+	case snbtFloatPattern.MatchString(value):
+		if f, err := strconv.ParseFloat(value[:len(value)-1], 32); err == nil {
+			fl := Float(f)
+			return &fl
+		}
+	case snbtDoubleSuffix.MatchString(value):
+		if d, err := strconv.ParseFloat(value[:len(value)-1], 64); err == nil {
+			do := Double(d)
+			return &do
+		}
+	case snbtDoublePattern.MatchString(value):
+		if d, err := strconv.ParseFloat(value, 64); err == nil {
+			do := Double(d)
+			return &do
+		}
+	case snbtIntPattern.MatchString(value):
+		suffix := snbtIntPattern.FindStringSubmatch(value)[1]
+		digits := value[:len(value)-len(suffix)]
+		switch strings.ToLower(suffix) {
+		case "b":
+			if n, err := strconv.ParseInt(digits, 10, 8); err == nil {
+				b := Byte(n)
+				return &b
+			}
+		case "s":
+			if n, err := strconv.ParseInt(digits, 10, 16); err == nil {
+				s := Short(n)
+				return &s
+			}
+		case "l":
+			if n, err := strconv.ParseInt(digits, 10, 64); err == nil {
+				l := Long(n)
+				return &l
+			}
+		default:
+			if n, err := strconv.ParseInt(digits, 10, 32); err == nil {
+				i := Int(n)
+				return &i
+			}
+		}
+	}
+	s := String(value)
+	return &s
+}
+
+func (p *snbtPrinter) print(field Field, depth int) {
+	switch f := field.(type) {
+	case *Compound:
+		p.printCompound(f, depth)
+	case *List:
+		p.printList(f, depth)
+	case *Byte:
+		p.sb.WriteString(strconv.Itoa(int(int8(*f))) + "b")
+	case *Short:
+		p.sb.WriteString(strconv.Itoa(int(*f)) + "s")
+	case *Int:
+		p.sb.WriteString(strconv.Itoa(int(*f)))
+	case *Long:
+		p.sb.WriteString(strconv.FormatInt(int64(*f), 10) + "L")
+	case *Float:
+		p.sb.WriteString(formatSNBTFloat(float64(*f), 32) + "f")
+	case *Double:
+		p.sb.WriteString(formatSNBTFloat(float64(*f), 64) + "d")
+	case *String:
+		p.sb.WriteString(quoteSNBT(string(*f)))
+	case *ByteArray:
+		p.sb.WriteString("[B;")
+		for i, b := range *f {
+			p.arraySeparator(i)
+			p.sb.WriteString(strconv.Itoa(int(int8(b))) + "B")
+		}
+		p.sb.WriteByte(']')
+	case *IntArray:
+		p.sb.WriteString("[I;")
+		for i, n := range *f {
+			p.arraySeparator(i)
+			p.sb.WriteString(strconv.Itoa(int(n)))
+		}
+		p.sb.WriteByte(']')
+	case *LongArray:
+		p.sb.WriteString("[L;")
+		for i, n := range *f {
+			p.arraySeparator(i)
+			p.sb.WriteString(strconv.FormatInt(n, 10) + "L")
+		}
+		p.sb.WriteByte(']')
+	}
+}
+
+func (p *snbtPrinter) printCompound(compound *Compound, depth int) {
+	if len(compound.Entries) == 0 {
+		p.sb.WriteString("{}")
+		return
+	}
+	p.sb.WriteByte('{')
+	for i, entry := range compound.Entries {
+		if i > 0 {
+			p.sb.WriteByte(',')
+		}
+		p.newline(depth + 1)
+		p.sb.WriteString(formatSNBTKey(entry.Name))
+		p.sb.WriteByte(':')
+		if len(p.indent) > 0 {
+			p.sb.WriteByte(' ')
+		}
+		p.print(entry.Value, depth+1)
+	}
+	p.newline(depth)
+	p.sb.WriteByte('}')
+}
+
+func (p *snbtPrinter) printList(list *List, depth int) {
+	if len(list.Values) == 0 {
+		p.sb.WriteString("[]")
+		return
+	}
+	// Only nested compounds and lists are broken over multiple lines
+	multiline := list.ElemType == tags.Compound || list.ElemType == tags.List
+	p.sb.WriteByte('[')
+	for i, value := range list.Values {
+		if i > 0 {
+			p.sb.WriteByte(',')
+			if !multiline && len(p.indent) > 0 {
+				p.sb.WriteByte(' ')
+			}
+		}
+		if multiline {
+			p.newline(depth + 1)
+		}
+		p.print(value, depth+1)
+	}
+	if multiline {
+		p.newline(depth)
+	}
+	p.sb.WriteByte(']')
+}
+
+func (p *snbtPrinter) arraySeparator(i int) {
+	if i > 0 {
+		p.sb.WriteByte(',')
+	}
+	if len(p.indent) > 0 {
+		p.sb.WriteByte(' ')
+	}
+}
+
+func (p *snbtPrinter) newline(depth int) {
+	if len(p.indent) == 0 {
+		return
+	}
+	p.sb.WriteByte('\n')
+	p.sb.WriteString(strings.Repeat(p.indent, depth))
+}
+
+func formatSNBTFloat(f float64, bitSize int) string {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		// SNBT has no literal for these
+		f = 0
+	}
+	s := strconv.FormatFloat(f, 'g', -1, bitSize)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+func formatSNBTKey(key string) string {
+	if snbtPlainPattern.MatchString(key) {
+		return key
+	}
+	return quoteSNBT(key)
+}
+
+// quoteSNBT prefers double quotes, switching to single quotes to avoid escaping a value containing double quotes
+func quoteSNBT(s string) string {
+	quote := byte('"')
+	if strings.IndexByte(s, '"') >= 0 && strings.IndexByte(s, '\'') < 0 {
+		quote = '\''
+	}
+	var sb strings.Builder
+	sb.WriteByte(quote)
+	for i := 0; i < len(s); i++ {
+		if s[i] == quote || s[i] == '\\' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(s[i])
+	}
+	sb.WriteByte(quote)
+	return sb.String()
+}
@@ -0,0 +1,91 @@
+package nbt
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSNBTRoundTripTable(t *testing.T) {
+	for _, test := range marshalTestCases {
+		if test.Skip {
+			continue
+		}
+		name, compound, err := ReadTree(bytes.NewReader(test.ExpectedOutput))
+		assert.NoError(t, err, test.Name)
+
+		for _, indent := range []string{"", "    "} {
+			parsed, err := ParseSNBT(FormatSNBTIndent(compound, indent))
+			assert.NoError(t, err, test.Name)
+
+			buf := bytes.NewBuffer(nil)
+			assert.NoError(t, WriteTree(buf, name, parsed.(*Compound)), test.Name)
+			assert.Equal(t, test.ExpectedOutput, buf.Bytes(), test.Name)
+		}
+	}
+}
+
+func TestParseSNBT(t *testing.T) {
+	field, err := ParseSNBT(`{display:{Name:'"x"'},Count:1b, "quoted key": "a\"b", pos: [1.5d, -2.0d], f: 0.5f, s: 7s, l: 9000000000L, i: -3, yes: true, word: stone}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{display:{Name:'"x"'},Count:1b,"quoted key":'a"b',pos:[1.5d,-2.0d],f:0.5f,s:7s,l:9000000000L,i:-3,yes:1b,word:"stone"}`, FormatSNBT(field))
+}
+
+func TestParseSNBTArrays(t *testing.T) {
+	field, err := ParseSNBT(`{bytes: [B; 1b, -2b], ints: [I; 1, 2], longs: [L; 3L], empty: [I;], list: [B, I]}`)
+	assert.NoError(t, err)
+	compound := field.(*Compound)
+	assert.Equal(t, &ByteArray{1, 0xfe}, compound.Get("bytes"))
+	assert.Equal(t, &IntArray{1, 2}, compound.Get("ints"))
+	assert.Equal(t, &LongArray{3}, compound.Get("longs"))
+	assert.Equal(t, &IntArray{}, compound.Get("empty"))
+
+	b, i := String("B"), String("I")
+	assert.Equal(t, &List{ElemType: b.Tag(), Values: []Field{&b, &i}}, compound.Get("list"))
+}
+
+func TestParseSNBTErrors(t *testing.T) {
+	for _, input := range []string{
+		`{`,
+		`{a:1,}`,
+		`{a 1}`,
+		`[1, 2b]`,
+		`[I; 1L]`,
+		`{a:"unterminated}`,
+		`{a:@}`,
+		`{a:1} trailing`,
+	} {
+		_, err := ParseSNBT(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestFormatSNBTIndent(t *testing.T) {
+	field, err := ParseSNBT(`{display:{Name:"x",Lore:["a","b"]},Items:[{id:"stone"}],Empty:{}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{
+  display: {
+    Name: "x",
+    Lore: ["a", "b"]
+  },
+  Items: [
+    {
+      id: "stone"
+    }
+  ],
+  Empty: {}
+}`, FormatSNBTIndent(field, "  "))
+}
+
+func TestSNBTToNBT(t *testing.T) {
+	bs, err := SNBTToNBT(`{strings:["hello","world"]}`)
+	assert.NoError(t, err)
+	assert.Equal(t, marshalTestCases[2].ExpectedOutput, bs)
+
+	s, err := NBTToSNBT(bs)
+	assert.NoError(t, err)
+	assert.Equal(t, `{strings:["hello","world"]}`, s)
+
+	_, err = SNBTToNBT(`[1, 2]`)
+	assert.Error(t, err)
+}
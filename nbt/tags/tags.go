@@ -85,7 +85,7 @@ func (t Tag) WriteTo(writer io.Writer) (int64, error) {
 
 func (t *Tag) ReadFrom(reader io.Reader) (int64, error) {
 	bs := make([]byte, 1)
-	if _, err := reader.Read(bs); err != nil {
+	if _, err := io.ReadFull(reader, bs); err != nil {
 		return 0, err
 	}
 	*t = Tag(bs[0])
@@ -0,0 +1,488 @@
+package nbt
+
+import (
+	"bytes"
+	"github.com/rotisserie/eris"
+	"io"
+	"minecraftServer/nbt/tags"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Compound is an ordered set of named tags, order is kept so documents round-trip byte for byte
+	Compound struct {
+		Entries []Entry
+	}
+
+	Entry struct {
+		Name  string
+		Value Field
+	}
+
+	// List is a list of nameless tags which all share ElemType
+	List struct {
+		ElemType tags.Tag
+		Values   []Field
+	}
+
+	IntArray  []int32
+	LongArray []int64
+
+	pathElem struct {
+		Name    string
+		Index   int
+		IsIndex bool
+	}
+)
+
+// NewField creates an empty tree node for the given tag
+func NewField(tag tags.Tag) (Field, error) {
+	switch tag {
+	case tags.Byte:
+		return new(Byte), nil
+	case tags.Short:
+		return new(Short), nil
+	case tags.Int:
+		return new(Int), nil
+	case tags.Long:
+		return new(Long), nil
+	case tags.Float:
+		return new(Float), nil
+	case tags.Double:
+		return new(Double), nil
+	case tags.ByteArray:
+		return new(ByteArray), nil
+	case tags.String:
+		return new(String), nil
+	case tags.List:
+		return new(List), nil
+	case tags.Compound:
+		return new(Compound), nil
+	case tags.IntArray:
+		return new(IntArray), nil
+	case tags.LongArray:
+		return new(LongArray), nil
+	}
+	return nil, eris.Errorf("unknown tag %v", byte(tag))
+}
+
+// ReadTree reads a named root compound without needing a Go struct
+func ReadTree(reader io.Reader) (string, *Compound, error) {
+	r := NewReader(reader)
+	header, err := r.Next()
+	if err != nil {
+		return "", nil, eris.Wrap(err, "failed to read root tag")
+	}
+	if header.Tag != tags.Compound {
+		return "", nil, eris.Errorf("expected root %v, got %v", tags.Compound, header.Tag)
+	}
+	compound, err := r.ReadField()
+	if err != nil {
+		return "", nil, err
+	}
+	return header.Name, compound.(*Compound), nil
+}
+
+// WriteTree writes compound as a named root compound
+func WriteTree(writer io.Writer, name string, compound *Compound) error {
+	enc := NewEncoder(writer)
+	if err := enc.encodeTree(name, compound); err != nil {
+		return eris.Wrap(err, "failed to write tree")
+	}
+	return enc.writer.Flush()
+}
+
+// MarshalToTree converts a struct into a tree using the struct marshaller, the root name is dropped
+func MarshalToTree(i interface{}) (*Compound, error) {
+	bs, err := MarshalToNBT(i)
+	if err != nil {
+		return nil, err
+	}
+	_, compound, err := ReadTree(bytes.NewReader(bs))
+	return compound, err
+}
+
+// UnmarshalTree converts a tree into the struct pointed to by v
+func UnmarshalTree(compound *Compound, v interface{}) error {
+	buf := bytes.NewBuffer(nil)
+	if err := WriteTree(buf, "", compound); err != nil {
+		return err
+	}
+	return Unmarshal(buf.Bytes(), v)
+}
+
+func (c Compound) WriteTo(to io.Writer) (count int64, err error) {
+	var nn int64
+	for _, entry := range c.Entries {
+		nn, err = writeAll(to, entry.Value.Tag(), String(entry.Name), entry.Value)
+		count += nn
+		if err != nil {
+			return
+		}
+	}
+	nn, err = tags.End.WriteTo(to)
+	count += nn
+	return
+}
+
+func (c *Compound) ReadFrom(from io.Reader) (count int64, err error) {
+	var nn int64
+	c.Entries = nil
+	for {
+		var tag tags.Tag
+		nn, err = tag.ReadFrom(from)
+		count += nn
+		if err != nil {
+			return
+		}
+		if tag == tags.End {
+			return
+		}
+
+		var name String
+		nn, err = name.ReadFrom(from)
+		count += nn
+		if err != nil {
+			return
+		}
+
+		var field Field
+		if field, err = NewField(tag); err != nil {
+			return
+		}
+		nn, err = field.ReadFrom(from)
+		count += nn
+		if err != nil {
+			err = eris.Wrapf(err, "failed to read '%v'", name)
+			return
+		}
+		c.Entries = append(c.Entries, Entry{Name: string(name), Value: field})
+	}
+}
+
+func (_ Compound) Tag() tags.Tag {
+	return tags.Compound
+}
+
+// Get returns the value stored under name, or nil if there isn't one
+func (c *Compound) Get(name string) Field {
+	if i := c.index(name); i >= 0 {
+		return c.Entries[i].Value
+	}
+	return nil
+}
+
+// Set replaces the value stored under name, appending a new entry if there isn't one
+func (c *Compound) Set(name string, value Field) {
+	if i := c.index(name); i >= 0 {
+		c.Entries[i].Value = value
+		return
+	}
+	c.Entries = append(c.Entries, Entry{Name: name, Value: value})
+}
+
+// Remove deletes the entry stored under name, returning whether it existed
+func (c *Compound) Remove(name string) bool {
+	i := c.index(name)
+	if i < 0 {
+		return false
+	}
+	c.Entries = append(c.Entries[:i], c.Entries[i+1:]...)
+	return true
+}
+
+func (c *Compound) Len() int {
+	return len(c.Entries)
+}
+
+func (c *Compound) index(name string) int {
+	for i, entry := range c.Entries {
+		if entry.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetPath looks up a nested value by a path like Level.Sections[3].BlockStates
+func (c *Compound) GetPath(path string) (Field, error) {
+	elems, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var current Field = c
+	for i, elem := range elems {
+		if current, err = child(current, elem); err != nil {
+			return nil, eris.Wrapf(err, "failed to resolve '%v'", joinPath(elems[:i+1]))
+		}
+	}
+	return current, nil
+}
+
+// SetPath stores value at path, creating any missing intermediate compounds
+func (c *Compound) SetPath(path string, value Field) error {
+	elems, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+
+	var current Field = c
+	for i, elem := range elems[:len(elems)-1] {
+		next, err := child(current, elem)
+		if err != nil && !elem.IsIndex {
+			compound, ok := current.(*Compound)
+			if !ok {
+				return eris.Wrapf(err, "failed to resolve '%v'", joinPath(elems[:i+1]))
+			}
+			next = &Compound{}
+			compound.Set(elem.Name, next)
+		} else if err != nil {
+			return eris.Wrapf(err, "failed to resolve '%v'", joinPath(elems[:i+1]))
+		}
+		current = next
+	}
+
+	last := elems[len(elems)-1]
+	switch parent := current.(type) {
+	case *Compound:
+		if last.IsIndex {
+			return eris.Errorf("cannot index %v at '%v'", tags.Compound, path)
+		}
+		parent.Set(last.Name, value)
+	case *List:
+		if !last.IsIndex {
+			return eris.Errorf("cannot get '%v' from %v", last.Name, tags.List)
+		}
+		return parent.Set(last.Index, value)
+	default:
+		return eris.Errorf("cannot set '%v' inside %v", path, current.Tag())
+	}
+	return nil
+}
+
+func child(parent Field, elem pathElem) (Field, error) {
+	switch p := parent.(type) {
+	case *Compound:
+		if elem.IsIndex {
+			return nil, eris.Errorf("cannot index %v", tags.Compound)
+		}
+		if value := p.Get(elem.Name); value != nil {
+			return value, nil
+		}
+		return nil, eris.Errorf("no entry named '%v'", elem.Name)
+	case *List:
+		if !elem.IsIndex {
+			return nil, eris.Errorf("cannot get '%v' from %v", elem.Name, tags.List)
+		}
+		if elem.Index < 0 || elem.Index >= len(p.Values) {
+			return nil, eris.Errorf("index %v out of range for %v of length %v", elem.Index, tags.List, len(p.Values))
+		}
+		return p.Values[elem.Index], nil
+	}
+	return nil, eris.Errorf("cannot descend into %v", parent.Tag())
+}
+
+func parsePath(path string) ([]pathElem, error) {
+	var elems []pathElem
+	for _, part := range strings.Split(path, ".") {
+		name := part
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			name = part[:i]
+			part = part[i:]
+		} else {
+			part = ""
+		}
+		if len(name) == 0 && (len(elems) == 0 || len(part) == 0) {
+			return nil, eris.Errorf("invalid path '%v'", path)
+		}
+		if len(name) > 0 {
+			elems = append(elems, pathElem{Name: name})
+		}
+		for len(part) > 0 {
+			end := strings.IndexByte(part, ']')
+			if part[0] != '[' || end < 0 {
+				return nil, eris.Errorf("invalid path '%v'", path)
+			}
+			index, err := strconv.Atoi(part[1:end])
+			if err != nil {
+				return nil, eris.Wrapf(err, "invalid index in path '%v'", path)
+			}
+			elems = append(elems, pathElem{Index: index, IsIndex: true})
+			part = part[end+1:]
+		}
+	}
+	return elems, nil
+}
+
+func joinPath(elems []pathElem) string {
+	var sb strings.Builder
+	for i, elem := range elems {
+		if elem.IsIndex {
+			sb.WriteString("[" + strconv.Itoa(elem.Index) + "]")
+			continue
+		}
+		if i > 0 {
+			sb.WriteByte('.')
+		}
+		sb.WriteString(elem.Name)
+	}
+	return sb.String()
+}
+
+func (l List) WriteTo(to io.Writer) (count int64, err error) {
+	count, err = writeAll(to, l.ElemType, Int(len(l.Values)))
+	if err != nil {
+		return
+	}
+	var nn int64
+	for _, value := range l.Values {
+		nn, err = value.WriteTo(to)
+		count += nn
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (l *List) ReadFrom(from io.Reader) (count int64, err error) {
+	var length Int
+	count, err = l.ElemType.ReadFrom(from)
+	if err != nil {
+		return
+	}
+	var nn int64
+	nn, err = length.ReadFrom(from)
+	count += nn
+	if err != nil {
+		return
+	}
+	l.Values = nil
+	for i := 0; i < int(length); i++ {
+		var field Field
+		if field, err = NewField(l.ElemType); err != nil {
+			return
+		}
+		nn, err = field.ReadFrom(from)
+		count += nn
+		if err != nil {
+			err = eris.Wrapf(err, "failed to read element %v", i)
+			return
+		}
+		l.Values = append(l.Values, field)
+	}
+	return
+}
+
+func (_ List) Tag() tags.Tag {
+	return tags.List
+}
+
+// Add appends value, the first value added decides the type of an empty list
+func (l *List) Add(value Field) error {
+	if len(l.Values) == 0 {
+		l.ElemType = value.Tag()
+	} else if value.Tag() != l.ElemType {
+		return eris.Errorf("cannot add %v to a list of %v", value.Tag(), l.ElemType)
+	}
+	l.Values = append(l.Values, value)
+	return nil
+}
+
+// Set replaces the value at index, which must have the same type as the rest of the list
+func (l *List) Set(index int, value Field) error {
+	if index < 0 || index >= len(l.Values) {
+		return eris.Errorf("index %v out of range for %v of length %v", index, tags.List, len(l.Values))
+	}
+	if value.Tag() != l.ElemType {
+		return eris.Errorf("cannot set %v in a list of %v", value.Tag(), l.ElemType)
+	}
+	l.Values[index] = value
+	return nil
+}
+
+func (ia IntArray) WriteTo(to io.Writer) (int64, error) {
+	count, err := Int(len(ia)).WriteTo(to)
+	if err != nil {
+		return count, err
+	}
+	for _, i := range ia {
+		nn, err := Int(i).WriteTo(to)
+		count += nn
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+func (ia *IntArray) ReadFrom(from io.Reader) (int64, error) {
+	var l Int
+	count, err := l.ReadFrom(from)
+	if err != nil {
+		return count, err
+	}
+	if l < 0 {
+		return count, eris.Errorf("negative IntArray length %v", l)
+	}
+	arr := make([]int32, 0, preallocated(int(l)))
+	for j := 0; j < int(l); j++ {
+		var i Int
+		nn, err := i.ReadFrom(from)
+		count += nn
+		if err != nil {
+			return count, err
+		}
+		arr = append(arr, int32(i))
+	}
+	*ia = arr
+	return count, nil
+}
+
+func (_ IntArray) Tag() tags.Tag {
+	return tags.IntArray
+}
+
+func (la LongArray) WriteTo(to io.Writer) (int64, error) {
+	count, err := Int(len(la)).WriteTo(to)
+	if err != nil {
+		return count, err
+	}
+	for _, l := range la {
+		nn, err := Long(l).WriteTo(to)
+		count += nn
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+func (la *LongArray) ReadFrom(from io.Reader) (int64, error) {
+	var l Int
+	count, err := l.ReadFrom(from)
+	if err != nil {
+		return count, err
+	}
+	if l < 0 {
+		return count, eris.Errorf("negative LongArray length %v", l)
+	}
+	arr := make([]int64, 0, preallocated(int(l)))
+	for j := 0; j < int(l); j++ {
+		var lo Long
+		nn, err := lo.ReadFrom(from)
+		count += nn
+		if err != nil {
+			return count, err
+		}
+		arr = append(arr, int64(lo))
+	}
+	*la = arr
+	return count, nil
+}
+
+func (_ LongArray) Tag() tags.Tag {
+	return tags.LongArray
+}
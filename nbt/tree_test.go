@@ -0,0 +1,97 @@
+package nbt
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"minecraftServer/nbt/tags"
+	"testing"
+)
+
+func TestTreeRoundTripTable(t *testing.T) {
+	for _, test := range marshalTestCases {
+		if test.Skip {
+			continue
+		}
+		name, compound, err := ReadTree(bytes.NewReader(test.ExpectedOutput))
+		assert.NoError(t, err, test.Name)
+
+		buf := bytes.NewBuffer(nil)
+		assert.NoError(t, WriteTree(buf, name, compound), test.Name)
+		assert.Equal(t, test.ExpectedOutput, buf.Bytes(), test.Name)
+	}
+}
+
+func TestCompoundGetPath(t *testing.T) {
+	blockStates := LongArray{1, 2, 3}
+	y := Byte(3)
+	section := &Compound{}
+	section.Set("Y", &y)
+	section.Set("BlockStates", &blockStates)
+
+	sections := &List{}
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, sections.Add(&Compound{}))
+	}
+	assert.NoError(t, sections.Add(section))
+
+	level := &Compound{}
+	level.Set("Sections", sections)
+	root := &Compound{}
+	root.Set("Level", level)
+
+	value, err := root.GetPath("Level.Sections[3].BlockStates")
+	assert.NoError(t, err)
+	assert.Equal(t, &blockStates, value)
+
+	_, err = root.GetPath("Level.Sections[4].BlockStates")
+	assert.Error(t, err)
+	_, err = root.GetPath("Level.Missing")
+	assert.Error(t, err)
+	_, err = root.GetPath("Level.Sections.Y")
+	assert.Error(t, err)
+	_, err = root.GetPath("Level..Sections")
+	assert.Error(t, err)
+}
+
+func TestCompoundSetPath(t *testing.T) {
+	root := &Compound{}
+	status := String("full")
+	assert.NoError(t, root.SetPath("Level.Status", &status))
+
+	value, err := root.GetPath("Level.Status")
+	assert.NoError(t, err)
+	assert.Equal(t, &status, value)
+
+	list := &List{}
+	assert.NoError(t, list.Add(new(Int)))
+	root.Set("Ints", list)
+	i := Int(42)
+	assert.NoError(t, root.SetPath("Ints[0]", &i))
+	assert.Equal(t, &i, list.Values[0])
+	assert.Error(t, root.SetPath("Ints[0]", &status))
+	assert.Error(t, root.SetPath("Ints[1]", &i))
+}
+
+func TestMarshalToTree(t *testing.T) {
+	type testStruct struct {
+		Name   string
+		Scores []int32
+		Nested struct {
+			Value int64
+		}
+	}
+	input := testStruct{Name: "tree", Scores: []int32{1, 2}}
+	input.Nested.Value = 1 << 40
+
+	compound, err := MarshalToTree(&input)
+	assert.NoError(t, err)
+	assert.Equal(t, tags.String, compound.Get("name").Tag())
+	assert.Equal(t, tags.IntArray, compound.Get("scores").Tag())
+	value, err := compound.GetPath("nested.value")
+	assert.NoError(t, err)
+	assert.Equal(t, Long(1<<40), *value.(*Long))
+
+	var output testStruct
+	assert.NoError(t, UnmarshalTree(compound, &output))
+	assert.Equal(t, input, output)
+}
@@ -1,10 +1,10 @@
 package nbt
 
 import (
+	"github.com/rotisserie/eris"
 	"io"
 	"math"
 	"minecraftServer/nbt/tags"
-	"reflect"
 )
 
 type (
@@ -45,53 +45,6 @@ type (
 	}
 )
 
-var fieldMap = map[reflect.Kind]func(value reflect.Value) Field{
-	reflect.Bool: func(v reflect.Value) Field {
-		b := Boolean(v.Bool())
-		return &b
-	},
-	reflect.Uint8: func(v reflect.Value) Field {
-		b := Byte(v.Uint())
-		return &b
-	},
-	reflect.Int16: func(v reflect.Value) Field {
-		s := Short(v.Int())
-		return &s
-	},
-	reflect.Uint16: func(v reflect.Value) Field {
-		s := UShort(v.Uint())
-		return &s
-	},
-	reflect.Int32: func(v reflect.Value) Field {
-		i := Int(v.Int())
-		return &i
-	},
-	reflect.Uint32: func(v reflect.Value) Field {
-		i := UInt(v.Uint())
-		return &i
-	},
-	reflect.Int64: func(v reflect.Value) Field {
-		l := Long(v.Int())
-		return &l
-	},
-	reflect.Uint64: func(v reflect.Value) Field {
-		l := ULong(v.Int())
-		return &l
-	},
-	reflect.Float32: func(v reflect.Value) Field {
-		f := Float(v.Float())
-		return &f
-	},
-	reflect.Float64: func(v reflect.Value) Field {
-		d := Double(v.Float())
-		return &d
-	},
-	reflect.String: func(v reflect.Value) Field {
-		s := String(v.String())
-		return &s
-	},
-}
-
 func writeAll(writer io.Writer, encoders ...FieldEncoder) (count int64, err error) {
 	var nn int64
 	for _, encoder := range encoders {
@@ -122,7 +75,7 @@ func (namedTag NamedTag) WriteTo(to io.Writer) (int64, error) {
 }
 
 func (s Short) WriteTo(to io.Writer) (int64, error) {
-	nn, err := to.Write([]byte{byte(s << 8), byte(s)})
+	nn, err := to.Write([]byte{byte(s >> 8), byte(s)})
 	return int64(nn), err
 }
 
@@ -132,7 +85,7 @@ func (s *Short) ReadFrom(from io.Reader) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	*s = Short(int16(by[0])>>8 | int16(by[1]))
+	*s = Short(int16(by[0])<<8 | int16(by[1]))
 	return int64(nn), nil
 }
 
@@ -141,7 +94,7 @@ func (s Short) Tag() tags.Tag {
 }
 
 func (s UShort) WriteTo(to io.Writer) (int64, error) {
-	nn, err := to.Write([]byte{byte(s << 8), byte(s)})
+	nn, err := to.Write([]byte{byte(s >> 8), byte(s)})
 	return int64(nn), err
 }
 
@@ -151,7 +104,7 @@ func (s *UShort) ReadFrom(from io.Reader) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	*s = UShort(uint16(by[0])>>8 | uint16(by[1]))
+	*s = UShort(uint16(by[0])<<8 | uint16(by[1]))
 	return int64(nn), nil
 }
 
@@ -161,7 +114,7 @@ func (_ UShort) Tag() tags.Tag {
 }
 
 func (i UInt) WriteTo(to io.Writer) (int64, error) {
-	nn, err := to.Write([]byte{byte(i << 24), byte(i << 16), byte(i << 8), byte(i)})
+	nn, err := to.Write([]byte{byte(i >> 24), byte(i >> 16), byte(i >> 8), byte(i)})
 	return int64(nn), err
 }
 
@@ -171,7 +124,7 @@ func (i *UInt) ReadFrom(from io.Reader) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	*i = UInt(uint32(by[0])>>24 | uint32(by[1])>>16 | uint32(by[2])>>8 | uint32(by[3]))
+	*i = UInt(uint32(by[0])<<24 | uint32(by[1])<<16 | uint32(by[2])<<8 | uint32(by[3]))
 	return int64(nn), err
 }
 
@@ -180,7 +133,7 @@ func (_ UInt) Tag() tags.Tag {
 }
 
 func (i Int) WriteTo(to io.Writer) (int64, error) {
-	nn, err := to.Write([]byte{byte(i << 24), byte(i << 16), byte(i << 8), byte(i)})
+	nn, err := to.Write([]byte{byte(i >> 24), byte(i >> 16), byte(i >> 8), byte(i)})
 	return int64(nn), err
 }
 
@@ -190,7 +143,7 @@ func (i *Int) ReadFrom(from io.Reader) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	*i = Int(int32(by[0])>>24 | int32(by[1])>>16 | int32(by[2])>>8 | int32(by[3]))
+	*i = Int(int32(by[0])<<24 | int32(by[1])<<16 | int32(by[2])<<8 | int32(by[3]))
 	return int64(nn), err
 }
 
@@ -199,8 +152,8 @@ func (_ Int) Tag() tags.Tag {
 }
 
 func (l ULong) WriteTo(to io.Writer) (int64, error) {
-	nn, err := to.Write([]byte{byte(l << 56), byte(l << 48), byte(l << 40), byte(l << 32),
-		byte(l << 24), byte(l << 16), byte(l << 8), byte(l)})
+	nn, err := to.Write([]byte{byte(l >> 56), byte(l >> 48), byte(l >> 40), byte(l >> 32),
+		byte(l >> 24), byte(l >> 16), byte(l >> 8), byte(l)})
 	return int64(nn), err
 }
 
@@ -210,8 +163,8 @@ func (l *ULong) ReadFrom(from io.Reader) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	*l = ULong(uint64(by[0])>>56 | uint64(by[1])>>48 | uint64(by[2])>>40 | uint64(by[3])>>32 |
-		uint64(by[4])>>24 | uint64(by[5])>>16 | uint64(by[6])>>8 | uint64(by[7]))
+	*l = ULong(uint64(by[0])<<56 | uint64(by[1])<<48 | uint64(by[2])<<40 | uint64(by[3])<<32 |
+		uint64(by[4])<<24 | uint64(by[5])<<16 | uint64(by[6])<<8 | uint64(by[7]))
 	return int64(nn), nil
 }
 
@@ -220,8 +173,8 @@ func (_ ULong) Tag() tags.Tag {
 }
 
 func (l Long) WriteTo(to io.Writer) (int64, error) {
-	nn, err := to.Write([]byte{byte(l << 56), byte(l << 48), byte(l << 40), byte(l << 32),
-		byte(l << 24), byte(l << 16), byte(l << 8), byte(l)})
+	nn, err := to.Write([]byte{byte(l >> 56), byte(l >> 48), byte(l >> 40), byte(l >> 32),
+		byte(l >> 24), byte(l >> 16), byte(l >> 8), byte(l)})
 	return int64(nn), err
 }
 
@@ -231,8 +184,8 @@ func (l *Long) ReadFrom(from io.Reader) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	*l = Long(int64(by[0])>>56 | int64(by[1])>>48 | int64(by[2])>>40 | int64(by[3])>>32 |
-		int64(by[4])>>24 | int64(by[5])>>16 | int64(by[6])>>8 | int64(by[7]))
+	*l = Long(int64(by[0])<<56 | int64(by[1])<<48 | int64(by[2])<<40 | int64(by[3])<<32 |
+		int64(by[4])<<24 | int64(by[5])<<16 | int64(by[6])<<8 | int64(by[7]))
 	return int64(nn), nil
 }
 
@@ -277,12 +230,16 @@ func (_ Double) Tag() tags.Tag {
 }
 
 func (s String) WriteTo(to io.Writer) (byteCount int64, err error) {
-	byteCount, err = UShort(len(s)).WriteTo(to)
+	bs := EncodeModifiedUTF8(string(s))
+	if len(bs) > MaxStringLength {
+		return 0, &StringTooLongError{Length: len(bs)}
+	}
+	byteCount, err = UShort(len(bs)).WriteTo(to)
 	if err != nil {
 		return
 	}
 
-	nn, err := to.Write([]byte(s))
+	nn, err := to.Write(bs)
 	byteCount += int64(nn)
 	return
 }
@@ -298,7 +255,11 @@ func (s *String) ReadFrom(from io.Reader) (int64, error) {
 	if err != nil {
 		return nn, err
 	}
-	*s = String(by)
+	decoded, err := DecodeModifiedUTF8(by)
+	if err != nil {
+		return nn + int64(count), err
+	}
+	*s = String(decoded)
 	return nn + int64(count), nil
 }
 
@@ -362,13 +323,15 @@ func (ba *ByteArray) ReadFrom(from io.Reader) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	by := make([]byte, l)
-	count, err := io.ReadFull(from, by)
+	if l < 0 {
+		return nn, eris.Errorf("negative ByteArray length %v", l)
+	}
+	by, err := readBytes(from, int(l))
 	if err != nil {
 		return nn, err
 	}
 	*ba = by
-	return nn + int64(count), nil
+	return nn + int64(len(by)), nil
 }
 
 func (_ ByteArray) Tag() tags.Tag {
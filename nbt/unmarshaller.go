@@ -1 +1,307 @@
 package nbt
+
+import (
+	"bytes"
+	"github.com/rotisserie/eris"
+	"io"
+	"minecraftServer/nbt/tags"
+	"reflect"
+)
+
+// dynamicTypes are what each tag decodes to in an interface{}, matching what the Encoder writes them from
+var dynamicTypes = map[tags.Tag]reflect.Type{
+	tags.Byte:      reflect.TypeOf(uint8(0)),
+	tags.Short:     reflect.TypeOf(int16(0)),
+	tags.Int:       reflect.TypeOf(int32(0)),
+	tags.Long:      reflect.TypeOf(int64(0)),
+	tags.Float:     reflect.TypeOf(float32(0)),
+	tags.Double:    reflect.TypeOf(float64(0)),
+	tags.String:    reflect.TypeOf(""),
+	tags.ByteArray: reflect.TypeOf([]byte(nil)),
+	tags.IntArray:  reflect.TypeOf([]int32(nil)),
+	tags.LongArray: reflect.TypeOf([]int64(nil)),
+	tags.List:      reflect.TypeOf([]interface{}(nil)),
+	tags.Compound:  reflect.TypeOf(map[string]interface{}(nil)),
+}
+
+type (
+	// Decoder reads values from a Reader into structs, honouring the same struct tags as the Encoder
+	Decoder struct {
+		reader *Reader
+	}
+)
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		reader: NewReader(r),
+	}
+}
+
+// Unmarshal decodes NBT data into the struct pointed to by v, honouring the same struct tags as MarshalToNBT,
+// or into a *Compound as a tree
+func Unmarshal(data []byte, v interface{}) error {
+	return UnmarshalFrom(bytes.NewReader(data), v)
+}
+
+// UnmarshalFrom decodes a single root compound from the reader into the struct or *Compound pointed to by v
+func UnmarshalFrom(reader io.Reader, v interface{}) error {
+	return NewDecoder(reader).Decode(v)
+}
+
+// SetEncoding changes the byte order and integer encoding used from the next value on, the default is JavaEncoding
+func (d *Decoder) SetEncoding(encoding Encoding) {
+	d.reader.SetEncoding(encoding)
+}
+
+// Decode reads a root compound into the struct, map[string]T or *Compound pointed to by i
+func (d *Decoder) Decode(i interface{}) error {
+	if compound, ok := i.(*Compound); ok {
+		if _, err := d.readRoot(); err != nil {
+			return err
+		}
+		tree, err := d.reader.ReadField()
+		if err != nil {
+			return err
+		}
+		*compound = *tree.(*Compound)
+		return nil
+	}
+	v := reflect.ValueOf(i)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return eris.Errorf("nbt: Unmarshal requires a non-nil pointer, got %v", v.Kind())
+	}
+	return d.DecodeValue(v)
+}
+
+// DecodeValue reads a root compound into a struct or map[string]T, setting the struct's Root field to the root
+// name if it has one
+func (d *Decoder) DecodeValue(v reflect.Value) error {
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Map {
+		if _, err := d.readRoot(); err != nil {
+			return err
+		}
+		return d.decodeMap(v)
+	}
+	if v.Kind() != reflect.Struct {
+		return eris.Errorf("nbt: cannot decode root compound into '%v'", v.Kind())
+	}
+
+	rootName, err := d.readRoot()
+	if err != nil {
+		return err
+	}
+	rootField := v.FieldByName("Root")
+	if rootField.IsValid() && rootField.Kind() == reflect.String && rootField.CanSet() {
+		rootField.SetString(rootName)
+	}
+
+	return d.decodeStruct(v)
+}
+
+func (d *Decoder) readRoot() (string, error) {
+	// Each value is its own document, so a Decoder can read several from one stream
+	d.reader.reset()
+	header, err := d.reader.Next()
+	if err != nil {
+		return "", eris.Wrap(err, "failed to read root tag")
+	}
+	if header.Tag != tags.Compound {
+		return "", eris.Errorf("expected root %v, got %v", tags.Compound, header.Tag)
+	}
+	return header.Name, nil
+}
+
+// decodeStruct reads named tags into the struct fields until the closing End tag
+func (d *Decoder) decodeStruct(v reflect.Value) error {
+	plan := planFor(v.Type())
+	for {
+		header, err := d.reader.Next()
+		if err != nil {
+			return err
+		}
+		if header.Tag == tags.End {
+			return nil
+		}
+
+		field, ok := plan.field(header.Name)
+		if !ok || !v.Field(field.Index).CanSet() {
+			if err = d.reader.Skip(); err != nil {
+				return eris.Wrapf(err, "failed to skip unknown field '%v'", d.reader.Path())
+			}
+			continue
+		}
+		if err = d.decodeField(header, v.Field(field.Index)); err != nil {
+			return eris.Wrapf(err, "failed to decode field '%v'", d.reader.Path())
+		}
+	}
+}
+
+// decodeMap reads named tags into a map[string]T until the closing End tag
+func (d *Decoder) decodeMap(v reflect.Value) error {
+	if v.Type().Key().Kind() != reflect.String {
+		return eris.Errorf("map keys must be strings, got '%v'", v.Type().Key().Kind())
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(v.Type()))
+	}
+	for {
+		header, err := d.reader.Next()
+		if err != nil {
+			return err
+		}
+		if header.Tag == tags.End {
+			return nil
+		}
+
+		value := reflect.New(v.Type().Elem()).Elem()
+		if err = d.decodeField(header, value); err != nil {
+			return eris.Wrapf(err, "failed to decode field '%v'", d.reader.Path())
+		}
+		v.SetMapIndex(reflect.ValueOf(header.Name).Convert(v.Type().Key()), value)
+	}
+}
+
+// decodeField reads the value of header into field, converting to the field's kind
+func (d *Decoder) decodeField(header Header, field reflect.Value) error {
+	switch field.Kind() {
+	case reflect.Ptr:
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		return d.decodeField(header, field.Elem())
+	case reflect.Interface:
+		typ, ok := dynamicTypes[header.Tag]
+		if !ok || field.NumMethod() > 0 {
+			break
+		}
+		value := reflect.New(typ).Elem()
+		if err := d.decodeField(header, value); err != nil {
+			return err
+		}
+		field.Set(value)
+		return nil
+	}
+
+	r := d.reader
+	switch header.Tag {
+	case tags.Byte:
+		switch field.Kind() {
+		case reflect.Bool:
+			b, err := r.ReadByte()
+			field.SetBool(b == 0x01)
+			return err
+		case reflect.Uint8:
+			b, err := r.ReadByte()
+			field.SetUint(uint64(b))
+			return err
+		}
+	case tags.Short:
+		switch field.Kind() {
+		case reflect.Int16:
+			s, err := r.ReadShort()
+			field.SetInt(int64(s))
+			return err
+		case reflect.Uint16:
+			s, err := r.ReadShort()
+			field.SetUint(uint64(uint16(s)))
+			return err
+		}
+	case tags.Int:
+		switch field.Kind() {
+		case reflect.Int32:
+			i, err := r.ReadInt()
+			field.SetInt(int64(i))
+			return err
+		case reflect.Uint32:
+			i, err := r.ReadInt()
+			field.SetUint(uint64(uint32(i)))
+			return err
+		}
+	case tags.Long:
+		switch field.Kind() {
+		case reflect.Int64:
+			l, err := r.ReadLong()
+			field.SetInt(l)
+			return err
+		case reflect.Uint64:
+			l, err := r.ReadLong()
+			field.SetUint(uint64(l))
+			return err
+		}
+	case tags.Float:
+		if field.Kind() == reflect.Float32 {
+			f, err := r.ReadFloat()
+			field.SetFloat(float64(f))
+			return err
+		}
+	case tags.Double:
+		if field.Kind() == reflect.Float64 {
+			do, err := r.ReadDouble()
+			field.SetFloat(do)
+			return err
+		}
+	case tags.String:
+		if field.Kind() == reflect.String {
+			s, err := r.ReadString()
+			field.SetString(s)
+			return err
+		}
+	case tags.ByteArray:
+		if isSliceOf(field, reflect.Uint8) {
+			ba, err := r.ReadByteArray()
+			field.SetBytes(ba)
+			return err
+		}
+	case tags.IntArray:
+		if isSliceOf(field, reflect.Int32) {
+			ia, err := r.ReadIntArray()
+			field.Set(reflect.ValueOf(ia))
+			return err
+		}
+	case tags.LongArray:
+		if isSliceOf(field, reflect.Int64) {
+			la, err := r.ReadLongArray()
+			field.Set(reflect.ValueOf(la))
+			return err
+		}
+	case tags.List:
+		if field.Kind() == reflect.Slice {
+			return d.decodeList(header, field)
+		}
+	case tags.Compound:
+		switch field.Kind() {
+		case reflect.Struct:
+			return d.decodeStruct(field)
+		case reflect.Map:
+			return d.decodeMap(field)
+		}
+	}
+	return eris.Errorf("cannot decode %v into '%v'", header.Tag, field.Type())
+}
+
+func (d *Decoder) decodeList(header Header, field reflect.Value) error {
+	slice := reflect.MakeSlice(field.Type(), 0, preallocated(header.Len))
+	zero := reflect.Zero(field.Type().Elem())
+	for {
+		elem, err := d.reader.Next()
+		if err != nil {
+			return err
+		}
+		if elem.Tag == tags.End {
+			break
+		}
+		slice = reflect.Append(slice, zero)
+		if err = d.decodeField(elem, slice.Index(elem.Index)); err != nil {
+			return eris.Wrapf(err, "failed to decode element %v", elem.Index)
+		}
+	}
+	field.Set(slice)
+	return nil
+}
+
+func isSliceOf(field reflect.Value, kind reflect.Kind) bool {
+	return field.Kind() == reflect.Slice && field.Type().Elem().Kind() == kind
+}
@@ -0,0 +1,184 @@
+package nbt
+
+import (
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"testing"
+)
+
+func TestUnmarshalTable(t *testing.T) {
+	for _, test := range marshalTestCases {
+		if test.Skip {
+			continue
+		}
+		v := reflect.New(reflect.TypeOf(test.InputStruct))
+		assert.NoError(t, Unmarshal(test.ExpectedOutput, v.Interface()), test.Name)
+		assert.Equal(t, test.InputStruct, v.Elem().Interface(), test.Name)
+	}
+}
+
+func TestUnmarshalRoundTrip(t *testing.T) {
+	type inner struct {
+		Name  string
+		Score int64
+	}
+	type testStruct struct {
+		Root      string
+		Short     int16   `nbt:"short"`
+		Int       int32   `nbt:"int"`
+		Long      int64   `nbt:"long"`
+		Float     float32 `nbt:"float"`
+		Double    float64 `nbt:"double"`
+		Flag      bool    `nbt:"flag"`
+		Bytes     []byte  `nbt:"bytes"`
+		ByteList  []byte  `nbt:"byteList" nbt_type:"List"`
+		Ints      []int32
+		Longs     []int64
+		Strings   []string
+		Inner     inner
+		Inners    []inner
+		Optional  int32 `nbt_opt:"true"`
+		EmptyList []string
+	}
+
+	input := testStruct{
+		Root:     "root",
+		Short:    -1234,
+		Int:      70000,
+		Long:     -9000000000,
+		Float:    1.5,
+		Double:   -2.25,
+		Flag:     true,
+		Bytes:    []byte{1, 2, 3},
+		ByteList: []byte{4, 5},
+		Ints:     []int32{-1, 65536},
+		Longs:    []int64{1 << 40},
+		Strings:  []string{"hello", "world"},
+		Inner:    inner{Name: "inner", Score: 300},
+		Inners:   []inner{{Name: "a", Score: 1}, {Name: "b", Score: 2}},
+	}
+
+	bs, err := MarshalToNBT(&input)
+	assert.NoError(t, err)
+
+	var output testStruct
+	assert.NoError(t, Unmarshal(bs, &output))
+	// Empty lists decode as empty rather than nil slices
+	output.EmptyList = nil
+	assert.Equal(t, input, output)
+}
+
+func TestUnmarshalSkipsUnknownFields(t *testing.T) {
+	bs, err := MarshalToNBT(struct {
+		Unknown struct {
+			List []string
+			Ints []int32
+		}
+		Known string
+	}{
+		Unknown: struct {
+			List []string
+			Ints []int32
+		}{List: []string{"skipped"}, Ints: []int32{1, 2}},
+		Known: "kept",
+	})
+	assert.NoError(t, err)
+
+	var output struct {
+		Known string
+	}
+	assert.NoError(t, Unmarshal(bs, &output))
+	assert.Equal(t, "kept", output.Known)
+}
+
+func TestUnmarshalCaseInsensitiveNames(t *testing.T) {
+	bs, err := MarshalToNBT(struct {
+		DataVersion int32 `nbt:"DataVersion"`
+	}{DataVersion: 2586})
+	assert.NoError(t, err)
+
+	var output struct {
+		DataVersion int32
+	}
+	assert.NoError(t, Unmarshal(bs, &output))
+	assert.Equal(t, int32(2586), output.DataVersion)
+}
+
+func TestUnmarshalTypeMismatch(t *testing.T) {
+	bs, err := MarshalToNBT(struct {
+		Value string
+	}{Value: "text"})
+	assert.NoError(t, err)
+
+	var output struct {
+		Value int32
+	}
+	assert.Error(t, Unmarshal(bs, &output))
+	assert.Error(t, Unmarshal(bs, output))
+}
+
+func TestUnmarshalInterfaceFields(t *testing.T) {
+	type inner struct {
+		Name string `nbt:"name"`
+	}
+	input := struct {
+		Value  int32             `nbt:"value"`
+		Names  []string          `nbt:"names"`
+		Inner  inner             `nbt:"inner"`
+		Scores map[string]uint8  `nbt:"scores"`
+		Ints   []int32           `nbt:"ints"`
+		Extra  map[string]string `nbt:"extra"`
+	}{
+		Value:  5,
+		Names:  []string{"a", "b"},
+		Inner:  inner{Name: "c"},
+		Scores: map[string]uint8{"x": 1},
+		Ints:   []int32{1, 2},
+	}
+	bs, err := MarshalToNBT(input)
+	assert.NoError(t, err)
+
+	var dynamic map[string]interface{}
+	assert.NoError(t, Unmarshal(bs, &dynamic))
+	assert.Equal(t, map[string]interface{}{
+		"value":  int32(5),
+		"names":  []interface{}{"a", "b"},
+		"inner":  map[string]interface{}{"name": "c"},
+		"scores": map[string]interface{}{"x": uint8(1)},
+		"ints":   []int32{1, 2},
+		"extra":  map[string]interface{}{},
+	}, dynamic)
+
+	// Decoding into interface{} gives values the encoder writes back out identically
+	again, err := MarshalToNBT(dynamic)
+	assert.NoError(t, err)
+	var tree, againTree Compound
+	assert.NoError(t, Unmarshal(bs, &tree))
+	assert.NoError(t, Unmarshal(again, &againTree))
+	assert.Equal(t, tree.Len(), againTree.Len())
+	for _, entry := range tree.Entries {
+		assert.Equal(t, entry.Value, againTree.Get(entry.Name), entry.Name)
+	}
+}
+
+func TestUnmarshalPointerFields(t *testing.T) {
+	type inner struct {
+		X int16 `nbt:"x"`
+	}
+	type testStruct struct {
+		Inner  *inner            `nbt:"inner"`
+		Inners []*inner          `nbt:"inners"`
+		ByName map[string]*inner `nbt:"byName"`
+	}
+	input := testStruct{
+		Inner:  &inner{X: 1},
+		Inners: []*inner{{X: 2}, {X: 3}},
+		ByName: map[string]*inner{"four": {X: 4}},
+	}
+	bs, err := MarshalToNBT(input)
+	assert.NoError(t, err)
+
+	var output testStruct
+	assert.NoError(t, Unmarshal(bs, &output))
+	assert.Equal(t, input, output)
+}
@@ -0,0 +1,60 @@
+package packet
+
+import (
+	"crypto/cipher"
+)
+
+// https://wiki.vg/Protocol_Encryption
+
+type (
+	// cfb8 is CFB mode with an 8 bit segment size, which the standard library doesn't provide
+	cfb8 struct {
+		block    cipher.Block
+		register []byte
+		stream   []byte
+		decrypt  bool
+	}
+)
+
+// NewCFB8Encrypter returns a stream which encrypts with CFB8 mode using block, iv has to be one block long
+func NewCFB8Encrypter(block cipher.Block, iv []byte) cipher.Stream {
+	return newCFB8(block, iv, false)
+}
+
+// NewCFB8Decrypter returns a stream which decrypts with CFB8 mode using block, iv has to be one block long
+func NewCFB8Decrypter(block cipher.Block, iv []byte) cipher.Stream {
+	return newCFB8(block, iv, true)
+}
+
+func newCFB8(block cipher.Block, iv []byte, decrypt bool) *cfb8 {
+	if len(iv) != block.BlockSize() {
+		panic("cfb8: IV length must equal block size")
+	}
+	register := make([]byte, len(iv))
+	copy(register, iv)
+	return &cfb8{
+		block:    block,
+		register: register,
+		stream:   make([]byte, len(iv)),
+		decrypt:  decrypt,
+	}
+}
+
+func (c *cfb8) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("cfb8: output smaller than input")
+	}
+	last := len(c.register) - 1
+	for i, in := range src {
+		c.block.Encrypt(c.stream, c.register)
+		out := in ^ c.stream[0]
+		// The register is shifted along by one byte of ciphertext each time
+		copy(c.register, c.register[1:])
+		if c.decrypt {
+			c.register[last] = in
+		} else {
+			c.register[last] = out
+		}
+		dst[i] = out
+	}
+}
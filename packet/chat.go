@@ -0,0 +1,43 @@
+package packet
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// https://wiki.vg/Chat
+
+type (
+	// ChatComponent is the JSON text format used by MOTDs, disconnect reasons and chat messages
+	ChatComponent struct {
+		Text   string          `json:"text"`
+		Color  string          `json:"color,omitempty"`
+		Bold   bool            `json:"bold,omitempty"`
+		Italic bool            `json:"italic,omitempty"`
+		Extra  []ChatComponent `json:"extra,omitempty"`
+	}
+)
+
+func TextComponent(text string) ChatComponent {
+	return ChatComponent{Text: text}
+}
+
+// JSON encodes the component for a Chat field
+func (c ChatComponent) JSON() string {
+	bs, _ := json.Marshal(c)
+	return string(bs)
+}
+
+// PlainText joins the text of the component and its children, dropping the formatting
+func (c ChatComponent) PlainText() string {
+	var builder strings.Builder
+	c.writePlainText(&builder)
+	return builder.String()
+}
+
+func (c ChatComponent) writePlainText(builder *strings.Builder) {
+	builder.WriteString(c.Text)
+	for _, extra := range c.Extra {
+		extra.writePlainText(builder)
+	}
+}
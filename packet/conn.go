@@ -0,0 +1,237 @@
+package packet
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"github.com/rotisserie/eris"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CompressionDisabled is the threshold of a Conn that isn't compressing
+const CompressionDisabled = -1
+
+type (
+	// Conn frames packets over a net.Conn, switching to the compressed format once a threshold is set and
+	// encrypting everything once a shared secret is
+	Conn struct {
+		conn net.Conn
+		// buffered reads from conn, reader is it or a decrypting wrapper around it
+		buffered *bufio.Reader
+		reader   io.Reader
+		registry *Registry
+		// maxServerAddress bounds Handshake.ServerAddress in ReadPayload
+		maxServerAddress int
+
+		// readThreshold is the threshold ReadPacket frames with, kept apart from the writer's so reading never
+		// waits on a write. Accessed atomically
+		readThreshold int32
+
+		// writeMu guards framing packets into pending, it's never held over network I/O
+		writeMu sync.Mutex
+		// writer is pending or an encrypting wrapper around it
+		writer    io.Writer
+		pending   *bytes.Buffer
+		threshold int
+		encrypted bool
+
+		// flushMu keeps flushes in order, it's held while pending is written to the network
+		flushMu sync.Mutex
+		// flushing is what flush is writing, reused between flushes
+		flushing []byte
+
+		// queue is set once StartWriter has moved writes onto their own goroutine
+		queue *sendQueue
+	}
+)
+
+func NewConn(conn net.Conn) *Conn {
+	buffered := bufio.NewReader(conn)
+	pending := bytes.NewBuffer(nil)
+	return &Conn{
+		conn:             conn,
+		buffered:         buffered,
+		reader:           buffered,
+		registry:         DefaultRegistry,
+		maxServerAddress: MaxServerAddressLength,
+		readThreshold:    CompressionDisabled,
+		writer:           pending,
+		pending:          pending,
+		threshold:        CompressionDisabled,
+	}
+}
+
+// Threshold returns the smallest packet that is compressed, or CompressionDisabled
+func (c *Conn) Threshold() int {
+	return int(atomic.LoadInt32(&c.readThreshold))
+}
+
+// SetCompression switches the framing of every later packet in both directions, a negative threshold disables
+// compression. The client only expects this after a SetCompression packet
+func (c *Conn) SetCompression(threshold int) {
+	if threshold < 0 {
+		threshold = CompressionDisabled
+	}
+	// The writer frames packets as it takes them off the queue, so everything sent before now has to be written
+	// under the old threshold first. If the flush fails so will the next write
+	_ = c.Flush()
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.threshold = threshold
+	atomic.StoreInt32(&c.readThreshold, int32(threshold))
+}
+
+// ReadPacket reads the next packet using the framing for the current threshold
+func (c *Conn) ReadPacket() (Packet, error) {
+	if c.Threshold() == CompressionDisabled {
+		return MakeUncompressedPacket(c.reader)
+	}
+	return MakeCompressedPacket(c.reader)
+}
+
+// WritePacket writes a packet using the framing for the current threshold, or queues it once StartWriter has been
+// called
+func (c *Conn) WritePacket(pkt Packet) error {
+	if c.queue != nil {
+		return c.enqueue(queued{pkt: pkt})
+	}
+	return c.writeNow(pkt)
+}
+
+func (c *Conn) writeNow(pkt Packet) error {
+	if _, err := c.writeFramed(pkt); err != nil {
+		return err
+	}
+	return c.flush()
+}
+
+// writeFramed frames a packet into pending without flushing it, returning how much is now pending
+func (c *Conn) writeFramed(pkt Packet) (int, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	var err error
+	if c.threshold == CompressionDisabled {
+		_, err = WriteTo(pkt, c.writer)
+	} else {
+		_, err = WriteCompressedTo(pkt, c.writer, c.threshold)
+	}
+	return c.pending.Len(), err
+}
+
+// flush writes everything pending to the connection. Only flushMu is held while it does, so framing more packets
+// and reading never wait on a client that's slow to read
+func (c *Conn) flush() error {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+	c.writeMu.Lock()
+	c.flushing = append(c.flushing[:0], c.pending.Bytes()...)
+	c.pending.Reset()
+	c.writeMu.Unlock()
+	if len(c.flushing) == 0 {
+		return nil
+	}
+	_, err := c.conn.Write(c.flushing)
+	return err
+}
+
+// EnableEncryption wraps both directions in AES/CFB8 keyed with the shared secret from EncryptionResponse. It has to be
+// called from the goroutine reading packets, straight after the EncryptionResponse is read
+func (c *Conn) EnableEncryption(sharedSecret []byte) error {
+	encrypt, decrypt, err := newCiphers(sharedSecret)
+	if err != nil {
+		return err
+	}
+	// Everything queued before now goes out unencrypted
+	if err = c.Flush(); err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.encrypted {
+		return eris.New("connection is already encrypted")
+	}
+	// Anything already buffered was sent encrypted, so decryption goes on top of the buffer
+	c.reader = cipher.StreamReader{S: decrypt, R: c.reader}
+	c.writer = cipher.StreamWriter{S: encrypt, W: c.writer}
+	c.encrypted = true
+	return nil
+}
+
+// Encrypted reports whether EnableEncryption has been called
+func (c *Conn) Encrypted() bool {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.encrypted
+}
+
+// SetRegistry switches the packet IDs and layouts to those of the version the client asked for in its handshake
+func (c *Conn) SetRegistry(registry *Registry) {
+	c.registry = registry
+}
+
+// SetMaxServerAddressLength changes the longest handshake address ReadPayload accepts. Proxies forwarding player
+// identities append them to the address, so it's raised for them
+func (c *Conn) SetMaxServerAddressLength(max int) {
+	c.maxServerAddress = max
+}
+
+// ReadPayload reads the next packet and decodes it as a serverbound payload of state
+func (c *Conn) ReadPayload(state State) (interface{}, error) {
+	pkt, err := c.ReadPacket()
+	if err != nil {
+		return nil, err
+	}
+	payload, err := c.registry.Decode(state, Serverbound, pkt)
+	if err != nil {
+		return nil, err
+	}
+	if handshake, ok := payload.(*Handshake); ok {
+		if err = checkStringLength("Handshake.ServerAddress", handshake.ServerAddress, c.maxServerAddress); err != nil {
+			return nil, err
+		}
+	}
+	return payload, nil
+}
+
+// Send encodes a payload with the packet ID registered for state and writes it
+func (c *Conn) Send(state State, payload interface{}) error {
+	pkt, err := c.registry.Encode(state, payload)
+	if err != nil {
+		return err
+	}
+	return c.WritePacket(pkt)
+}
+
+// WriteData marshals data into a packet with the given ID and writes it
+func (c *Conn) WriteData(id int32, data interface{}) error {
+	pkt, err := MakePacketWithData(id, data)
+	if err != nil {
+		return err
+	}
+	return c.WritePacket(pkt)
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+func (c *Conn) SetDeadline(t time.Time) error {
+	return c.conn.SetDeadline(t)
+}
+
+// SetReadDeadline limits how long reads wait, it can be called from another goroutine to cut a read short
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// Close closes the connection, waiting a little for queued packets to be written first
+func (c *Conn) Close() error {
+	if c.queue != nil {
+		c.closeQueue()
+	}
+	return c.conn.Close()
+}
@@ -0,0 +1,92 @@
+package packet
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestWriteCompressedTo(t *testing.T) {
+	small := bytes.NewBuffer(nil)
+	_, err := WriteCompressedTo(MakePacket(0x01, bytes.NewReader([]byte{1, 2, 3})), small, 64)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x05,             // Packet Length
+		0x00,             // Data Length, uncompressed
+		0x01,             // ID
+		0x01, 0x02, 0x03, // Data
+	}, small.Bytes())
+
+	data := bytes.Repeat([]byte{0x42}, 1000)
+	large := bytes.NewBuffer(nil)
+	_, err = WriteCompressedTo(MakePacket(0x01, bytes.NewReader(data)), large, 64)
+	assert.NoError(t, err)
+	assert.Less(t, large.Len(), len(data))
+
+	for _, frame := range [][]byte{small.Bytes(), large.Bytes()} {
+		pkt, err := MakeCompressedPacket(bytes.NewReader(frame))
+		assert.NoError(t, err)
+		assert.Equal(t, VarInt(0x01), pkt.ID())
+		reader, err := pkt.DataReader()
+		assert.NoError(t, err)
+		bs, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		assert.Equal(t, int(pkt.DataLength()), len(bs))
+	}
+}
+
+func TestConnSwitchesFraming(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	serverConn, clientConn := NewConn(server), NewConn(client)
+
+	type payload struct {
+		Message string
+	}
+	messages := []string{"uncompressed", "small", string(bytes.Repeat([]byte("large"), 100))}
+	go func() {
+		for i, message := range messages {
+			if i == 1 {
+				serverConn.SetCompression(64)
+			}
+			assert.NoError(t, serverConn.WriteData(0x07, &payload{Message: message}))
+		}
+	}()
+
+	for i, message := range messages {
+		if i == 1 {
+			clientConn.SetCompression(64)
+		}
+		pkt, err := clientConn.ReadPacket()
+		assert.NoError(t, err)
+		assert.Equal(t, VarInt(0x07), pkt.ID())
+		var p payload
+		assert.NoError(t, Unmarshal(pkt, &p))
+		assert.Equal(t, message, p.Message)
+	}
+}
+
+func TestConnServerAddressLimit(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	serverConn, clientConn := NewConn(server), NewConn(client)
+	long := &Handshake{ProtocolVersion: 754, ServerAddress: strings.Repeat("a", MaxServerAddressLength+1), NextState: 2}
+
+	go func() {
+		assert.NoError(t, clientConn.Send(Handshaking, long))
+		assert.NoError(t, clientConn.Send(Handshaking, long))
+	}()
+	_, err := serverConn.ReadPayload(Handshaking)
+	assertLimitError(t, err, "Handshake.ServerAddress")
+
+	// Behind a proxy the address carries the forwarded identity too
+	serverConn.SetMaxServerAddressLength(MaxStringLength)
+	payload, err := serverConn.ReadPayload(Handshaking)
+	assert.NoError(t, err)
+	assert.Equal(t, long, payload)
+}
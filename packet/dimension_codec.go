@@ -0,0 +1,79 @@
+package packet
+
+type (
+	// DimensionCodecNBT is sent in JoinGame, describing every dimension and biome the client might be sent to
+	DimensionCodecNBT struct {
+		DimensionType struct {
+			Type  string
+			Value []DimensionTypeEntryNBT
+		} `nbt:"minecraft:dimension_type"`
+		Biome BiomeRegistryNBT `nbt:"minecraft:worldgen/biome"`
+	}
+	DimensionTypeEntryNBT struct {
+		Name    string
+		Id      int32
+		Element DimensionTypeNBT
+	}
+	DimensionTypeNBT struct {
+		PiglinSafe         bool `nbt:"piglin_safe"`
+		Natural            bool
+		AmbientLight       float32 `nbt:"ambient_light"`
+		FixedTime          int64   `nbt:"fixed_time" nbt_opt:"true"`
+		Infiniburn         string
+		RespawnAnchorWorks bool `nbt:"respawn_anchor_works"`
+		HasSkylight        bool `nbt:"has_skylight"`
+		BedWorks           bool `nbt:"bed_works"`
+		Effects            string
+		HasRaids           bool    `nbt:"has_raids"`
+		LogicalHeight      int32   `nbt:"logical_height"`
+		CoordinateScale    float32 `nbt:"coordinate_scale"`
+		Ultrawarm          bool
+		HasCeiling         bool `nbt:"has_ceiling"`
+	}
+	BiomeRegistryNBT struct {
+		Type  string
+		Value struct {
+			Name    string
+			Id      int32
+			Element struct {
+				Precipitation string
+				Depth         float32
+				Temperature   float32
+				Scale         float32
+				Downfall      float32
+				Category      string
+				Effects       struct {
+					SkyColor           int32  `nbt:"sky_color"`
+					WaterFogColor      int32  `nbt:"water_fog_color"`
+					FogColor           int32  `nbt:"fog_color"`
+					WaterColor         int32  `nbt:"water_color"`
+					FoliageColor       int32  `nbt:"foliage_color" nbt_opt:"true"`
+					GrassColorModifier string `nbt:"grass_color_modifier" nbt_opt:"true"`
+					Music              struct {
+						ReplaceCurrentMusic bool `nbt:"replace_current_music"`
+						Sound               string
+						MaxDelay            int32 `nbt:"max_delay"`
+						MinDelay            int32 `nbt:"min_delay"`
+					}
+					AmbientSound   string `nbt:"ambient_sound" nbt_opt:"true"`
+					AdditionsSound struct {
+						Sound      string
+						TickChance float64 `nbt:"tick_chance"`
+					} `nbt:"additions_sound" nbt_opt:"true"`
+					MoodSound struct {
+						Sound             string
+						TickDelay         int32 `nbt:"tick_delay"`
+						Offset            float64
+						BlockSearchExtent int32 `nbt:"block_search_extent"`
+					} `nbt:"mood_sound" nbt_opt:"true"`
+					Particle struct {
+						Probability float32
+						Options     struct {
+							Type string
+						}
+					} `nbt:"particle" nbt_opt:"true"`
+				}
+			}
+		}
+	}
+)
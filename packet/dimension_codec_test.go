@@ -0,0 +1,68 @@
+package packet
+
+import (
+	"github.com/stretchr/testify/assert"
+	"minecraftServer/nbt"
+	"testing"
+)
+
+func TestDimensionCodecNBTRoundTrip(t *testing.T) {
+	overworld := DimensionTypeNBT{
+		PiglinSafe:         false,
+		Natural:            true,
+		AmbientLight:       0,
+		Infiniburn:         "minecraft:infiniburn_overworld",
+		RespawnAnchorWorks: false,
+		HasSkylight:        true,
+		BedWorks:           true,
+		Effects:            "minecraft:overworld",
+		HasRaids:           true,
+		LogicalHeight:      256,
+		CoordinateScale:    1,
+		Ultrawarm:          false,
+		HasCeiling:         false,
+	}
+	nether := DimensionTypeNBT{
+		PiglinSafe:         true,
+		AmbientLight:       0.1,
+		FixedTime:          18000,
+		Infiniburn:         "minecraft:infiniburn_nether",
+		RespawnAnchorWorks: true,
+		Effects:            "minecraft:the_nether",
+		LogicalHeight:      128,
+		CoordinateScale:    8,
+		Ultrawarm:          true,
+		HasCeiling:         true,
+	}
+
+	var codec DimensionCodecNBT
+	codec.DimensionType.Type = "minecraft:dimension_type"
+	codec.DimensionType.Value = []DimensionTypeEntryNBT{
+		{Name: "minecraft:overworld", Id: 0, Element: overworld},
+		{Name: "minecraft:the_nether", Id: 1, Element: nether},
+	}
+	codec.Biome.Type = "minecraft:worldgen/biome"
+	codec.Biome.Value.Name = "minecraft:plains"
+	codec.Biome.Value.Id = 1
+	codec.Biome.Value.Element.Precipitation = "rain"
+	codec.Biome.Value.Element.Depth = 0.125
+	codec.Biome.Value.Element.Temperature = 0.8
+	codec.Biome.Value.Element.Scale = 0.05
+	codec.Biome.Value.Element.Downfall = 0.4
+	codec.Biome.Value.Element.Category = "plains"
+	codec.Biome.Value.Element.Effects.SkyColor = 7907327
+	codec.Biome.Value.Element.Effects.WaterFogColor = 329011
+	codec.Biome.Value.Element.Effects.FogColor = 12638463
+	codec.Biome.Value.Element.Effects.WaterColor = 4159204
+	codec.Biome.Value.Element.Effects.MoodSound.Sound = "minecraft:ambient.cave"
+	codec.Biome.Value.Element.Effects.MoodSound.TickDelay = 6000
+	codec.Biome.Value.Element.Effects.MoodSound.Offset = 2
+	codec.Biome.Value.Element.Effects.MoodSound.BlockSearchExtent = 8
+
+	bs, err := nbt.MarshalToNBT(&codec)
+	assert.NoError(t, err)
+
+	var decoded DimensionCodecNBT
+	assert.NoError(t, nbt.Unmarshal(bs, &decoded))
+	assert.Equal(t, codec, decoded)
+}
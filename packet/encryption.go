@@ -0,0 +1,98 @@
+package packet
+
+import (
+	"crypto/aes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"crypto/x509"
+	"github.com/rotisserie/eris"
+)
+
+const (
+	// ServerKeyBits is the size of the RSA key the vanilla client expects
+	ServerKeyBits = 1024
+	// SharedSecretLength is the length of the AES-128 key the client picks
+	SharedSecretLength = 16
+	verifyTokenLength  = 4
+)
+
+// ErrVerifyTokenMismatch is returned for an EncryptionResponse that didn't encrypt the verify token it was sent
+var ErrVerifyTokenMismatch = eris.New("verify token does not match")
+
+type (
+	// ServerKey is the RSA key pair used to exchange the shared secret during Login
+	ServerKey struct {
+		private   *rsa.PrivateKey
+		publicDER []byte
+	}
+)
+
+// GenerateServerKey makes a new key pair, vanilla servers make one each time they start
+func GenerateServerKey() (*ServerKey, error) {
+	private, err := rsa.GenerateKey(rand.Reader, ServerKeyBits)
+	if err != nil {
+		return nil, eris.Wrap(err, "failed to generate server key")
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	if err != nil {
+		return nil, eris.Wrap(err, "failed to encode server public key")
+	}
+	return &ServerKey{
+		private:   private,
+		publicDER: publicDER,
+	}, nil
+}
+
+// PublicKey returns the DER encoded public key sent in EncryptionRequest, also used in the server hash
+func (k *ServerKey) PublicKey() []byte {
+	return k.publicDER
+}
+
+// NewVerifyToken returns random bytes for the client to send back encrypted
+func NewVerifyToken() ([]byte, error) {
+	token := make([]byte, verifyTokenLength)
+	if _, err := rand.Read(token); err != nil {
+		return nil, eris.Wrap(err, "failed to generate verify token")
+	}
+	return token, nil
+}
+
+// EncryptionRequest makes the packet which starts encryption, serverID is empty on current versions
+func (k *ServerKey) EncryptionRequest(serverID string, verifyToken []byte) *EncryptionRequest {
+	return &EncryptionRequest{
+		ServerID:          serverID,
+		PublicKeyLength:   int32(len(k.publicDER)),
+		PublicKey:         k.publicDER,
+		VerifyTokenLength: int32(len(verifyToken)),
+		VerifyToken:       verifyToken,
+	}
+}
+
+// DecryptResponse decrypts the client's shared secret, checking its verify token matches the one it was sent
+func (k *ServerKey) DecryptResponse(response *EncryptionResponse, verifyToken []byte) ([]byte, error) {
+	token, err := rsa.DecryptPKCS1v15(rand.Reader, k.private, response.VerifyToken)
+	if err != nil {
+		return nil, eris.Wrap(err, "failed to decrypt verify token")
+	}
+	if subtle.ConstantTimeCompare(token, verifyToken) != 1 {
+		return nil, ErrVerifyTokenMismatch
+	}
+	sharedSecret, err := rsa.DecryptPKCS1v15(rand.Reader, k.private, response.SharedSecret)
+	if err != nil {
+		return nil, eris.Wrap(err, "failed to decrypt shared secret")
+	}
+	if len(sharedSecret) != SharedSecretLength {
+		return nil, eris.Errorf("shared secret is %v bytes, expected %v", len(sharedSecret), SharedSecretLength)
+	}
+	return sharedSecret, nil
+}
+
+// newCiphers makes the AES/CFB8 streams for each direction, the shared secret is both the key and the IV
+func newCiphers(sharedSecret []byte) (encrypt, decrypt *cfb8, err error) {
+	block, err := aes.NewCipher(sharedSecret)
+	if err != nil {
+		return nil, nil, eris.Wrap(err, "failed to create AES cipher")
+	}
+	return newCFB8(block, sharedSecret, false), newCFB8(block, sharedSecret, true), nil
+}
@@ -0,0 +1,164 @@
+package packet
+
+import (
+	"crypto/aes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestCFB8(t *testing.T) {
+	// NIST SP 800-38A F.3.7
+	key, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	iv, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	plaintext, _ := hex.DecodeString("6bc1bee22e409f96e93d7e117393172aae2d")
+	ciphertext, _ := hex.DecodeString("3b79424c9c0dd436bace9e0ed4586a4f32b9")
+	block, err := aes.NewCipher(key)
+	assert.NoError(t, err)
+
+	encrypted := make([]byte, len(plaintext))
+	NewCFB8Encrypter(block, iv).XORKeyStream(encrypted, plaintext)
+	assert.Equal(t, ciphertext, encrypted)
+
+	// Decrypting in pieces carries the register over between calls
+	decrypter := NewCFB8Decrypter(block, iv)
+	decrypted := make([]byte, len(ciphertext))
+	decrypter.XORKeyStream(decrypted[:5], ciphertext[:5])
+	decrypter.XORKeyStream(decrypted[5:], ciphertext[5:])
+	assert.Equal(t, plaintext, decrypted)
+}
+
+// scriptedClient plays the client side of the encryption handshake the way the vanilla client does
+func scriptedClient(t *testing.T, conn *Conn, sharedSecret []byte, tamper bool) {
+	pkt, err := conn.ReadPacket()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, VarInt(0x01), pkt.ID())
+	var request EncryptionRequest
+	assert.NoError(t, Unmarshal(pkt, &request))
+
+	parsed, err := x509.ParsePKIXPublicKey(request.PublicKey)
+	if !assert.NoError(t, err) {
+		return
+	}
+	publicKey := parsed.(*rsa.PublicKey)
+	assert.Equal(t, ServerKeyBits, publicKey.N.BitLen())
+
+	token := request.VerifyToken
+	if tamper {
+		token = []byte{0, 0, 0, 0}
+	}
+	encryptedSecret, err := rsa.EncryptPKCS1v15(rand.Reader, publicKey, sharedSecret)
+	assert.NoError(t, err)
+	encryptedToken, err := rsa.EncryptPKCS1v15(rand.Reader, publicKey, token)
+	assert.NoError(t, err)
+	assert.NoError(t, conn.WriteData(0x01, &EncryptionResponse{
+		SharedSecretLength: int32(len(encryptedSecret)),
+		SharedSecret:       encryptedSecret,
+		VerifyTokenLength:  int32(len(encryptedToken)),
+		VerifyToken:        encryptedToken,
+	}))
+	if tamper {
+		return
+	}
+
+	assert.NoError(t, conn.EnableEncryption(sharedSecret))
+	pkt, err = conn.ReadPacket()
+	if !assert.NoError(t, err) {
+		return
+	}
+	var success LoginSuccess
+	assert.NoError(t, Unmarshal(pkt, &success))
+	assert.NoError(t, conn.WriteData(0x00, &LoginStart{Name: success.Username}))
+}
+
+func serverHandshake(t *testing.T, conn *Conn, key *ServerKey) ([]byte, error) {
+	verifyToken, err := NewVerifyToken()
+	assert.NoError(t, err)
+	assert.NoError(t, conn.WriteData(0x01, key.EncryptionRequest("", verifyToken)))
+
+	pkt, err := conn.ReadPacket()
+	assert.NoError(t, err)
+	var response EncryptionResponse
+	assert.NoError(t, Unmarshal(pkt, &response))
+	return key.DecryptResponse(&response, verifyToken)
+}
+
+func TestEncryptionHandshake(t *testing.T) {
+	key, err := GenerateServerKey()
+	assert.NoError(t, err)
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	serverConn, clientConn := NewConn(server), NewConn(client)
+
+	sharedSecret := make([]byte, SharedSecretLength)
+	_, err = rand.Read(sharedSecret)
+	assert.NoError(t, err)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scriptedClient(t, clientConn, sharedSecret, false)
+	}()
+
+	secret, err := serverHandshake(t, serverConn, key)
+	assert.NoError(t, err)
+	assert.Equal(t, sharedSecret, secret)
+	assert.NoError(t, serverConn.EnableEncryption(secret))
+	assert.True(t, serverConn.Encrypted())
+
+	assert.NoError(t, serverConn.WriteData(0x02, &LoginSuccess{Username: "Notch"}))
+	pkt, err := serverConn.ReadPacket()
+	assert.NoError(t, err)
+	var start LoginStart
+	assert.NoError(t, Unmarshal(pkt, &start))
+	assert.Equal(t, "Notch", start.Name)
+	<-done
+}
+
+func TestEncryptionHandshakeRejectsWrongToken(t *testing.T) {
+	key, err := GenerateServerKey()
+	assert.NoError(t, err)
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scriptedClient(t, NewConn(client), make([]byte, SharedSecretLength), true)
+	}()
+
+	_, err = serverHandshake(t, NewConn(server), key)
+	assert.Error(t, err)
+	<-done
+}
+
+func TestConnEncryptsWire(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	serverConn := NewConn(server)
+	sharedSecret := []byte("0123456789abcdef")
+	assert.NoError(t, serverConn.EnableEncryption(sharedSecret))
+
+	frame := []byte{0x04, 0x02, 0x02, 'a', 'b'}
+	go func() {
+		assert.NoError(t, serverConn.WriteData(0x02, &struct{ Name string }{Name: "ab"}))
+	}()
+	wire := make([]byte, len(frame))
+	_, err := io.ReadFull(client, wire)
+	assert.NoError(t, err)
+	assert.NotEqual(t, frame, wire)
+
+	block, err := aes.NewCipher(sharedSecret)
+	assert.NoError(t, err)
+	NewCFB8Decrypter(block, sharedSecret).XORKeyStream(wire, wire)
+	assert.Equal(t, frame, wire)
+}
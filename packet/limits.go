@@ -0,0 +1,111 @@
+package packet
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+const (
+	// MaxFrameSize is the largest packet accepted, both on the wire and once decompressed
+	MaxFrameSize = 2 << 20
+	// MaxStringLength is the longest string the protocol allows, in characters
+	MaxStringLength = 32767
+	// MaxUsernameLength is the longest name a player can log in with
+	MaxUsernameLength = 16
+	// MaxServerAddressLength is the longest address a Conn accepts in a handshake unless told otherwise
+	MaxServerAddressLength = 255
+	// maxStringBytes is MaxStringLength with every character taking the most UTF-8 bytes it can
+	maxStringBytes = MaxStringLength * utf8.UTFMax
+	// maxPreallocated is the most elements allocated for an array before any of them have been read
+	maxPreallocated = 4096
+)
+
+type (
+	// LimitError is returned for a length the protocol doesn't allow, like a negative array length or an oversized
+	// frame. The stream can't be trusted afterwards, so the client is disconnected
+	LimitError struct {
+		// Field is what was too long, e.g. LoginStart.Name, empty when the caller adds it
+		Field  string
+		Length int64
+		Max    int64
+	}
+
+	// inflateLimitReader stops a compressed packet inflating to more than it said it would
+	inflateLimitReader struct {
+		io.ReadCloser
+		limit, read int64
+	}
+)
+
+// checkLength rejects negative lengths and those over max
+func checkLength(field string, length, max int64) error {
+	if length < 0 || length > max {
+		return &LimitError{Field: field, Length: length, Max: max}
+	}
+	return nil
+}
+
+// checkStringLength rejects strings with more than max characters
+func checkStringLength(field, s string, max int) error {
+	if count := utf8.RuneCountInString(s); count > max {
+		return &LimitError{Field: field, Length: int64(count), Max: int64(max)}
+	}
+	return nil
+}
+
+// readBytes reads n bytes, growing the buffer as data arrives rather than trusting n up front
+func readBytes(reader io.Reader, n int64) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	read, err := io.CopyN(buf, reader, n)
+	if err == io.EOF && read < n {
+		err = io.ErrUnexpectedEOF
+	}
+	return buf.Bytes(), err
+}
+
+// preallocated caps the capacity allocated for count elements before any have been read, a packet claiming millions
+// of them has to actually hold them to get the memory
+func preallocated(count int64) int {
+	if count > maxPreallocated {
+		return maxPreallocated
+	}
+	return int(count)
+}
+
+// readBuffer is readBytes into a field, for generated code
+func readBuffer(reader io.Reader, into *[]byte, n int64) error {
+	bs, err := readBytes(reader, n)
+	*into = bs
+	return err
+}
+
+// Read lets through one byte more than the limit, which is enough to tell the packet lied
+func (r *inflateLimitReader) Read(p []byte) (int, error) {
+	if allowed := r.limit - r.read + 1; int64(len(p)) > allowed {
+		p = p[:allowed]
+	}
+	n, err := r.ReadCloser.Read(p)
+	r.read += int64(n)
+	if r.read > r.limit {
+		return n, &LimitError{Field: "decompressed packet", Length: r.read, Max: r.limit}
+	}
+	return n, err
+}
+
+func (e *LimitError) Error() string {
+	message := fmt.Sprintf("length %v is over the limit of %v", e.Length, e.Max)
+	if e.Length < 0 {
+		message = fmt.Sprintf("negative length %v", e.Length)
+	}
+	if e.Field != "" {
+		message += " for " + e.Field
+	}
+	return message
+}
+
+// Disconnect is the message shown to the client
+func (e *LimitError) Disconnect() *Disconnect {
+	return &Disconnect{Reason: TextComponent("Bad packet: " + e.Error()).JSON()}
+}
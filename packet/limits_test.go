@@ -0,0 +1,147 @@
+package packet
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func varIntBytes(t *testing.T, values ...VarInt) []byte {
+	buf := bytes.NewBuffer(nil)
+	for _, value := range values {
+		_, err := value.WriteTo(buf)
+		assert.NoError(t, err)
+	}
+	return buf.Bytes()
+}
+
+// compressedFrame frames data as sent with compression on, claiming it inflates to dataLen bytes
+func compressedFrame(t *testing.T, dataLen VarInt, data []byte) []byte {
+	compressed := bytes.NewBuffer(nil)
+	writer := zlib.NewWriter(compressed)
+	_, err := writer.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	body := append(varIntBytes(t, dataLen), compressed.Bytes()...)
+	return append(varIntBytes(t, VarInt(len(body))), body...)
+}
+
+func assertLimitError(t *testing.T, err error, field string) {
+	var limit *LimitError
+	if assert.True(t, errors.As(err, &limit), "%v", err) {
+		assert.Equal(t, field, limit.Field)
+	}
+}
+
+func TestFrameLimits(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame []byte
+		read  func(io.Reader) (Packet, error)
+		field string
+	}{
+		{
+			name:  "oversized",
+			frame: varIntBytes(t, MaxFrameSize+1, 0x00),
+			read:  MakeUncompressedPacket,
+			field: "packet",
+		},
+		{
+			name:  "negative",
+			frame: varIntBytes(t, -1, 0x00),
+			read:  MakeUncompressedPacket,
+			field: "packet",
+		},
+		{
+			name:  "oversized compressed",
+			frame: varIntBytes(t, MaxFrameSize+1),
+			read:  MakeCompressedPacket,
+			field: "packet",
+		},
+		{
+			name:  "inflates past the limit",
+			frame: compressedFrame(t, MaxFrameSize+1, []byte{0x00}),
+			read:  MakeCompressedPacket,
+			field: "decompressed packet",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.read(bytes.NewReader(test.frame))
+			assertLimitError(t, err, test.field)
+		})
+	}
+}
+
+func TestInflationCap(t *testing.T) {
+	// Claims 16 bytes but inflates to a megabyte of zeroes
+	pkt, err := MakeCompressedPacket(bytes.NewReader(compressedFrame(t, 16, make([]byte, 1<<20))))
+	assert.NoError(t, err)
+	reader, err := pkt.DataReader()
+	assert.NoError(t, err)
+	_, err = io.ReadAll(reader)
+	assertLimitError(t, err, "decompressed packet")
+
+	pkt, err = MakeCompressedPacket(bytes.NewReader(compressedFrame(t, 16, make([]byte, 16))))
+	assert.NoError(t, err)
+	reader, err = pkt.DataReader()
+	assert.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Len(t, data, 15)
+}
+
+func TestFieldLimits(t *testing.T) {
+	var s String
+	// Nothing follows the length, it has to be rejected before anything is read or allocated
+	_, err := s.ReadFrom(bytes.NewReader(varIntBytes(t, maxStringBytes+1)))
+	assertLimitError(t, err, "String")
+	long := strings.Repeat("é", MaxStringLength+1)
+	_, err = s.ReadFrom(bytes.NewReader(append(varIntBytes(t, VarInt(len(long))), long...)))
+	assertLimitError(t, err, "String")
+
+	var ba ByteArray
+	_, err = ba.ReadFrom(bytes.NewReader(varIntBytes(t, -1)))
+	assertLimitError(t, err, "ByteArray")
+
+	tests := []struct {
+		payload generatedPacket
+		field   string
+	}{
+		{payload: &LoginStart{Name: strings.Repeat("a", MaxUsernameLength+1)}, field: "LoginStart.Name"},
+		{payload: &EncryptionResponse{SharedSecretLength: MaxFrameSize + 1}, field: "EncryptionResponse.SharedSecret"},
+	}
+	for _, test := range tests {
+		buf := bytes.NewBuffer(nil)
+		assert.NoError(t, test.payload.MarshalPacket(buf))
+		assertLimitError(t, test.payload.UnmarshalPacket(buf), test.field)
+	}
+
+	// At the limit is fine
+	buf := bytes.NewBuffer(nil)
+	assert.NoError(t, (&LoginStart{Name: strings.Repeat("é", MaxUsernameLength)}).MarshalPacket(buf))
+	var loginStart LoginStart
+	assert.NoError(t, loginStart.UnmarshalPacket(buf))
+}
+
+func TestArrayCountsDontPreallocate(t *testing.T) {
+	// A frame's worth of Statistics with none of them there
+	data := varIntBytes(t, MaxFrameSize)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	err := (&Statistics{}).UnmarshalPacket(bytes.NewReader(data))
+	runtime.ReadMemStats(&after)
+	assert.Error(t, err)
+	assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(1<<20))
+}
+
+func TestLimitErrorDisconnects(t *testing.T) {
+	err := &LimitError{Field: "LoginStart.Name", Length: 17, Max: 16}
+	assert.Equal(t, "length 17 is over the limit of 16 for LoginStart.Name", err.Error())
+	assert.Equal(t, `{"text":"Bad packet: length 17 is over the limit of 16 for LoginStart.Name"}`, err.Disconnect().Reason)
+}
@@ -63,9 +63,15 @@ func MakeUncompressedPacket(reader io.Reader) (Packet, error) {
 	}
 
 	pktIdLen := lens[1]
+	if err = checkLength("packet", int64(pktLen), MaxFrameSize); err != nil {
+		return nil, err
+	}
 
 	// Length is ID + data
 	dataLen := pktLen - VarInt(pktIdLen)
+	if dataLen < 0 {
+		return nil, eris.Errorf("packet length %v is shorter than its ID", pktLen)
+	}
 	var byteData ByteArray
 	baReader, err := ByteArrayReader(dataLen, reader)
 	if err != nil {
@@ -84,29 +90,56 @@ func MakeUncompressedPacket(reader io.Reader) (Packet, error) {
 	}, nil
 }
 
+// MakeCompressedPacket reads a frame sent once compression is enabled. A Data Length of 0 means the packet was
+// under the threshold and follows uncompressed
 func MakeCompressedPacket(reader io.Reader) (Packet, error) {
 	var pktLen VarInt
-	var dataLen VarInt
-	var compressedData ByteArray
-	err := ReadFields(reader, &pktLen, &dataLen, &compressedData)
-	if err != nil {
+	if _, err := pktLen.ReadFrom(reader); err != nil {
 		return nil, err
 	}
+	if err := checkLength("packet", int64(pktLen), MaxFrameSize); err != nil {
+		return nil, err
+	}
+	frame, err := readBytes(reader, int64(pktLen))
+	if err != nil {
+		return nil, eris.Wrap(err, "failed to read packet frame")
+	}
+	frameReader := bytes.NewReader(frame)
 
+	var dataLen VarInt
+	if _, err := dataLen.ReadFrom(frameReader); err != nil {
+		return nil, eris.Wrap(err, "failed to read data length")
+	}
+
+	var pktId VarInt
+	if dataLen == 0 {
+		if _, err := pktId.ReadFrom(frameReader); err != nil {
+			return nil, err
+		}
+		return &UncompressedPacket{
+			packetID:   pktId,
+			dataLength: VarInt(frameReader.Len()),
+			readCloser: io.NopCloser(frameReader),
+		}, nil
+	}
+
+	if err = checkLength("decompressed packet", int64(dataLen), MaxFrameSize); err != nil {
+		return nil, err
+	}
 	// zlReader needs closing within the packet code
-	zlReader, err := zlib.NewReader(bytes.NewReader(compressedData))
+	inflater, err := zlib.NewReader(frameReader)
 	if err != nil {
 		return nil, err
 	}
-
-	var pktId VarInt
-	if _, err = pktId.ReadFrom(zlReader); err != nil {
+	zlReader := &inflateLimitReader{ReadCloser: inflater, limit: int64(dataLen)}
+	pktIdLen, err := pktId.ReadFrom(zlReader)
+	if err != nil {
 		return nil, err
 	}
 
 	return &CompressedPacket{
 		packetID:   pktId,
-		dataLength: dataLen,
+		dataLength: dataLen - VarInt(pktIdLen),
 		readCloser: zlReader,
 	}, nil
 }
@@ -152,3 +185,47 @@ func WriteTo(pkt Packet, writer io.Writer) (count int64, err error) {
 	_, err = io.Copy(writer, b)
 	return
 }
+
+// WriteCompressedTo writes a packet in the compressed format, only compressing it if the ID and data are at least
+// threshold bytes long
+func WriteCompressedTo(pkt Packet, writer io.Writer, threshold int) (count int64, err error) {
+	data := bytes.NewBuffer(nil)
+	if _, err = pkt.ID().WriteTo(data); err != nil {
+		return
+	}
+	r, err := pkt.DataReader()
+	if err != nil {
+		return
+	}
+	if _, err = io.Copy(data, r); err != nil {
+		return
+	}
+
+	frame := bytes.NewBuffer(nil)
+	if data.Len() < threshold {
+		if _, err = VarInt(0).WriteTo(frame); err != nil {
+			return
+		}
+		if _, err = data.WriteTo(frame); err != nil {
+			return
+		}
+	} else {
+		if _, err = VarInt(data.Len()).WriteTo(frame); err != nil {
+			return
+		}
+		zlWriter := zlib.NewWriter(frame)
+		if _, err = data.WriteTo(zlWriter); err != nil {
+			return
+		}
+		if err = zlWriter.Close(); err != nil {
+			return
+		}
+	}
+
+	count, err = VarInt(frame.Len()).WriteTo(writer)
+	if err != nil {
+		return
+	}
+	n, err := frame.WriteTo(writer)
+	return count + n, err
+}
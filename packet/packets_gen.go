@@ -0,0 +1,1190 @@
+// Code generated by packetgen from protocol.json; DO NOT EDIT.
+
+package packet
+
+import (
+	"github.com/google/uuid"
+	"io"
+	"minecraftServer/nbt"
+)
+
+// Packet IDs of protocol 754 (1.16.5)
+const (
+	HandshakingHandshakeID         VarInt = 0x00
+	StatusStatusRequestID          VarInt = 0x00
+	StatusPingID                   VarInt = 0x01
+	StatusStatusResponseID         VarInt = 0x00
+	StatusPongID                   VarInt = 0x01
+	LoginLoginStartID              VarInt = 0x00
+	LoginEncryptionResponseID      VarInt = 0x01
+	LoginLoginPluginResponseID     VarInt = 0x02
+	LoginDisconnectID              VarInt = 0x00
+	LoginEncryptionRequestID       VarInt = 0x01
+	LoginLoginSuccessID            VarInt = 0x02
+	LoginSetCompressionID          VarInt = 0x03
+	LoginLoginPluginRequestID      VarInt = 0x04
+	PlayKeepAliveResponseID        VarInt = 0x10
+	PlaySpawnEntityID              VarInt = 0x00
+	PlaySpawnExperienceOrbID       VarInt = 0x01
+	PlaySpawnLivingEntityID        VarInt = 0x02
+	PlaySpawnPaintingID            VarInt = 0x03
+	PlaySpawnPlayerID              VarInt = 0x04
+	PlayEntityAnimationID          VarInt = 0x05
+	PlayStatisticsID               VarInt = 0x06
+	PlayAcknowledgePlayerDiggingID VarInt = 0x07
+	PlayBlockBreakAnimationID      VarInt = 0x08
+	PlayDisconnectID               VarInt = 0x19
+	PlayKeepAliveID                VarInt = 0x1F
+	PlayJoinGameID                 VarInt = 0x24
+)
+
+type (
+	// Handshake is a Serverbound Handshaking packet
+	Handshake struct {
+		ProtocolVersion int32 `pkt_type:"VarInt"`
+		ServerAddress   string
+		ServerPort      uint16
+		NextState       int32 `pkt_type:"VarInt"`
+	}
+
+	// StatusRequest is a Serverbound Status packet
+	StatusRequest struct{}
+
+	// Ping is a Serverbound Status packet
+	Ping struct {
+		Payload int64
+	}
+
+	// StatusResponse is a Clientbound Status packet
+	StatusResponse struct {
+		JSONResponse string
+	}
+
+	// Pong is a Clientbound Status packet
+	Pong struct {
+		Payload int64
+	}
+
+	// LoginStart is a Serverbound Login packet
+	LoginStart struct {
+		Name string
+	}
+
+	// EncryptionResponse is a Serverbound Login packet
+	EncryptionResponse struct {
+		SharedSecretLength int32  `pkt_type:"VarInt"`
+		SharedSecret       []byte `pkt_len:"SharedSecretLength"`
+		VerifyTokenLength  int32  `pkt_type:"VarInt"`
+		VerifyToken        []byte `pkt_len:"VerifyTokenLength"`
+	}
+
+	// LoginPluginResponse is a Serverbound Login packet
+	LoginPluginResponse struct {
+		MessageID  int32 `pkt_type:"VarInt"`
+		Successful bool
+		Data       []byte `pkt_opt:"Successful"`
+	}
+
+	// Disconnect is a Clientbound Login and Clientbound Play packet
+	Disconnect struct {
+		Reason string
+	}
+
+	// EncryptionRequest is a Clientbound Login packet
+	EncryptionRequest struct {
+		ServerID          string
+		PublicKeyLength   int32  `pkt_type:"VarInt"`
+		PublicKey         []byte `pkt_len:"PublicKeyLength"`
+		VerifyTokenLength int32  `pkt_type:"VarInt"`
+		VerifyToken       []byte `pkt_len:"VerifyTokenLength"`
+	}
+
+	// LoginSuccess is a Clientbound Login packet
+	LoginSuccess struct {
+		UUID     uuid.UUID
+		Username string
+	}
+
+	// SetCompression is a Clientbound Login packet
+	SetCompression struct {
+		Threshold int32 `pkt_type:"VarInt"`
+	}
+
+	// LoginPluginRequest is a Clientbound Login packet
+	LoginPluginRequest struct {
+		MessageID int32 `pkt_type:"VarInt"`
+		Channel   string
+		Data      []byte
+	}
+
+	// KeepAliveResponse is a Serverbound Play packet
+	KeepAliveResponse struct {
+		KeepAliveID int64
+	}
+
+	// SpawnEntity is a Clientbound Play packet
+	SpawnEntity struct {
+		EntityID   int32 `pkt_type:"VarInt"`
+		ObjectUUID uuid.UUID
+		Type       int32 `pkt_type:"VarInt"`
+		X          float64
+		Y          float64
+		Z          float64
+		Pitch      Angle
+		Yaw        Angle
+		Data       int32
+		VelocityX  int16
+		VelocityY  int16
+		VelocityZ  int16
+	}
+
+	// SpawnExperienceOrb is a Clientbound Play packet
+	SpawnExperienceOrb struct {
+		EntityID int32 `pkt_type:"VarInt"`
+		X        float64
+		Y        float64
+		Z        float64
+		Count    int16
+	}
+
+	// SpawnLivingEntity is a Clientbound Play packet
+	SpawnLivingEntity struct {
+		EntityID   int32 `pkt_type:"VarInt"`
+		EntityUUID uuid.UUID
+		Type       int32 `pkt_type:"VarInt"`
+		X          float64
+		Y          float64
+		Z          float64
+		Yaw        Angle
+		Pitch      Angle
+		HeadPitch  Angle
+		VelocityX  int16
+		VelocityY  int16
+		VelocityZ  int16
+	}
+
+	// SpawnPainting is a Clientbound Play packet
+	SpawnPainting struct {
+		EntityID   int32 `pkt_type:"VarInt"`
+		EntityUUID uuid.UUID
+		Motive     int32 `pkt_type:"VarInt"`
+		Location   Position
+		// South = 0, West = 1, North = 2, East = 3
+		Direction uint8
+	}
+
+	// SpawnPlayer is a Clientbound Play packet
+	SpawnPlayer struct {
+		EntityID   int32 `pkt_type:"VarInt"`
+		PlayerUUID uuid.UUID
+		X          float64
+		Y          float64
+		Z          float64
+		Yaw        Angle
+		Pitch      Angle
+	}
+
+	// EntityAnimation is a Clientbound Play packet
+	EntityAnimation struct {
+		EntityID int32 `pkt_type:"VarInt"`
+		// 0 swings the main arm, 1 is taking damage, 2 leaves a bed, 3 swings the offhand, 4 and 5 are critical effects
+		Animation uint8
+	}
+
+	// Statistics is a Clientbound Play packet
+	Statistics struct {
+		Count     int32       `pkt_type:"VarInt"`
+		Statistic []Statistic `pkt_len:"Count"`
+	}
+
+	// AcknowledgePlayerDigging is a Clientbound Play packet
+	AcknowledgePlayerDigging struct {
+		Location   Position
+		Block      int32 `pkt_type:"VarInt"`
+		Status     int32 `pkt_type:"VarInt"`
+		Successful bool
+	}
+
+	// BlockBreakAnimation is a Clientbound Play packet
+	BlockBreakAnimation struct {
+		EntityID     int32 `pkt_type:"VarInt"`
+		Location     Position
+		DestroyStage uint8
+	}
+
+	// KeepAlive is a Clientbound Play packet
+	KeepAlive struct {
+		KeepAliveID int64
+	}
+
+	// JoinGame is a Clientbound Play packet
+	JoinGame struct {
+		EntityID            int32
+		IsHardcore          bool
+		Gamemode            uint8
+		PreviousGamemode    int8
+		WorldCount          int32             `pkt_type:"VarInt"`
+		WorldNames          []string          `pkt_len:"WorldCount"`
+		DimensionCodec      DimensionCodecNBT `pkt_type:"nbt"`
+		Dimension           DimensionTypeNBT  `pkt_type:"nbt"`
+		WorldName           string
+		HashedSeed          int64
+		MaxPlayers          int32 `pkt_type:"VarInt"`
+		ViewDistance        int32 `pkt_type:"VarInt"`
+		ReducedDebugInfo    bool
+		EnableRespawnScreen bool
+		IsDebug             bool
+		IsFlat              bool
+	}
+
+	Statistic struct {
+		CategoryID  int32 `pkt_type:"VarInt"`
+		StatisticID int32 `pkt_type:"VarInt"`
+		Value       int32 `pkt_type:"VarInt"`
+	}
+)
+
+// registerHandshaking adds the Handshaking packets of protocol 754
+func registerHandshaking(r *Registry) {
+	r.Register(Handshaking, Serverbound, HandshakingHandshakeID, Handshake{})
+}
+
+// registerStatus adds the Status packets of protocol 754
+func registerStatus(r *Registry) {
+	r.Register(Status, Serverbound, StatusStatusRequestID, StatusRequest{})
+	r.Register(Status, Serverbound, StatusPingID, Ping{})
+	r.Register(Status, Clientbound, StatusStatusResponseID, StatusResponse{})
+	r.Register(Status, Clientbound, StatusPongID, Pong{})
+}
+
+// registerLogin adds the Login packets of protocol 754
+func registerLogin(r *Registry) {
+	r.Register(Login, Serverbound, LoginLoginStartID, LoginStart{})
+	r.Register(Login, Serverbound, LoginEncryptionResponseID, EncryptionResponse{})
+	r.Register(Login, Serverbound, LoginLoginPluginResponseID, LoginPluginResponse{})
+	r.Register(Login, Clientbound, LoginDisconnectID, Disconnect{})
+	r.Register(Login, Clientbound, LoginEncryptionRequestID, EncryptionRequest{})
+	r.Register(Login, Clientbound, LoginLoginSuccessID, LoginSuccess{})
+	r.Register(Login, Clientbound, LoginSetCompressionID, SetCompression{})
+	r.Register(Login, Clientbound, LoginLoginPluginRequestID, LoginPluginRequest{})
+}
+
+// registerPlay adds the Play packets of protocol 754
+func registerPlay(r *Registry) {
+	r.Register(Play, Serverbound, PlayKeepAliveResponseID, KeepAliveResponse{})
+	r.Register(Play, Clientbound, PlaySpawnEntityID, SpawnEntity{})
+	r.Register(Play, Clientbound, PlaySpawnExperienceOrbID, SpawnExperienceOrb{})
+	r.Register(Play, Clientbound, PlaySpawnLivingEntityID, SpawnLivingEntity{})
+	r.Register(Play, Clientbound, PlaySpawnPaintingID, SpawnPainting{})
+	r.Register(Play, Clientbound, PlaySpawnPlayerID, SpawnPlayer{})
+	r.Register(Play, Clientbound, PlayEntityAnimationID, EntityAnimation{})
+	r.Register(Play, Clientbound, PlayStatisticsID, Statistics{})
+	r.Register(Play, Clientbound, PlayAcknowledgePlayerDiggingID, AcknowledgePlayerDigging{})
+	r.Register(Play, Clientbound, PlayBlockBreakAnimationID, BlockBreakAnimation{})
+	r.Register(Play, Clientbound, PlayDisconnectID, Disconnect{})
+	r.Register(Play, Clientbound, PlayKeepAliveID, KeepAlive{})
+	r.Register(Play, Clientbound, PlayJoinGameID, JoinGame{})
+}
+
+// MarshalPacket writes the fields in order without reflection
+func (p *Handshake) MarshalPacket(w io.Writer) error {
+	if _, err := VarInt(p.ProtocolVersion).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := String(p.ServerAddress).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := UnsignedShort(p.ServerPort).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := VarInt(p.NextState).WriteTo(w); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnmarshalPacket reads the fields in order without reflection
+func (p *Handshake) UnmarshalPacket(r io.Reader) error {
+	if _, err := (*VarInt)(&p.ProtocolVersion).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*String)(&p.ServerAddress).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*UnsignedShort)(&p.ServerPort).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*VarInt)(&p.NextState).ReadFrom(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalPacket writes the fields in order without reflection
+func (p *StatusRequest) MarshalPacket(w io.Writer) error {
+	return nil
+}
+
+// UnmarshalPacket reads the fields in order without reflection
+func (p *StatusRequest) UnmarshalPacket(r io.Reader) error {
+	return nil
+}
+
+// MarshalPacket writes the fields in order without reflection
+func (p *Ping) MarshalPacket(w io.Writer) error {
+	if _, err := Long(p.Payload).WriteTo(w); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnmarshalPacket reads the fields in order without reflection
+func (p *Ping) UnmarshalPacket(r io.Reader) error {
+	if _, err := (*Long)(&p.Payload).ReadFrom(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalPacket writes the fields in order without reflection
+func (p *StatusResponse) MarshalPacket(w io.Writer) error {
+	if _, err := String(p.JSONResponse).WriteTo(w); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnmarshalPacket reads the fields in order without reflection
+func (p *StatusResponse) UnmarshalPacket(r io.Reader) error {
+	if _, err := (*String)(&p.JSONResponse).ReadFrom(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalPacket writes the fields in order without reflection
+func (p *Pong) MarshalPacket(w io.Writer) error {
+	if _, err := Long(p.Payload).WriteTo(w); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnmarshalPacket reads the fields in order without reflection
+func (p *Pong) UnmarshalPacket(r io.Reader) error {
+	if _, err := (*Long)(&p.Payload).ReadFrom(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalPacket writes the fields in order without reflection
+func (p *LoginStart) MarshalPacket(w io.Writer) error {
+	if _, err := String(p.Name).WriteTo(w); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnmarshalPacket reads the fields in order without reflection
+func (p *LoginStart) UnmarshalPacket(r io.Reader) error {
+	if _, err := (*String)(&p.Name).ReadFrom(r); err != nil {
+		return err
+	}
+	if err := checkStringLength("LoginStart.Name", p.Name, MaxUsernameLength); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalPacket writes the fields in order without reflection
+func (p *EncryptionResponse) MarshalPacket(w io.Writer) error {
+	if _, err := VarInt(p.SharedSecretLength).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := w.Write(p.SharedSecret); err != nil {
+		return err
+	}
+	if _, err := VarInt(p.VerifyTokenLength).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := w.Write(p.VerifyToken); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnmarshalPacket reads the fields in order without reflection
+func (p *EncryptionResponse) UnmarshalPacket(r io.Reader) error {
+	if _, err := (*VarInt)(&p.SharedSecretLength).ReadFrom(r); err != nil {
+		return err
+	}
+	if err := checkLength("EncryptionResponse.SharedSecret", int64(p.SharedSecretLength), MaxFrameSize); err != nil {
+		return err
+	}
+	if err := readBuffer(r, &p.SharedSecret, int64(p.SharedSecretLength)); err != nil {
+		return err
+	}
+	if _, err := (*VarInt)(&p.VerifyTokenLength).ReadFrom(r); err != nil {
+		return err
+	}
+	if err := checkLength("EncryptionResponse.VerifyToken", int64(p.VerifyTokenLength), MaxFrameSize); err != nil {
+		return err
+	}
+	if err := readBuffer(r, &p.VerifyToken, int64(p.VerifyTokenLength)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalPacket writes the fields in order without reflection
+func (p *LoginPluginResponse) MarshalPacket(w io.Writer) error {
+	if _, err := VarInt(p.MessageID).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Boolean(p.Successful).WriteTo(w); err != nil {
+		return err
+	}
+	if p.Successful {
+		if _, err := w.Write(p.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnmarshalPacket reads the fields in order without reflection
+func (p *LoginPluginResponse) UnmarshalPacket(r io.Reader) error {
+	if _, err := (*VarInt)(&p.MessageID).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Boolean)(&p.Successful).ReadFrom(r); err != nil {
+		return err
+	}
+	if p.Successful {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		p.Data = data
+	}
+	return nil
+}
+
+// MarshalPacket writes the fields in order without reflection
+func (p *Disconnect) MarshalPacket(w io.Writer) error {
+	if _, err := String(p.Reason).WriteTo(w); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnmarshalPacket reads the fields in order without reflection
+func (p *Disconnect) UnmarshalPacket(r io.Reader) error {
+	if _, err := (*String)(&p.Reason).ReadFrom(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalPacket writes the fields in order without reflection
+func (p *EncryptionRequest) MarshalPacket(w io.Writer) error {
+	if _, err := String(p.ServerID).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := VarInt(p.PublicKeyLength).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := w.Write(p.PublicKey); err != nil {
+		return err
+	}
+	if _, err := VarInt(p.VerifyTokenLength).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := w.Write(p.VerifyToken); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnmarshalPacket reads the fields in order without reflection
+func (p *EncryptionRequest) UnmarshalPacket(r io.Reader) error {
+	if _, err := (*String)(&p.ServerID).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*VarInt)(&p.PublicKeyLength).ReadFrom(r); err != nil {
+		return err
+	}
+	if err := checkLength("EncryptionRequest.PublicKey", int64(p.PublicKeyLength), MaxFrameSize); err != nil {
+		return err
+	}
+	if err := readBuffer(r, &p.PublicKey, int64(p.PublicKeyLength)); err != nil {
+		return err
+	}
+	if _, err := (*VarInt)(&p.VerifyTokenLength).ReadFrom(r); err != nil {
+		return err
+	}
+	if err := checkLength("EncryptionRequest.VerifyToken", int64(p.VerifyTokenLength), MaxFrameSize); err != nil {
+		return err
+	}
+	if err := readBuffer(r, &p.VerifyToken, int64(p.VerifyTokenLength)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalPacket writes the fields in order without reflection
+func (p *LoginSuccess) MarshalPacket(w io.Writer) error {
+	if _, err := UUID(p.UUID).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := String(p.Username).WriteTo(w); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnmarshalPacket reads the fields in order without reflection
+func (p *LoginSuccess) UnmarshalPacket(r io.Reader) error {
+	if _, err := (*UUID)(&p.UUID).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*String)(&p.Username).ReadFrom(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalPacket writes the fields in order without reflection
+func (p *SetCompression) MarshalPacket(w io.Writer) error {
+	if _, err := VarInt(p.Threshold).WriteTo(w); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnmarshalPacket reads the fields in order without reflection
+func (p *SetCompression) UnmarshalPacket(r io.Reader) error {
+	if _, err := (*VarInt)(&p.Threshold).ReadFrom(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalPacket writes the fields in order without reflection
+func (p *LoginPluginRequest) MarshalPacket(w io.Writer) error {
+	if _, err := VarInt(p.MessageID).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := String(p.Channel).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := w.Write(p.Data); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnmarshalPacket reads the fields in order without reflection
+func (p *LoginPluginRequest) UnmarshalPacket(r io.Reader) error {
+	if _, err := (*VarInt)(&p.MessageID).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*String)(&p.Channel).ReadFrom(r); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	p.Data = data
+	return nil
+}
+
+// MarshalPacket writes the fields in order without reflection
+func (p *KeepAliveResponse) MarshalPacket(w io.Writer) error {
+	if _, err := Long(p.KeepAliveID).WriteTo(w); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnmarshalPacket reads the fields in order without reflection
+func (p *KeepAliveResponse) UnmarshalPacket(r io.Reader) error {
+	if _, err := (*Long)(&p.KeepAliveID).ReadFrom(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalPacket writes the fields in order without reflection
+func (p *SpawnEntity) MarshalPacket(w io.Writer) error {
+	if _, err := VarInt(p.EntityID).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := UUID(p.ObjectUUID).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := VarInt(p.Type).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Double(p.X).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Double(p.Y).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Double(p.Z).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := p.Pitch.WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := p.Yaw.WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Int(p.Data).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Short(p.VelocityX).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Short(p.VelocityY).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Short(p.VelocityZ).WriteTo(w); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnmarshalPacket reads the fields in order without reflection
+func (p *SpawnEntity) UnmarshalPacket(r io.Reader) error {
+	if _, err := (*VarInt)(&p.EntityID).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*UUID)(&p.ObjectUUID).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*VarInt)(&p.Type).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Double)(&p.X).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Double)(&p.Y).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Double)(&p.Z).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := p.Pitch.ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := p.Yaw.ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Int)(&p.Data).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Short)(&p.VelocityX).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Short)(&p.VelocityY).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Short)(&p.VelocityZ).ReadFrom(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalPacket writes the fields in order without reflection
+func (p *SpawnExperienceOrb) MarshalPacket(w io.Writer) error {
+	if _, err := VarInt(p.EntityID).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Double(p.X).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Double(p.Y).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Double(p.Z).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Short(p.Count).WriteTo(w); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnmarshalPacket reads the fields in order without reflection
+func (p *SpawnExperienceOrb) UnmarshalPacket(r io.Reader) error {
+	if _, err := (*VarInt)(&p.EntityID).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Double)(&p.X).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Double)(&p.Y).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Double)(&p.Z).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Short)(&p.Count).ReadFrom(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalPacket writes the fields in order without reflection
+func (p *SpawnLivingEntity) MarshalPacket(w io.Writer) error {
+	if _, err := VarInt(p.EntityID).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := UUID(p.EntityUUID).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := VarInt(p.Type).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Double(p.X).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Double(p.Y).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Double(p.Z).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := p.Yaw.WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := p.Pitch.WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := p.HeadPitch.WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Short(p.VelocityX).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Short(p.VelocityY).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Short(p.VelocityZ).WriteTo(w); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnmarshalPacket reads the fields in order without reflection
+func (p *SpawnLivingEntity) UnmarshalPacket(r io.Reader) error {
+	if _, err := (*VarInt)(&p.EntityID).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*UUID)(&p.EntityUUID).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*VarInt)(&p.Type).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Double)(&p.X).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Double)(&p.Y).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Double)(&p.Z).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := p.Yaw.ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := p.Pitch.ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := p.HeadPitch.ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Short)(&p.VelocityX).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Short)(&p.VelocityY).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Short)(&p.VelocityZ).ReadFrom(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalPacket writes the fields in order without reflection
+func (p *SpawnPainting) MarshalPacket(w io.Writer) error {
+	if _, err := VarInt(p.EntityID).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := UUID(p.EntityUUID).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := VarInt(p.Motive).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := p.Location.WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := UnsignedByte(p.Direction).WriteTo(w); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnmarshalPacket reads the fields in order without reflection
+func (p *SpawnPainting) UnmarshalPacket(r io.Reader) error {
+	if _, err := (*VarInt)(&p.EntityID).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*UUID)(&p.EntityUUID).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*VarInt)(&p.Motive).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := p.Location.ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*UnsignedByte)(&p.Direction).ReadFrom(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalPacket writes the fields in order without reflection
+func (p *SpawnPlayer) MarshalPacket(w io.Writer) error {
+	if _, err := VarInt(p.EntityID).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := UUID(p.PlayerUUID).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Double(p.X).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Double(p.Y).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Double(p.Z).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := p.Yaw.WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := p.Pitch.WriteTo(w); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnmarshalPacket reads the fields in order without reflection
+func (p *SpawnPlayer) UnmarshalPacket(r io.Reader) error {
+	if _, err := (*VarInt)(&p.EntityID).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*UUID)(&p.PlayerUUID).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Double)(&p.X).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Double)(&p.Y).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Double)(&p.Z).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := p.Yaw.ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := p.Pitch.ReadFrom(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalPacket writes the fields in order without reflection
+func (p *EntityAnimation) MarshalPacket(w io.Writer) error {
+	if _, err := VarInt(p.EntityID).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := UnsignedByte(p.Animation).WriteTo(w); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnmarshalPacket reads the fields in order without reflection
+func (p *EntityAnimation) UnmarshalPacket(r io.Reader) error {
+	if _, err := (*VarInt)(&p.EntityID).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*UnsignedByte)(&p.Animation).ReadFrom(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalPacket writes the fields in order without reflection
+func (p *Statistics) MarshalPacket(w io.Writer) error {
+	if _, err := VarInt(p.Count).WriteTo(w); err != nil {
+		return err
+	}
+	for i := range p.Statistic {
+		if err := p.Statistic[i].MarshalPacket(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnmarshalPacket reads the fields in order without reflection
+func (p *Statistics) UnmarshalPacket(r io.Reader) error {
+	if _, err := (*VarInt)(&p.Count).ReadFrom(r); err != nil {
+		return err
+	}
+	if err := checkLength("Statistics.Statistic", int64(p.Count), MaxFrameSize); err != nil {
+		return err
+	}
+	p.Statistic = make([]Statistic, 0, preallocated(int64(p.Count)))
+	for i := 0; i < int(p.Count); i++ {
+		var element Statistic
+		if err := element.UnmarshalPacket(r); err != nil {
+			return err
+		}
+		p.Statistic = append(p.Statistic, element)
+	}
+	return nil
+}
+
+// MarshalPacket writes the fields in order without reflection
+func (p *AcknowledgePlayerDigging) MarshalPacket(w io.Writer) error {
+	if _, err := p.Location.WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := VarInt(p.Block).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := VarInt(p.Status).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Boolean(p.Successful).WriteTo(w); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnmarshalPacket reads the fields in order without reflection
+func (p *AcknowledgePlayerDigging) UnmarshalPacket(r io.Reader) error {
+	if _, err := p.Location.ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*VarInt)(&p.Block).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*VarInt)(&p.Status).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Boolean)(&p.Successful).ReadFrom(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalPacket writes the fields in order without reflection
+func (p *BlockBreakAnimation) MarshalPacket(w io.Writer) error {
+	if _, err := VarInt(p.EntityID).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := p.Location.WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := UnsignedByte(p.DestroyStage).WriteTo(w); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnmarshalPacket reads the fields in order without reflection
+func (p *BlockBreakAnimation) UnmarshalPacket(r io.Reader) error {
+	if _, err := (*VarInt)(&p.EntityID).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := p.Location.ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*UnsignedByte)(&p.DestroyStage).ReadFrom(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalPacket writes the fields in order without reflection
+func (p *KeepAlive) MarshalPacket(w io.Writer) error {
+	if _, err := Long(p.KeepAliveID).WriteTo(w); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnmarshalPacket reads the fields in order without reflection
+func (p *KeepAlive) UnmarshalPacket(r io.Reader) error {
+	if _, err := (*Long)(&p.KeepAliveID).ReadFrom(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalPacket writes the fields in order without reflection
+func (p *JoinGame) MarshalPacket(w io.Writer) error {
+	if _, err := Int(p.EntityID).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Boolean(p.IsHardcore).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := UnsignedByte(p.Gamemode).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Byte(p.PreviousGamemode).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := VarInt(p.WorldCount).WriteTo(w); err != nil {
+		return err
+	}
+	for i := range p.WorldNames {
+		if _, err := String(p.WorldNames[i]).WriteTo(w); err != nil {
+			return err
+		}
+	}
+	if err := nbt.NewEncoder(w).Encode(&p.DimensionCodec); err != nil {
+		return err
+	}
+	if err := nbt.NewEncoder(w).Encode(&p.Dimension); err != nil {
+		return err
+	}
+	if _, err := String(p.WorldName).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Long(p.HashedSeed).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := VarInt(p.MaxPlayers).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := VarInt(p.ViewDistance).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Boolean(p.ReducedDebugInfo).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Boolean(p.EnableRespawnScreen).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Boolean(p.IsDebug).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := Boolean(p.IsFlat).WriteTo(w); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnmarshalPacket reads the fields in order without reflection
+func (p *JoinGame) UnmarshalPacket(r io.Reader) error {
+	if _, err := (*Int)(&p.EntityID).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Boolean)(&p.IsHardcore).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*UnsignedByte)(&p.Gamemode).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Byte)(&p.PreviousGamemode).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*VarInt)(&p.WorldCount).ReadFrom(r); err != nil {
+		return err
+	}
+	if err := checkLength("JoinGame.WorldNames", int64(p.WorldCount), MaxFrameSize); err != nil {
+		return err
+	}
+	p.WorldNames = make([]string, 0, preallocated(int64(p.WorldCount)))
+	for i := 0; i < int(p.WorldCount); i++ {
+		var element string
+		if _, err := (*String)(&element).ReadFrom(r); err != nil {
+			return err
+		}
+		p.WorldNames = append(p.WorldNames, element)
+	}
+	if err := nbt.NewDecoder(r).Decode(&p.DimensionCodec); err != nil {
+		return err
+	}
+	if err := nbt.NewDecoder(r).Decode(&p.Dimension); err != nil {
+		return err
+	}
+	if _, err := (*String)(&p.WorldName).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Long)(&p.HashedSeed).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*VarInt)(&p.MaxPlayers).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*VarInt)(&p.ViewDistance).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Boolean)(&p.ReducedDebugInfo).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Boolean)(&p.EnableRespawnScreen).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Boolean)(&p.IsDebug).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*Boolean)(&p.IsFlat).ReadFrom(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalPacket writes the fields in order without reflection
+func (p *Statistic) MarshalPacket(w io.Writer) error {
+	if _, err := VarInt(p.CategoryID).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := VarInt(p.StatisticID).WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := VarInt(p.Value).WriteTo(w); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnmarshalPacket reads the fields in order without reflection
+func (p *Statistic) UnmarshalPacket(r io.Reader) error {
+	if _, err := (*VarInt)(&p.CategoryID).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*VarInt)(&p.StatisticID).ReadFrom(r); err != nil {
+		return err
+	}
+	if _, err := (*VarInt)(&p.Value).ReadFrom(r); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package packet
+
+import (
+	"bytes"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"reflect"
+	"testing"
+)
+
+type generatedPacket interface {
+	MarshalPacket(w io.Writer) error
+	UnmarshalPacket(r io.Reader) error
+}
+
+func TestGeneratedMarshalMatchesReflection(t *testing.T) {
+	joinGame := testJoinGame()
+	tests := []generatedPacket{
+		&Handshake{ProtocolVersion: 754, ServerAddress: "localhost", ServerPort: 25565, NextState: 2},
+		&StatusRequest{},
+		&Ping{Payload: -1},
+		&EncryptionResponse{SharedSecretLength: 2, SharedSecret: []byte{1, 2}, VerifyTokenLength: 1, VerifyToken: []byte{3}},
+		&LoginPluginResponse{MessageID: 4, Successful: true, Data: []byte("data")},
+		&LoginPluginResponse{MessageID: 4},
+		&LoginSuccess{UUID: uuid.New(), Username: "Notch"},
+		&LoginPluginRequest{MessageID: 1, Channel: "minecraft:brand", Data: []byte("vanilla")},
+		&SpawnEntity{EntityID: 1, ObjectUUID: uuid.New(), Type: 2, X: 1.5, Y: -64, Z: 3, Pitch: 64, Yaw: -128, Data: 1, VelocityX: -1},
+		&SpawnLivingEntity{EntityID: 1, EntityUUID: uuid.New(), Type: 3, HeadPitch: 12, VelocityZ: 300},
+		&SpawnPainting{EntityID: 1, Motive: 4, Location: Position{X: -10, Y: 64, Z: 33}, Direction: 2},
+		&AcknowledgePlayerDigging{Location: Position{X: 1, Y: 2, Z: 3}, Block: 9, Status: 2, Successful: true},
+		&Statistics{Count: 2, Statistic: []Statistic{{CategoryID: 1, StatisticID: 2, Value: 3}, {CategoryID: 4, StatisticID: 5, Value: 600}}},
+		&joinGame,
+	}
+	for _, payload := range tests {
+		name := reflect.TypeOf(payload).Elem().Name()
+		reflected, err := Marshal(payload)
+		assert.NoError(t, err, name)
+
+		buf := bytes.NewBuffer(nil)
+		assert.NoError(t, payload.MarshalPacket(buf), name)
+		assert.Equal(t, reflected, buf.Bytes(), name)
+
+		decoded := reflect.New(reflect.TypeOf(payload).Elem()).Interface().(generatedPacket)
+		assert.NoError(t, decoded.UnmarshalPacket(bytes.NewReader(reflected)), name)
+		assert.Equal(t, payload, decoded, name)
+	}
+}
+
+func TestGeneratedUnmarshalRejectsNegativeLengths(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	assert.NoError(t, (&Statistics{Count: -1}).MarshalPacket(buf))
+	var statistics Statistics
+	assert.EqualError(t, statistics.UnmarshalPacket(buf), "negative length -1 for Statistics.Statistic")
+}
@@ -2,7 +2,7 @@ package packet
 
 import (
 	"bytes"
-	"github.com/google/uuid"
+	"github.com/rotisserie/eris"
 	"io"
 	"minecraftServer/nbt"
 	"reflect"
@@ -12,8 +12,16 @@ type (
 	encoder struct {
 		buf *bytes.Buffer
 	}
+
+	// PacketMarshaler is implemented by payloads that can write themselves without reflection, like the generated
+	// ones. Marshal prefers it, for the payload itself and for any struct inside it
+	PacketMarshaler interface {
+		MarshalPacket(w io.Writer) error
+	}
 )
 
+var packetMarshalerType = reflect.TypeOf((*PacketMarshaler)(nil)).Elem()
+
 func Marshal(i interface{}) ([]byte, error) {
 	encoder := &encoder{
 		buf: bytes.NewBuffer(nil),
@@ -45,95 +53,133 @@ func (e *encoder) EncodeReader(i interface{}) (io.Reader, int, error) {
 }
 
 func (e *encoder) EncodeValue(v reflect.Value) error {
+	if marshaler, ok := packetMarshaler(v); ok {
+		return marshaler.MarshalPacket(e.buf)
+	}
 	if v.Kind() == reflect.Ptr {
 		v = v.Elem()
 	}
-	typ := v.Type()
-
-	for i := 0; i < v.NumField(); i++ {
-		field := v.Field(i)
-		tags := makeTags(typ.Field(i).Tag)
+	return e.encodeStruct(v)
+}
 
-		// Handle optional
-		if len(tags.PktOpt) > 0 && !v.FieldByName(tags.PktOpt).Bool() {
+// encodeStruct writes the fields of a struct using reflection
+func (e *encoder) encodeStruct(v reflect.Value) error {
+	typ := v.Type()
+	for _, plan := range planFor(typ).Fields {
+		if plan.Optional >= 0 && !v.Field(plan.Optional).Bool() {
 			continue
 		}
+		if err := e.encodeField(v.Field(plan.Index), plan.Tags); err != nil {
+			return eris.Wrapf(err, "failed to write %v.%v", typ.Name(), typ.Field(plan.Index).Name)
+		}
+	}
+	return nil
+}
 
-		var encoder FieldEncoder
-		switch field.Kind() {
-		case reflect.Bool:
-			encoder = Boolean(field.Bool())
-		case reflect.Int8:
-			encoder = Byte(field.Int())
-		case reflect.Uint8:
-			encoder = UnsignedByte(field.Int())
-		case reflect.Int16:
-			encoder = Short(field.Int())
-		case reflect.Uint16:
-			encoder = UnsignedShort(field.Int())
-		case reflect.Int32:
-			if tags.PktType == "VarInt" {
-				encoder = VarInt(field.Int())
-			} else {
-				encoder = Int(field.Int())
-			}
-		case reflect.Int64:
-			if tags.PktType == "VarLong" {
-				encoder = VarLong(field.Int())
-			} else {
-				encoder = Long(field.Int())
-			}
-		case reflect.Float32:
-			encoder = Float(field.Float())
-		case reflect.Float64:
-			encoder = Double(field.Float())
-		case reflect.String:
-			encoder = String(field.String())
-		case reflect.Slice:
-			sliceType := field.Type().Elem().Kind()
-			if sliceType == reflect.Uint8 {
-				encoder = ByteArray(field.Bytes())
-			} else {
-				l := field.Len()
-
-				for i := 0; i < l; i++ {
-					if err := e.EncodeValue(field.Index(i)); err != nil {
-						return err
-					}
-				}
-				continue
-			}
-		case reflect.Array:
-			l := field.Len()
-			sliceType := field.Type().Elem().Kind()
+// packetMarshaler finds MarshalPacket on a value, or on its address since the generated ones have pointer receivers
+func packetMarshaler(v reflect.Value) (PacketMarshaler, bool) {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil, false
+	}
+	if marshaler, ok := v.Interface().(PacketMarshaler); ok {
+		return marshaler, true
+	}
+	if v.Kind() == reflect.Ptr || !reflect.PtrTo(v.Type()).Implements(packetMarshalerType) {
+		return nil, false
+	}
+	if !v.CanAddr() {
+		copied := reflect.New(v.Type())
+		copied.Elem().Set(v)
+		return copied.Interface().(PacketMarshaler), true
+	}
+	return v.Addr().Interface().(PacketMarshaler), true
+}
 
-			// UUID
-			if l == 16 && sliceType == reflect.Uint8 {
-				u := field.Interface().(uuid.UUID)
-				encoder = UUID(u)
-			}
-		default:
-			if tags.PktType == "nbt" {
-				bs, err := nbt.MarshalValueToNBT(field)
-				if err != nil {
-					return err
-				}
-				if _, err := e.buf.Write(bs); err != nil {
+// encodeField writes a single field, or each element of a slice of them
+func (e *encoder) encodeField(field reflect.Value, tags pktTags) error {
+	var encoder FieldEncoder
+	switch field.Kind() {
+	case reflect.Bool:
+		encoder = Boolean(field.Bool())
+	case reflect.Int8:
+		encoder = Byte(field.Int())
+	case reflect.Uint8:
+		encoder = UnsignedByte(field.Uint())
+	case reflect.Int16:
+		encoder = Short(field.Int())
+	case reflect.Uint16:
+		encoder = UnsignedShort(field.Uint())
+	case reflect.Int32:
+		if tags.PktType == "VarInt" {
+			encoder = VarInt(field.Int())
+		} else {
+			encoder = Int(field.Int())
+		}
+	case reflect.Int64:
+		if tags.PktType == "VarLong" {
+			encoder = VarLong(field.Int())
+		} else {
+			encoder = Long(field.Int())
+		}
+	case reflect.Float32:
+		encoder = Float(field.Float())
+	case reflect.Float64:
+		encoder = Double(field.Float())
+	case reflect.String:
+		encoder = String(field.String())
+	case reflect.Slice:
+		sliceType := field.Type().Elem().Kind()
+		if sliceType == reflect.Uint8 {
+			encoder = ByteArray(field.Bytes())
+		} else {
+			// The length is its own field, so the elements are written back to back
+			for i := 0; i < field.Len(); i++ {
+				if err := e.encodeField(field.Index(i), tags); err != nil {
 					return err
 				}
-				continue
 			}
-			if field.CanInterface() {
-				if _, err := e.Encode(field.Interface()); err != nil {
-					return err
-				}
+			return nil
+		}
+	case reflect.Array:
+		// Byte arrays like UUIDs are written whole
+		if field.Type().Elem().Kind() == reflect.Uint8 {
+			bs := make([]byte, field.Len())
+			reflect.Copy(reflect.ValueOf(bs), field)
+			encoder = ByteArray(bs)
+			break
+		}
+		for i := 0; i < field.Len(); i++ {
+			if err := e.encodeField(field.Index(i), tags); err != nil {
+				return err
 			}
 		}
-		if encoder != nil {
-			_, err := encoder.WriteTo(e.buf)
-			if err != nil {
+		return nil
+	case reflect.Ptr:
+		if field.IsNil() {
+			return eris.Errorf("nil %v", field.Type())
+		}
+		return e.encodeField(field.Elem(), tags)
+	default:
+		if tags.PktType == "nbt" {
+			if err := nbt.NewEncoder(e.buf).EncodeValue(field); err != nil {
 				return err
 			}
+			return nil
+		}
+		if !field.CanInterface() {
+			break
+		}
+		// Types like Position have their own wire format
+		if fieldEncoder, ok := field.Interface().(FieldEncoder); ok {
+			encoder = fieldEncoder
+		} else if _, err := e.Encode(field.Interface()); err != nil {
+			return err
+		}
+	}
+	if encoder != nil {
+		_, err := encoder.WriteTo(e.buf)
+		if err != nil {
+			return err
 		}
 	}
 	return nil
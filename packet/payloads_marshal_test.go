@@ -5,6 +5,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"io"
+	"reflect"
 	"testing"
 )
 
@@ -43,3 +44,96 @@ func TestMarshal(t *testing.T) {
 	}, &newTest))
 	assert.Equal(t, test, newTest)
 }
+
+// countedBytes writes itself as a length prefixed string, to tell it apart from the reflective encoding
+type countedBytes struct {
+	Data []byte
+}
+
+func (c *countedBytes) MarshalPacket(w io.Writer) error {
+	_, err := String(c.Data).WriteTo(w)
+	return err
+}
+
+func (c *countedBytes) UnmarshalPacket(r io.Reader) error {
+	var s String
+	_, err := s.ReadFrom(r)
+	c.Data = []byte(s)
+	return err
+}
+
+func TestMarshalPrefersPacketMarshaler(t *testing.T) {
+	expected := []byte{0x03, 'a', 'b', 'c'}
+
+	bs, err := Marshal(&countedBytes{Data: []byte("abc")})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, bs)
+
+	// Also when only the pointer has the method
+	bs, err = Marshal(countedBytes{Data: []byte("abc")})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, bs)
+
+	type outer struct {
+		Before bool
+		Inner  countedBytes
+	}
+	bs, err = Marshal(outer{Before: true, Inner: countedBytes{Data: []byte("abc")}})
+	assert.NoError(t, err)
+	assert.Equal(t, append([]byte{0x01}, expected...), bs)
+}
+
+func TestPlanFor(t *testing.T) {
+	plan := planFor(reflect.TypeOf(LoginPluginResponse{}))
+	assert.Same(t, plan, planFor(reflect.TypeOf(LoginPluginResponse{})))
+	assert.Equal(t, fieldPlan{Index: 2, Tags: pktTags{PktOpt: "Successful"}, Optional: 1, Length: -1}, plan.Fields[2])
+
+	plan = planFor(reflect.TypeOf(EncryptionResponse{}))
+	assert.Equal(t, 0, plan.Fields[1].Length)
+	assert.Equal(t, -1, plan.Fields[1].Optional)
+
+	type broken struct {
+		Data []byte `pkt_len:"Missing"`
+	}
+	assert.Panics(t, func() { planFor(reflect.TypeOf(broken{})) })
+}
+
+// benchmarkSpawnEntity has a bit of everything fixed width
+var benchmarkSpawnEntity = &SpawnEntity{
+	EntityID:   1234,
+	ObjectUUID: uuid.MustParse("e52d49e2f2244a7380cfcacf6aecbcae"),
+	Type:       2,
+	X:          10.5,
+	Y:          64,
+	Z:          -3.25,
+	Pitch:      12,
+	Yaw:        -64,
+	Data:       1,
+	VelocityX:  100,
+	VelocityY:  -200,
+	VelocityZ:  300,
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	b.Run("Generated", func(b *testing.B) {
+		buf := bytes.NewBuffer(nil)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf.Reset()
+			if err := benchmarkSpawnEntity.MarshalPacket(buf); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Reflection", func(b *testing.B) {
+		e := &encoder{buf: bytes.NewBuffer(nil)}
+		v := reflect.ValueOf(benchmarkSpawnEntity).Elem()
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			e.buf.Reset()
+			if err := e.encodeStruct(v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package packet
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+type (
+	// structPlan is the per-type work of reading pkt tags, worked out once and cached
+	structPlan struct {
+		Fields []fieldPlan
+	}
+
+	fieldPlan struct {
+		Index int
+		Tags  pktTags
+		// Optional is the index of the bool field saying whether this one is sent, -1 when it always is
+		Optional int
+		// Length is the index of the field holding this one's length, -1 when it runs to the end of the packet
+		Length int
+	}
+)
+
+var structPlans sync.Map
+
+// planFor returns the cached plan for a struct type, it panics on tags naming missing fields since they're
+// programming errors
+func planFor(typ reflect.Type) *structPlan {
+	if plan, ok := structPlans.Load(typ); ok {
+		return plan.(*structPlan)
+	}
+
+	plan := &structPlan{Fields: make([]fieldPlan, typ.NumField())}
+	for i := range plan.Fields {
+		tags := makeTags(typ.Field(i).Tag)
+		plan.Fields[i] = fieldPlan{
+			Index:    i,
+			Tags:     tags,
+			Optional: fieldIndex(typ, tags.PktOpt),
+			Length:   fieldIndex(typ, tags.PktLen),
+		}
+	}
+
+	actual, _ := structPlans.LoadOrStore(typ, plan)
+	return actual.(*structPlan)
+}
+
+func fieldIndex(typ reflect.Type, name string) int {
+	if len(name) == 0 {
+		return -1
+	}
+	field, ok := typ.FieldByName(name)
+	if !ok || len(field.Index) != 1 {
+		panic(fmt.Sprintf("packet: %v has no field '%v'", typ, name))
+	}
+	return field.Index[0]
+}
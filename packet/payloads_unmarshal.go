@@ -1,9 +1,10 @@
 package packet
 
 import (
-	"github.com/google/uuid"
+	"bytes"
 	"github.com/rotisserie/eris"
 	"io"
+	"minecraftServer/nbt"
 	"reflect"
 )
 
@@ -27,6 +28,18 @@ type (
 		PktLen  string
 		PktOpt  string
 	}
+
+	// PacketUnmarshaler is implemented by payloads that can read themselves without reflection, like the generated
+	// ones. Unmarshal prefers it, for the payload itself and for any struct inside it
+	PacketUnmarshaler interface {
+		UnmarshalPacket(r io.Reader) error
+	}
+
+	// countingReader keeps track of what a PacketUnmarshaler read, so the rest of the packet's length is still known
+	countingReader struct {
+		reader io.Reader
+		count  int64
+	}
 )
 
 func makeTags(tag reflect.StructTag) pktTags {
@@ -47,6 +60,17 @@ func Unmarshal(pkt Packet, i interface{}) error {
 		reader: reader,
 		bytes:  int64(pkt.DataLength()),
 	}
+	if decoder.bytes == 0 {
+		// Packets made in memory don't know their length, whatever the reader holds is the rest of the packet
+		data, err := io.ReadAll(io.LimitReader(reader, MaxFrameSize+1))
+		if err != nil {
+			return err
+		}
+		if err = checkLength("packet", int64(len(data)), MaxFrameSize); err != nil {
+			return err
+		}
+		decoder.reader, decoder.bytes = bytes.NewReader(data), int64(len(data))
+	}
 	return decoder.Decode(i)
 }
 
@@ -54,193 +78,204 @@ func (d *decoder) Decode(i interface{}) error {
 	return d.DecodeValue(reflect.ValueOf(i))
 }
 
-// TODO: Internal structs
 func (d *decoder) DecodeValue(v reflect.Value) error {
 	if v.Kind() == reflect.Ptr {
 		v = v.Elem()
 	}
-	typ := v.Type()
-
-	for i := 0; i < v.NumField(); i++ {
-		field := v.Field(i)
-		tags := makeTags(typ.Field(i).Tag)
+	if v.CanAddr() && v.Addr().CanInterface() {
+		if unmarshaler, ok := v.Addr().Interface().(PacketUnmarshaler); ok {
+			return d.counted(unmarshaler.UnmarshalPacket)
+		}
+	}
+	return d.decodeStruct(v)
+}
 
-		// Handle optional
-		if len(tags.PktOpt) > 0 && !v.FieldByName(tags.PktOpt).Bool() {
+// decodeStruct reads the fields of a struct using reflection
+func (d *decoder) decodeStruct(v reflect.Value) error {
+	typ := v.Type()
+	for _, plan := range planFor(typ).Fields {
+		if plan.Optional >= 0 && !v.Field(plan.Optional).Bool() {
 			continue
 		}
-
-		bytesRead := int64(0)
-		var err error
-		// TODO: Split these out into separate functions
-		switch field.Kind() {
-		// Boolean
-		case reflect.Bool:
-			var b Boolean
-			bytesRead, err = b.ReadFrom(d.reader)
-			if err != nil {
-				// TODO: Wrap
-				return err
-			}
-			field.SetBool(bool(b))
-		// Byte
-		case reflect.Int8:
-			var b Byte
-			bytesRead, err = b.ReadFrom(d.reader)
-			if err != nil {
-				// TODO: Wrap
-				return err
-			}
-			field.SetInt(int64(b))
-		// UnsignedByte
-		case reflect.Uint8:
-			var b UnsignedByte
-			bytesRead, err = b.ReadFrom(d.reader)
-			if err != nil {
-				// TODO: Wrap
-				return err
-			}
-			field.SetUint(uint64(b))
-		// Short
-		case reflect.Int16:
-			var s Short
-			bytesRead, err = s.ReadFrom(d.reader)
-			if err != nil {
-				// TODO: Wrap
-				return err
-			}
-			field.SetInt(int64(s))
-		// UnsignedShort
-		case reflect.Uint16:
-			var s UnsignedShort
-			bytesRead, err = s.ReadFrom(d.reader)
-			if err != nil {
-				// TODO: Wrap
-				return err
-			}
-			field.SetUint(uint64(s))
-		// Int/VarInt
-		case reflect.Int32:
-			if tags.PktType == "VarInt" {
-				var i VarInt
-				bytesRead, err = i.ReadFrom(d.reader)
-				if err != nil {
-					// TODO: Wrap
-					return err
-				}
-				field.SetInt(int64(i))
-			} else {
-				var i Int
-				bytesRead, err = i.ReadFrom(d.reader)
-				if err != nil {
-					// TODO: Wrap
-					return err
-				}
-				field.SetInt(int64(i))
-			}
-		// Long/VarLong
-		case reflect.Int64:
-			if tags.PktType == "VarLong" {
-				var l VarLong
-				bytesRead, err = l.ReadFrom(d.reader)
-				if err != nil {
-					// TODO: Wrap
-					return err
-				}
-				field.SetInt(int64(l))
-			} else {
-				var l Long
-				bytesRead, err = l.ReadFrom(d.reader)
-				if err != nil {
-					// TODO: Wrap
-					return err
-				}
-				field.SetInt(int64(l))
-			}
-		// Float
-		case reflect.Float32:
-			var f Float
-			bytesRead, err = f.ReadFrom(d.reader)
-			if err != nil {
-				// TODO: Wrap
-				return err
-			}
-			field.SetFloat(float64(f))
-		// Double
-		case reflect.Float64:
-			var do Double
-			bytesRead, err = do.ReadFrom(d.reader)
-			if err != nil {
-				// TODO: Wrap
-				return err
-			}
-			field.SetFloat(float64(do))
-		// String
-		case reflect.String:
-			var s String
-			bytesRead, err = s.ReadFrom(d.reader)
-			if err != nil {
-				// TODO: Wrap
-				return err
-			}
-			field.SetString(string(s))
-		// Arrays
-		case reflect.Slice:
-			bytesRead, err = d.handleSlice(v, field, tags)
-			if err != nil {
-				return err
-			}
-		case reflect.Array:
-			l := field.Len()
-			sliceType := field.Type().Elem().Kind()
-
-			// UUID
-			if l == 16 && sliceType == reflect.Uint8 {
-				var uu UUID
-				_, err = uu.ReadFrom(d.reader)
-				if err != nil {
-					return err
-				}
-				field.Set(reflect.ValueOf(uuid.UUID(uu)))
-			} else {
-				return eris.Errorf("unknown array type %v", sliceType)
-			}
-		default:
-			if field.CanInterface() {
-				if err = d.DecodeValue(field); err != nil {
-					return err
-				}
-			}
-		}
-		d.bytes -= bytesRead
+		if err := d.decodeField(v, v.Field(plan.Index), plan); err != nil {
+			return eris.Wrapf(err, "failed to read %v.%v", typ.Name(), typ.Field(plan.Index).Name)
+		}
 	}
 	return nil
 }
 
-// TODO: Handle slice of structs
-func (d *decoder) handleSlice(v reflect.Value, field reflect.Value, tags pktTags) (bytesRead int64, err error) {
-	sliceType := field.Type().Elem().Kind()
-	if sliceType == reflect.Uint8 {
-		length := d.bytes
-		if len(tags.PktLen) > 0 {
-			lenField := v.FieldByName(tags.PktLen)
-			length = lenField.Int()
+// decodeField reads a single field of v, or each element of a slice or array of them
+func (d *decoder) decodeField(v reflect.Value, field reflect.Value, plan fieldPlan) error {
+	var bytesRead int64
+	var err error
+	switch field.Kind() {
+	case reflect.Bool:
+		var b Boolean
+		bytesRead, err = b.ReadFrom(d.reader)
+		field.SetBool(bool(b))
+	case reflect.Int8:
+		var b Byte
+		bytesRead, err = b.ReadFrom(d.reader)
+		field.SetInt(int64(b))
+	case reflect.Uint8:
+		var b UnsignedByte
+		bytesRead, err = b.ReadFrom(d.reader)
+		field.SetUint(uint64(b))
+	case reflect.Int16:
+		var s Short
+		bytesRead, err = s.ReadFrom(d.reader)
+		field.SetInt(int64(s))
+	case reflect.Uint16:
+		var s UnsignedShort
+		bytesRead, err = s.ReadFrom(d.reader)
+		field.SetUint(uint64(s))
+	case reflect.Int32:
+		if plan.Tags.PktType == "VarInt" {
+			var i VarInt
+			bytesRead, err = i.ReadFrom(d.reader)
+			field.SetInt(int64(i))
+		} else {
+			var i Int
+			bytesRead, err = i.ReadFrom(d.reader)
+			field.SetInt(int64(i))
+		}
+	case reflect.Int64:
+		if plan.Tags.PktType == "VarLong" {
+			var l VarLong
+			bytesRead, err = l.ReadFrom(d.reader)
+			field.SetInt(int64(l))
+		} else {
+			var l Long
+			bytesRead, err = l.ReadFrom(d.reader)
+			field.SetInt(int64(l))
+		}
+	case reflect.Float32:
+		var f Float
+		bytesRead, err = f.ReadFrom(d.reader)
+		field.SetFloat(float64(f))
+	case reflect.Float64:
+		var f Double
+		bytesRead, err = f.ReadFrom(d.reader)
+		field.SetFloat(float64(f))
+	case reflect.String:
+		var s String
+		bytesRead, err = s.ReadFrom(d.reader)
+		field.SetString(string(s))
+	case reflect.Slice:
+		return d.decodeSlice(v, field, plan)
+	case reflect.Array:
+		return d.decodeArray(v, field, plan)
+	case reflect.Ptr:
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		return d.decodeField(v, field.Elem(), plan)
+	default:
+		if !field.CanAddr() || !field.Addr().CanInterface() {
+			return eris.Errorf("can't decode into %v", field.Type())
 		}
+		if plan.Tags.PktType == "nbt" {
+			return d.counted(func(reader io.Reader) error {
+				return nbt.NewDecoder(reader).DecodeValue(field.Addr())
+			})
+		}
+		// Types like Position have their own wire format
+		if fieldDecoder, ok := field.Addr().Interface().(FieldDecoder); ok {
+			bytesRead, err = fieldDecoder.ReadFrom(d.reader)
+			break
+		}
+		if field.Kind() != reflect.Struct {
+			return eris.Errorf("unsupported field type %v", field.Type())
+		}
+		return d.DecodeValue(field)
+	}
+	d.bytes -= bytesRead
+	return err
+}
 
-		var reader io.Reader
-		reader, err = ByteArrayReader(VarInt(length), d.reader)
-		if err != nil {
-			return
+// decodeSlice reads the number of elements held in the pkt_len field, or elements until the packet runs out
+// without one
+func (d *decoder) decodeSlice(v reflect.Value, field reflect.Value, plan fieldPlan) error {
+	length := int64(-1)
+	if plan.Length >= 0 {
+		length = v.Field(plan.Length).Int()
+		if length < 0 {
+			return &LimitError{Length: length}
 		}
-		var ba ByteArray
-		bytesRead, err = ba.ReadFrom(reader)
-		if err != nil {
-			return
+	}
+
+	if field.Type().Elem().Kind() == reflect.Uint8 {
+		if length < 0 {
+			length = d.bytes
+		}
+		if length > d.bytes {
+			return &LimitError{Length: length, Max: d.bytes}
 		}
-		bs := []byte(ba)
-		field.Set(reflect.ValueOf(bs))
-		return
+		bs, err := readBytes(d.reader, length)
+		d.bytes -= int64(len(bs))
+		field.SetBytes(bs)
+		return err
+	}
+
+	if field.Type().Elem().Kind() == reflect.Slice {
+		return eris.Errorf("can't decode nested slice %v", field.Type())
 	}
+	// Each element is read with this field's pkt_type, but has no length or flag of its own
+	elementPlan := fieldPlan{Tags: pktTags{PktType: plan.Tags.PktType}, Optional: -1, Length: -1}
+	// Every element takes at least a byte, so a bogus length can't allocate more than the packet holds
+	capacity := length
+	if capacity < 0 || capacity > d.bytes {
+		capacity = d.bytes
+	}
+	if capacity < 0 {
+		capacity = 0
+	}
+	slice := reflect.MakeSlice(field.Type(), 0, int(capacity))
+	for i := int64(0); length < 0 && d.bytes > 0 || i < length; i++ {
+		slice = reflect.Append(slice, reflect.Zero(field.Type().Elem()))
+		if err := d.decodeField(v, slice.Index(int(i)), elementPlan); err != nil {
+			return eris.Wrapf(err, "failed to read element %v", i)
+		}
+	}
+	field.Set(slice)
+	return nil
+}
 
-	return 0, eris.Errorf("unknown slice type %v", sliceType)
+// decodeArray reads a fixed number of elements, byte arrays like UUIDs are read whole
+func (d *decoder) decodeArray(v reflect.Value, field reflect.Value, plan fieldPlan) error {
+	if field.Type().Elem().Kind() == reflect.Uint8 {
+		n, err := io.ReadFull(d.reader, field.Slice(0, field.Len()).Bytes())
+		d.bytes -= int64(n)
+		return err
+	}
+	elementPlan := fieldPlan{Tags: pktTags{PktType: plan.Tags.PktType}, Optional: -1, Length: -1}
+	for i := 0; i < field.Len(); i++ {
+		if err := d.decodeField(v, field.Index(i), elementPlan); err != nil {
+			return eris.Wrapf(err, "failed to read element %v", i)
+		}
+	}
+	return nil
+}
+
+// counted runs read against the decoder's reader, taking what it read off the bytes left
+func (d *decoder) counted(read func(reader io.Reader) error) error {
+	counter := &countingReader{reader: d.reader}
+	err := read(counter)
+	d.bytes -= counter.count
+	return err
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.count += int64(n)
+	return n, err
+}
+
+// ReadByte keeps single byte reads like VarInts cheap when the underlying reader supports them
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := readByte(c.reader)
+	if err == nil {
+		c.count++
+	}
+	return b, err
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"github.com/stretchr/testify/assert"
 	"io"
+	"reflect"
 	"testing"
 )
 
@@ -30,3 +31,157 @@ func TestUnmarshal(t *testing.T) {
 		OptValue: false,
 	}, te)
 }
+
+func TestUnmarshalPrefersPacketUnmarshaler(t *testing.T) {
+	type outer struct {
+		Inner countedBytes
+		// Rest needs the length left after Inner
+		Rest []byte
+	}
+	buf := bytes.NewBuffer(nil)
+	assert.NoError(t, WriteFields(buf, String("abc"), ByteArray{1, 2}))
+	var decoded outer
+	assert.NoError(t, Unmarshal(&UncompressedPacket{
+		readCloser: io.NopCloser(buf),
+		dataLength: VarInt(buf.Len()),
+	}, &decoded))
+	assert.Equal(t, outer{Inner: countedBytes{Data: []byte("abc")}, Rest: []byte{1, 2}}, decoded)
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+	data, err := Marshal(benchmarkSpawnEntity)
+	if err != nil {
+		b.Fatal(err)
+	}
+	reader := bytes.NewReader(data)
+	b.Run("Generated", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			reader.Reset(data)
+			var decoded SpawnEntity
+			if err := decoded.UnmarshalPacket(reader); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Reflection", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			reader.Reset(data)
+			var decoded SpawnEntity
+			d := &decoder{reader: reader, bytes: int64(len(data))}
+			if err := d.decodeStruct(reflect.ValueOf(&decoded).Elem()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// unmarshalBytes decodes data like it had arrived as a packet
+func unmarshalBytes(data []byte, i interface{}) error {
+	return Unmarshal(&UncompressedPacket{
+		readCloser: io.NopCloser(bytes.NewReader(data)),
+		dataLength: VarInt(len(data)),
+	}, i)
+}
+
+func TestUnmarshalNested(t *testing.T) {
+	type point struct {
+		X, Y int16
+	}
+	type entry struct {
+		Name  string
+		Point point
+	}
+	type test struct {
+		Single     point
+		EntryCount int32   `pkt_type:"VarInt"`
+		Entries    []entry `pkt_len:"EntryCount"`
+		IDCount    int32   `pkt_type:"VarInt"`
+		IDs        []int32 `pkt_type:"VarInt" pkt_len:"IDCount"`
+		Fixed      [3]int16
+		Bytes      [4]byte
+		Pointer    *int32 `pkt_type:"VarInt"`
+		Struct     *point
+		HasMissing bool
+		Missing    *point `pkt_opt:"HasMissing"`
+		Rest       []point
+	}
+	pointer := int32(300)
+	value := test{
+		Single:     point{X: 1, Y: -1},
+		EntryCount: 2,
+		Entries:    []entry{{Name: "a", Point: point{X: 2}}, {Name: "b", Point: point{Y: 3}}},
+		IDCount:    3,
+		IDs:        []int32{1, 128, -1},
+		Fixed:      [3]int16{4, 5, 6},
+		Bytes:      [4]byte{7, 8, 9, 10},
+		Pointer:    &pointer,
+		Struct:     &point{X: 11, Y: 12},
+		Rest:       []point{{X: 13, Y: 14}, {X: 15, Y: 16}},
+	}
+
+	bs, err := Marshal(&value)
+	assert.NoError(t, err)
+	var decoded test
+	assert.NoError(t, unmarshalBytes(bs, &decoded))
+	assert.Equal(t, value, decoded)
+}
+
+func TestUnmarshalPacketWithData(t *testing.T) {
+	// Packets made in memory have no recorded length for the byte slices to be checked against
+	type test struct {
+		Count int32  `pkt_type:"VarInt"`
+		Data  []byte `pkt_len:"Count"`
+		Rest  []int16
+	}
+	value := test{Count: 3, Data: []byte{1, 2, 3}, Rest: []int16{4, 5}}
+	pkt, err := MakePacketWithData(0x00, &value)
+	assert.NoError(t, err)
+	var decoded test
+	assert.NoError(t, Unmarshal(pkt, &decoded))
+	assert.Equal(t, value, decoded)
+
+	pkt, err = MakePacketWithData(0x00, &test{Count: 4, Data: []byte{1, 2, 3}})
+	assert.NoError(t, err)
+	assertLimitError(t, Unmarshal(pkt, &decoded), "")
+}
+
+func TestUnmarshalRejectsNegativeLengths(t *testing.T) {
+	type test struct {
+		Count int32    `pkt_type:"VarInt"`
+		Names []string `pkt_len:"Count"`
+	}
+	bs, err := Marshal(&test{Count: -1})
+	assert.NoError(t, err)
+	var decoded test
+	assert.EqualError(t, unmarshalBytes(bs, &decoded), "failed to read test.Names: negative length -1")
+}
+
+// TestReflectionRoundTrip runs the generated packets with nested data through the reflective path, which has to
+// agree with their generated methods
+func TestReflectionRoundTrip(t *testing.T) {
+	joinGame := testJoinGame()
+	tests := []struct {
+		payload generatedPacket
+		decoded interface{}
+	}{
+		{
+			payload: &Statistics{Count: 2, Statistic: []Statistic{{CategoryID: 1, StatisticID: 2, Value: 3}, {CategoryID: 8, StatisticID: 9, Value: 1000}}},
+			decoded: &Statistics{},
+		},
+		{payload: &joinGame, decoded: &JoinGame{}},
+	}
+	for _, test := range tests {
+		e := &encoder{buf: bytes.NewBuffer(nil)}
+		assert.NoError(t, e.encodeStruct(reflect.ValueOf(test.payload).Elem()))
+		generated := bytes.NewBuffer(nil)
+		assert.NoError(t, test.payload.MarshalPacket(generated))
+		assert.Equal(t, generated.Bytes(), e.buf.Bytes())
+
+		d := &decoder{reader: bytes.NewReader(e.buf.Bytes()), bytes: int64(e.buf.Len())}
+		assert.NoError(t, d.decodeStruct(reflect.ValueOf(test.decoded).Elem()))
+		assert.Equal(t, int64(0), d.bytes)
+		assert.Equal(t, test.payload, test.decoded)
+	}
+}
@@ -0,0 +1,268 @@
+package packet
+
+import (
+	"fmt"
+	"github.com/rotisserie/eris"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// closeTimeout is how long Close waits for queued packets, like a final Disconnect, to reach a client that isn't
+// reading
+const closeTimeout = 5 * time.Second
+
+// flushSize is how much the writer frames before flushing, even with more packets queued
+const flushSize = 32 << 10
+
+// ErrDropped is returned by SendDroppable for a packet DropWhenFull discarded
+var ErrDropped = eris.New("packet dropped from a full queue")
+
+type (
+	// QueuePolicy decides what happens to a packet sent while the connection's queue is full
+	QueuePolicy byte
+
+	// QueueStats describes a connection's outbound queue
+	QueueStats struct {
+		// Written and Dropped count packets since the writer started
+		Written  uint64
+		Dropped  uint64
+		Depth    int
+		Capacity int
+	}
+
+	// QueueFullError is returned when a packet is sent to a full queue with KickWhenFull, the connection is closed
+	QueueFullError struct {
+		Capacity int
+	}
+
+	// sendQueue feeds a connection's writer goroutine, which batches whatever is queued into one flush
+	sendQueue struct {
+		// Accessed atomically, first for alignment
+		written uint64
+		dropped uint64
+
+		items  chan queued
+		policy QueuePolicy
+		// timeout bounds each flush, so a client that stops reading can't park the writer forever
+		timeout time.Duration
+		// stop is closed by Close to have the writer drain the queue and exit, done once it has
+		stop     chan struct{}
+		stopOnce sync.Once
+		done     chan struct{}
+		err      error
+	}
+
+	// queued is a packet to write, or a request to flush everything before it when flushed is set
+	queued struct {
+		pkt       Packet
+		droppable bool
+		flushed   chan struct{}
+	}
+)
+
+const (
+	// BlockWhenFull makes senders wait for room
+	BlockWhenFull QueuePolicy = iota
+	// DropWhenFull discards packets sent with SendDroppable and makes the rest wait
+	DropWhenFull
+	// KickWhenFull closes the connection, a client that far behind isn't coming back
+	KickWhenFull
+)
+
+var queuePolicyNames = []string{"block", "drop", "kick"}
+
+func ParseQueuePolicy(name string) (QueuePolicy, error) {
+	for i, policyName := range queuePolicyNames {
+		if strings.EqualFold(name, policyName) {
+			return QueuePolicy(i), nil
+		}
+	}
+	return BlockWhenFull, eris.Errorf("unknown queue policy '%v', expected one of %v", name, strings.Join(queuePolicyNames, ", "))
+}
+
+func (p QueuePolicy) String() string {
+	return queuePolicyNames[p]
+}
+
+// StartWriter moves writing onto a goroutine fed by a queue of size packets, so sending never waits on the client
+// unless policy says to. A flush that takes longer than timeout closes the connection, zero waits forever. It has to
+// be called before the connection is shared
+func (c *Conn) StartWriter(size int, policy QueuePolicy, timeout time.Duration) error {
+	if c.queue != nil {
+		return eris.New("writer already started")
+	}
+	if size < 1 {
+		return eris.Errorf("invalid queue size %v", size)
+	}
+	c.queue = &sendQueue{
+		items:   make(chan queued, size),
+		policy:  policy,
+		timeout: timeout,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go c.writeQueued()
+	return nil
+}
+
+// SendDroppable is Send for packets the client can do without, which DropWhenFull discards instead of waiting,
+// returning ErrDropped
+func (c *Conn) SendDroppable(state State, payload interface{}) error {
+	pkt, err := c.registry.Encode(state, payload)
+	if err != nil {
+		return err
+	}
+	if c.queue == nil {
+		return c.writeNow(pkt)
+	}
+	return c.enqueue(queued{pkt: pkt, droppable: true})
+}
+
+// Flush waits until every packet sent so far has been written to the connection
+func (c *Conn) Flush() error {
+	if c.queue == nil {
+		return nil
+	}
+	flushed := make(chan struct{})
+	select {
+	case c.queue.items <- queued{flushed: flushed}:
+	case <-c.queue.done:
+		return c.queue.err
+	}
+	select {
+	case <-flushed:
+		return nil
+	case <-c.queue.done:
+		return c.queue.err
+	}
+}
+
+// QueueStats reports on the outbound queue, which is always empty before StartWriter
+func (c *Conn) QueueStats() QueueStats {
+	q := c.queue
+	if q == nil {
+		return QueueStats{}
+	}
+	return QueueStats{
+		Written:  atomic.LoadUint64(&q.written),
+		Dropped:  atomic.LoadUint64(&q.dropped),
+		Depth:    len(q.items),
+		Capacity: cap(q.items),
+	}
+}
+
+func (c *Conn) enqueue(item queued) error {
+	q := c.queue
+	select {
+	case <-q.done:
+		return q.err
+	case <-q.stop:
+		return eris.Wrap(net.ErrClosed, "connection is closing")
+	default:
+	}
+	select {
+	case q.items <- item:
+		return nil
+	default:
+	}
+
+	switch {
+	case q.policy == KickWhenFull:
+		_ = c.conn.Close()
+		return &QueueFullError{Capacity: cap(q.items)}
+	case q.policy == DropWhenFull && item.droppable:
+		atomic.AddUint64(&q.dropped, 1)
+		return ErrDropped
+	}
+	select {
+	case q.items <- item:
+		return nil
+	case <-q.done:
+		return q.err
+	}
+}
+
+// writeQueued is the writer goroutine, it flushes whenever it catches up with the queue so packets sent together go
+// out together
+func (c *Conn) writeQueued() {
+	q := c.queue
+	defer close(q.done)
+	for {
+		select {
+		case item := <-q.items:
+			if err := c.writeItem(item); err != nil {
+				q.err = err
+				// Unblocks the reader, the connection is no use without writes
+				_ = c.conn.Close()
+				return
+			}
+		case <-q.stop:
+			for {
+				select {
+				case item := <-q.items:
+					if err := c.writeItem(item); err != nil {
+						q.err = err
+						return
+					}
+				default:
+					q.err = eris.Wrap(net.ErrClosed, "connection closed")
+					if err := c.flush(); err != nil {
+						q.err = err
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+func (c *Conn) writeItem(item queued) error {
+	if item.flushed != nil {
+		// Left open on failure, Flush reports the writer's error once it's done
+		if err := c.flushQueued(); err != nil {
+			return err
+		}
+		close(item.flushed)
+		return nil
+	}
+	pending, err := c.writeFramed(item.pkt)
+	if err != nil {
+		return err
+	}
+	atomic.AddUint64(&c.queue.written, 1)
+	if len(c.queue.items) == 0 || pending >= flushSize {
+		return c.flushQueued()
+	}
+	return nil
+}
+
+// flushQueued is flush bounded by the writer's timeout, unless closeQueue has already set a deadline of its own
+func (c *Conn) flushQueued() error {
+	q := c.queue
+	if q == nil || q.timeout <= 0 {
+		return c.flush()
+	}
+	select {
+	case <-q.stop:
+	default:
+		if err := c.conn.SetWriteDeadline(time.Now().Add(q.timeout)); err != nil {
+			return err
+		}
+	}
+	return c.flush()
+}
+
+// closeQueue lets the writer finish what's queued before the connection is closed
+func (c *Conn) closeQueue() {
+	q := c.queue
+	q.stopOnce.Do(func() { close(q.stop) })
+	_ = c.conn.SetWriteDeadline(time.Now().Add(closeTimeout))
+	<-q.done
+}
+
+func (e *QueueFullError) Error() string {
+	return fmt.Sprintf("outbound queue of %v packets is full", e.Capacity)
+}
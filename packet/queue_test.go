@@ -0,0 +1,180 @@
+package packet
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+	"time"
+)
+
+type queuedMessage struct {
+	Message string
+}
+
+func readMessage(t *testing.T, conn *Conn) string {
+	pkt, err := conn.ReadPacket()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	var message queuedMessage
+	assert.NoError(t, Unmarshal(pkt, &message))
+	return message.Message
+}
+
+func messagePacket(t *testing.T, message string) Packet {
+	pkt, err := MakePacketWithData(0x07, &queuedMessage{Message: message})
+	assert.NoError(t, err)
+	return pkt
+}
+
+// stalledWriter starts a writer on a pipe nobody reads from, then waits for it to take the first packet off the
+// queue and get stuck writing it
+func stalledWriter(t *testing.T, policy QueuePolicy) (*Conn, *Conn) {
+	server, client := net.Pipe()
+	t.Cleanup(func() { _ = client.Close() })
+	serverConn, clientConn := NewConn(server), NewConn(client)
+	assert.NoError(t, serverConn.StartWriter(1, policy, 0))
+	assert.NoError(t, serverConn.WritePacket(messagePacket(t, "stuck")))
+	assert.Eventually(t, func() bool { return serverConn.QueueStats().Depth == 0 }, time.Second, time.Millisecond)
+	assert.NoError(t, serverConn.WritePacket(messagePacket(t, "queued")))
+	return serverConn, clientConn
+}
+
+func TestParseQueuePolicy(t *testing.T) {
+	for _, policy := range []QueuePolicy{BlockWhenFull, DropWhenFull, KickWhenFull} {
+		parsed, err := ParseQueuePolicy(policy.String())
+		assert.NoError(t, err)
+		assert.Equal(t, policy, parsed)
+	}
+	_, err := ParseQueuePolicy("wait")
+	assert.Error(t, err)
+}
+
+func TestWriterSendsInOrder(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	serverConn, clientConn := NewConn(server), NewConn(client)
+	assert.NoError(t, serverConn.StartWriter(4, BlockWhenFull, 0))
+	assert.Error(t, serverConn.StartWriter(4, BlockWhenFull, 0))
+
+	messages := []string{"one", "two", "three", "four", "five", "six"}
+	go func() {
+		for _, message := range messages {
+			assert.NoError(t, serverConn.WritePacket(messagePacket(t, message)))
+		}
+		// Close waits for everything queued to be written
+		assert.NoError(t, serverConn.Close())
+	}()
+	for _, message := range messages {
+		assert.Equal(t, message, readMessage(t, clientConn))
+	}
+	_, err := clientConn.ReadPacket()
+	assert.Error(t, err)
+	assert.Equal(t, uint64(len(messages)), serverConn.QueueStats().Written)
+}
+
+func TestWriterSwitchesFraming(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	serverConn, clientConn := NewConn(server), NewConn(client)
+	assert.NoError(t, serverConn.StartWriter(4, BlockWhenFull, 0))
+
+	messages := []string{"uncompressed", "small", string(bytes.Repeat([]byte("large"), 100))}
+	go func() {
+		for i, message := range messages {
+			// The packets before have to go out with the old framing, even if they're still queued
+			if i == 1 {
+				serverConn.SetCompression(64)
+			}
+			assert.NoError(t, serverConn.WritePacket(messagePacket(t, message)))
+		}
+	}()
+	for i, message := range messages {
+		if i == 1 {
+			clientConn.SetCompression(64)
+		}
+		assert.Equal(t, message, readMessage(t, clientConn))
+	}
+}
+
+func TestWriterFullQueue(t *testing.T) {
+	t.Run("block", func(t *testing.T) {
+		serverConn, clientConn := stalledWriter(t, BlockWhenFull)
+		sent := make(chan error)
+		go func() {
+			sent <- serverConn.SendDroppable(Status, &Pong{Payload: 1})
+		}()
+		select {
+		case <-sent:
+			t.Fatal("send didn't wait for room in the queue")
+		case <-time.After(10 * time.Millisecond):
+		}
+		assert.Equal(t, "stuck", readMessage(t, clientConn))
+		assert.NoError(t, <-sent)
+		assert.Equal(t, "queued", readMessage(t, clientConn))
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		serverConn, clientConn := stalledWriter(t, DropWhenFull)
+		assert.ErrorIs(t, serverConn.SendDroppable(Status, &Pong{Payload: 1}), ErrDropped)
+		assert.Equal(t, uint64(1), serverConn.QueueStats().Dropped)
+		assert.Equal(t, 1, serverConn.QueueStats().Depth)
+
+		assert.Equal(t, "stuck", readMessage(t, clientConn))
+		assert.Equal(t, "queued", readMessage(t, clientConn))
+		assert.NoError(t, serverConn.Flush())
+		assert.Equal(t, uint64(2), serverConn.QueueStats().Written)
+	})
+
+	t.Run("kick", func(t *testing.T) {
+		serverConn, _ := stalledWriter(t, KickWhenFull)
+		err := serverConn.SendDroppable(Status, &Pong{Payload: 1})
+		var full *QueueFullError
+		assert.True(t, errors.As(err, &full))
+		assert.Equal(t, 1, full.Capacity)
+		// The writer gives up once the connection is closed under it
+		assert.Error(t, serverConn.Flush())
+		assert.Error(t, serverConn.WritePacket(messagePacket(t, "late")))
+	})
+}
+
+func TestReadingDoesNotWaitOnWriter(t *testing.T) {
+	serverConn, clientConn := stalledWriter(t, BlockWhenFull)
+	go func() {
+		assert.NoError(t, clientConn.WritePacket(messagePacket(t, "hello")))
+	}()
+	read := make(chan string)
+	go func() {
+		read <- readMessage(t, serverConn)
+	}()
+	select {
+	case message := <-read:
+		assert.Equal(t, "hello", message)
+	case <-time.After(time.Second):
+		t.Fatal("read waited on the stalled writer")
+	}
+}
+
+func TestWriterTimesOut(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	serverConn := NewConn(server)
+	assert.NoError(t, serverConn.StartWriter(4, BlockWhenFull, 10*time.Millisecond))
+	assert.NoError(t, serverConn.WritePacket(messagePacket(t, "unread")))
+	flushed := make(chan error)
+	go func() {
+		flushed <- serverConn.Flush()
+	}()
+	select {
+	case err := <-flushed:
+		assert.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("writer waited on a client that never reads")
+	}
+	// The connection is closed along with the writer
+	_, err := serverConn.ReadPacket()
+	assert.Error(t, err)
+}
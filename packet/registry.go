@@ -0,0 +1,187 @@
+package packet
+
+import (
+	"fmt"
+	"reflect"
+)
+
+type (
+	// Registry maps the packet IDs of each state and direction to their payload structs, for one protocol version
+	Registry struct {
+		byID   map[registryKey]registryType
+		byType map[typeKey]registryEntry
+	}
+
+	// Layout translates a payload to and from the struct a protocol version actually sends, for packets whose
+	// fields changed between versions. The converters take and return struct values, not pointers
+	Layout struct {
+		// Wire is the struct that's marshalled, e.g. joinGame17{}
+		Wire     interface{}
+		ToWire   func(payload interface{}) interface{}
+		FromWire func(wire interface{}) interface{}
+	}
+
+	registryKey struct {
+		State     State
+		Direction Direction
+		ID        VarInt
+	}
+
+	typeKey struct {
+		State State
+		Type  reflect.Type
+	}
+
+	registryType struct {
+		Type   reflect.Type
+		Layout *Layout
+	}
+
+	registryEntry struct {
+		Direction Direction
+		ID        VarInt
+		Layout    *Layout
+	}
+
+	// UnknownPacketError is returned for IDs or payloads the registry has nothing for
+	UnknownPacketError struct {
+		State     State
+		Direction Direction
+		ID        VarInt
+		// Type is set when encoding an unregistered payload
+		Type reflect.Type
+	}
+
+	// DecodeError is returned for a registered packet whose payload couldn't be unmarshalled
+	DecodeError struct {
+		Type string
+		Err  error
+	}
+)
+
+//go:generate go run ../cmd/packetgen -spec protocol.json -out packets_gen.go
+
+// DefaultRegistry holds the packets of protocol 754 (1.16.4 and 1.16.5), it's used until the handshake says
+// otherwise
+var DefaultRegistry = NewRegistry()
+
+func init() {
+	registerCommon(DefaultRegistry)
+	registerPlay(DefaultRegistry)
+}
+
+// registerCommon adds the Handshaking, Status and Login packets, which haven't changed across the supported versions
+func registerCommon(r *Registry) {
+	registerHandshaking(r)
+	registerStatus(r)
+	registerLogin(r)
+}
+
+func (e *UnknownPacketError) Error() string {
+	if e.Type != nil {
+		return fmt.Sprintf("no %v packet registered for '%v'", e.State, e.Type)
+	}
+	return fmt.Sprintf("unknown %v %v packet 0x%02x", e.Direction, e.State, int32(e.ID))
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("failed to unmarshal %v: %v", e.Type, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		byID:   map[registryKey]registryType{},
+		byType: map[typeKey]registryEntry{},
+	}
+}
+
+// Register adds a payload struct under an ID, it panics on duplicates since they're programming errors.
+// A payload type can only be registered once per state
+func (r *Registry) Register(state State, direction Direction, id VarInt, payload interface{}) {
+	r.register(state, direction, id, payload, nil)
+}
+
+// RegisterLayout adds a payload struct under an ID like Register, but marshals it as layout.Wire
+func (r *Registry) RegisterLayout(state State, direction Direction, id VarInt, payload interface{}, layout Layout) {
+	r.register(state, direction, id, payload, &layout)
+}
+
+func (r *Registry) register(state State, direction Direction, id VarInt, payload interface{}, layout *Layout) {
+	typ := payloadType(payload)
+	key := registryKey{State: state, Direction: direction, ID: id}
+	if existing, ok := r.byID[key]; ok {
+		panic(fmt.Sprintf("packet: %v %v 0x%02x is already registered to '%v'", direction, state, int32(id), existing.Type))
+	}
+	if _, ok := r.byType[typeKey{State: state, Type: typ}]; ok {
+		panic(fmt.Sprintf("packet: '%v' is already registered in %v", typ, state))
+	}
+	r.byID[key] = registryType{Type: typ, Layout: layout}
+	r.byType[typeKey{State: state, Type: typ}] = registryEntry{Direction: direction, ID: id, Layout: layout}
+}
+
+// Decode unmarshals a packet into a new payload struct, returned as a pointer, e.g. *LoginStart
+func (r *Registry) Decode(state State, direction Direction, pkt Packet) (interface{}, error) {
+	registered, ok := r.byID[registryKey{State: state, Direction: direction, ID: pkt.ID()}]
+	if !ok {
+		return nil, &UnknownPacketError{State: state, Direction: direction, ID: pkt.ID()}
+	}
+	typ := registered.Type
+	if registered.Layout != nil {
+		typ = payloadType(registered.Layout.Wire)
+	}
+	payload := reflect.New(typ)
+	if err := Unmarshal(pkt, payload.Interface()); err != nil {
+		return nil, &DecodeError{Type: typ.Name(), Err: err}
+	}
+	if registered.Layout == nil {
+		return payload.Interface(), nil
+	}
+	translated := reflect.New(registered.Type)
+	translated.Elem().Set(reflect.ValueOf(registered.Layout.FromWire(payload.Elem().Interface())))
+	return translated.Interface(), nil
+}
+
+// Encode marshals a payload struct, or a pointer to one, into a packet with its registered ID
+func (r *Registry) Encode(state State, payload interface{}) (Packet, error) {
+	typ := payloadType(payload)
+	entry, ok := r.byType[typeKey{State: state, Type: typ}]
+	if !ok {
+		return nil, &UnknownPacketError{State: state, Type: typ}
+	}
+	if entry.Layout != nil {
+		payload = entry.Layout.ToWire(reflect.Indirect(reflect.ValueOf(payload)).Interface())
+	}
+	return MakePacketWithData(int32(entry.ID), payload)
+}
+
+// ID looks up the ID and direction a payload is registered under
+func (r *Registry) ID(state State, payload interface{}) (VarInt, Direction, error) {
+	typ := payloadType(payload)
+	entry, ok := r.byType[typeKey{State: state, Type: typ}]
+	if !ok {
+		return 0, 0, &UnknownPacketError{State: state, Type: typ}
+	}
+	return entry.ID, entry.Direction, nil
+}
+
+// Decode uses DefaultRegistry to unmarshal a packet into its payload struct
+func Decode(state State, direction Direction, pkt Packet) (interface{}, error) {
+	return DefaultRegistry.Decode(state, direction, pkt)
+}
+
+// Encode uses DefaultRegistry to marshal a payload into a packet
+func Encode(state State, payload interface{}) (Packet, error) {
+	return DefaultRegistry.Encode(state, payload)
+}
+
+func payloadType(payload interface{}) reflect.Type {
+	typ := reflect.TypeOf(payload)
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
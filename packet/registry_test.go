@@ -0,0 +1,84 @@
+package packet
+
+import (
+	"bytes"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// readBack writes a packet out and reads it in again, like it would arrive from a connection
+func readBack(t *testing.T, pkt Packet) Packet {
+	buf := bytes.NewBuffer(nil)
+	_, err := WriteTo(pkt, buf)
+	assert.NoError(t, err)
+	read, err := MakeUncompressedPacket(buf)
+	assert.NoError(t, err)
+	return read
+}
+
+func TestRegistryRoundTrip(t *testing.T) {
+	joinGame := testJoinGame()
+	tests := []struct {
+		state   State
+		payload interface{}
+		id      VarInt
+	}{
+		{state: Status, payload: &StatusResponse{JSONResponse: "{}"}, id: 0x00},
+		{state: Login, payload: &LoginSuccess{UUID: uuid.New(), Username: "Notch"}, id: 0x02},
+		{state: Login, payload: &Disconnect{Reason: `{"text":"bye"}`}, id: 0x00},
+		{state: Play, payload: &Disconnect{Reason: `{"text":"bye"}`}, id: 0x19},
+		{state: Play, payload: &KeepAlive{KeepAliveID: -42}, id: 0x1F},
+		// Decoded by its generated UnmarshalPacket, reflection can't read the NBT
+		{state: Play, payload: &joinGame, id: 0x24},
+	}
+	for _, test := range tests {
+		pkt, err := Encode(test.state, test.payload)
+		assert.NoError(t, err)
+		assert.Equal(t, test.id, pkt.ID())
+
+		decoded, err := Decode(test.state, Clientbound, readBack(t, pkt))
+		assert.NoError(t, err)
+		assert.Equal(t, test.payload, decoded)
+	}
+}
+
+func TestRegistryDecodesServerbound(t *testing.T) {
+	pkt, err := MakePacketWithData(0x00, &Handshake{ProtocolVersion: 754, ServerAddress: "localhost", ServerPort: 25565, NextState: 2})
+	assert.NoError(t, err)
+	pkt = readBack(t, pkt)
+
+	payload, err := Decode(Handshaking, Serverbound, pkt)
+	assert.NoError(t, err)
+	assert.Equal(t, &Handshake{ProtocolVersion: 754, ServerAddress: "localhost", ServerPort: 25565, NextState: 2}, payload)
+}
+
+func TestRegistryUnknown(t *testing.T) {
+	_, err := Decode(Login, Serverbound, MakePacket(0x7f, nil))
+	var unknown *UnknownPacketError
+	assert.True(t, errors.As(err, &unknown))
+	assert.Equal(t, VarInt(0x7f), unknown.ID)
+	assert.Equal(t, "unknown Serverbound Login packet 0x7f", err.Error())
+
+	// SetCompression only exists in Login
+	_, err = Encode(Play, &SetCompression{})
+	assert.True(t, errors.As(err, &unknown))
+
+	_, err = Encode(Login, struct{ Unregistered int32 }{})
+	assert.Error(t, err)
+}
+
+func TestRegistryPanicsOnDuplicates(t *testing.T) {
+	r := NewRegistry()
+	r.Register(Login, Serverbound, 0x00, LoginStart{})
+	assert.Panics(t, func() {
+		r.Register(Login, Serverbound, 0x00, Ping{})
+	})
+	assert.Panics(t, func() {
+		r.Register(Login, Serverbound, 0x05, &LoginStart{})
+	})
+	assert.NotPanics(t, func() {
+		r.Register(Status, Serverbound, 0x00, LoginStart{})
+	})
+}
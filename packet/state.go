@@ -0,0 +1,64 @@
+package packet
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type (
+	// State is the connection state, which decides what each packet ID means
+	State byte
+
+	// Direction is which way a packet travels
+	Direction byte
+
+	// InvalidNextStateError is returned for a Handshake asking for a state other than Status or Login
+	InvalidNextStateError struct {
+		NextState int32
+	}
+)
+
+const (
+	Handshaking State = iota
+	Status
+	Login
+	Play
+)
+
+const (
+	Serverbound Direction = iota
+	Clientbound
+)
+
+var stateNames = []string{"Handshaking", "Status", "Login", "Play"}
+
+// StateFromVarInt converts the next state of a Handshake, which can only be Status or Login
+func StateFromVarInt(varInt VarInt) (State, error) {
+	state := State(varInt)
+	if varInt != VarInt(Status) && varInt != VarInt(Login) {
+		return Handshaking, &InvalidNextStateError{NextState: int32(varInt)}
+	}
+	return state, nil
+}
+
+func (s State) String() string {
+	if int(s) < len(stateNames) {
+		return stateNames[s]
+	}
+	return fmt.Sprintf("State(%v)", byte(s))
+}
+
+func (s State) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+func (d Direction) String() string {
+	if d == Serverbound {
+		return "Serverbound"
+	}
+	return "Clientbound"
+}
+
+func (e *InvalidNextStateError) Error() string {
+	return fmt.Sprintf("invalid next state %v", e.NextState)
+}
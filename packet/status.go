@@ -0,0 +1,138 @@
+package packet
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/rotisserie/eris"
+	"image/png"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// https://wiki.vg/Server_List_Ping
+
+const (
+	// LegacyPingID is the first byte a pre-Netty client sends instead of a packet length
+	LegacyPingID = 0xFE
+	// legacyKickID starts the reply to a legacy ping
+	legacyKickID = 0xFF
+	// FaviconSize is the width and height the client expects a favicon to be
+	FaviconSize = 64
+)
+
+type (
+	// ServerStatus is the JSON body of StatusResponse
+	ServerStatus struct {
+		Version     StatusVersion `json:"version"`
+		Players     StatusPlayers `json:"players"`
+		Description ChatComponent `json:"description"`
+		// Favicon is a data URI of a 64x64 PNG
+		Favicon string `json:"favicon,omitempty"`
+	}
+
+	StatusVersion struct {
+		Name     string `json:"name"`
+		Protocol int32  `json:"protocol"`
+	}
+
+	StatusPlayers struct {
+		Max    int                  `json:"max"`
+		Online int                  `json:"online"`
+		Sample []StatusPlayerSample `json:"sample,omitempty"`
+	}
+
+	StatusPlayerSample struct {
+		Name string `json:"name"`
+		Id   string `json:"id"`
+	}
+)
+
+// StatusResponse wraps the status as the packet's JSON
+func (s *ServerStatus) StatusResponse() (*StatusResponse, error) {
+	bs, err := json.Marshal(s)
+	if err != nil {
+		return nil, eris.Wrap(err, "failed to marshal server status")
+	}
+	return &StatusResponse{JSONResponse: string(bs)}, nil
+}
+
+// EncodeFavicon checks data is a 64x64 PNG and turns it into the data URI used by ServerStatus.Favicon
+func EncodeFavicon(data []byte) (string, error) {
+	config, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", eris.Wrap(err, "favicon is not a PNG")
+	}
+	if config.Width != FaviconSize || config.Height != FaviconSize {
+		return "", eris.Errorf("favicon is %vx%v, expected %vx%v", config.Width, config.Height, FaviconSize, FaviconSize)
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// ReadLegacyPing checks whether the connection opens with a pre-Netty ping, consuming it if it does. extended is
+// set when the client is 1.4 or newer and understands the longer reply
+func (c *Conn) ReadLegacyPing() (legacy, extended bool, err error) {
+	first, err := c.buffered.Peek(1)
+	if err != nil || first[0] != LegacyPingID {
+		return false, false, err
+	}
+	if _, err = c.buffered.Discard(1); err != nil {
+		return true, false, err
+	}
+	// 1.4 and up follow with 0x01, beta clients send nothing else
+	if c.buffered.Buffered() > 0 {
+		next, err := c.buffered.Peek(1)
+		if err != nil {
+			return true, false, err
+		}
+		extended = next[0] == 0x01
+	}
+	return true, extended, nil
+}
+
+// WriteLegacyPingResponse replies to a legacy ping with the status as a kick packet, which the client shows
+// in its server list
+func (c *Conn) WriteLegacyPingResponse(status *ServerStatus, extended bool) error {
+	if err := c.Flush(); err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	err := writeLegacyPingResponse(c.writer, status, extended)
+	c.writeMu.Unlock()
+	if err != nil {
+		return err
+	}
+	return c.flushQueued()
+}
+
+func writeLegacyPingResponse(writer io.Writer, status *ServerStatus, extended bool) error {
+	motd := status.Description.PlainText()
+	var reply string
+	if extended {
+		reply = strings.Join([]string{
+			"§1",
+			fmt.Sprint(status.Version.Protocol),
+			status.Version.Name,
+			motd,
+			fmt.Sprint(status.Players.Online),
+			fmt.Sprint(status.Players.Max),
+		}, "\x00")
+	} else {
+		// § is the separator, so it can't be in the MOTD
+		reply = fmt.Sprintf("%v§%v§%v", strings.ReplaceAll(motd, "§", ""), status.Players.Online, status.Players.Max)
+	}
+
+	chars := utf16.Encode([]rune(reply))
+	buf := bytes.NewBuffer(make([]byte, 0, 3+len(chars)*2))
+	buf.WriteByte(legacyKickID)
+	buf.WriteByte(byte(len(chars) >> 8))
+	buf.WriteByte(byte(len(chars)))
+	for _, char := range chars {
+		buf.WriteByte(byte(char >> 8))
+		buf.WriteByte(byte(char))
+	}
+	_, err := buf.WriteTo(writer)
+	return err
+}
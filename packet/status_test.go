@@ -0,0 +1,109 @@
+package packet
+
+import (
+	"bytes"
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/png"
+	"net"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+var statusTestStatus = &ServerStatus{
+	Version: StatusVersion{Name: "1.16.5", Protocol: 754},
+	Players: StatusPlayers{
+		Max:    20,
+		Online: 1,
+		Sample: []StatusPlayerSample{{Name: "Notch", Id: "069a79f4-44e9-4726-a5be-fca90e38aaf5"}},
+	},
+	Description: ChatComponent{Text: "Hello ", Extra: []ChatComponent{{Text: "world", Color: "gold"}}},
+}
+
+func TestStatusResponse(t *testing.T) {
+	response, err := statusTestStatus.StatusResponse()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"version": {"name": "1.16.5", "protocol": 754},
+		"players": {"max": 20, "online": 1, "sample": [{"name": "Notch", "id": "069a79f4-44e9-4726-a5be-fca90e38aaf5"}]},
+		"description": {"text": "Hello ", "extra": [{"text": "world", "color": "gold"}]}
+	}`, response.JSONResponse)
+}
+
+func TestEncodeFavicon(t *testing.T) {
+	for _, size := range []int{FaviconSize, 32} {
+		buf := bytes.NewBuffer(nil)
+		assert.NoError(t, png.Encode(buf, image.NewRGBA(image.Rect(0, 0, size, size))))
+		favicon, err := EncodeFavicon(buf.Bytes())
+		if size != FaviconSize {
+			assert.Error(t, err)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, "data:image/png;base64,"+base64.StdEncoding.EncodeToString(buf.Bytes()), favicon)
+	}
+
+	_, err := EncodeFavicon([]byte("not a png"))
+	assert.Error(t, err)
+}
+
+func decodeLegacyReply(t *testing.T, bs []byte) string {
+	assert.Equal(t, byte(legacyKickID), bs[0])
+	length := int(bs[1])<<8 | int(bs[2])
+	assert.Equal(t, length*2, len(bs)-3)
+	chars := make([]uint16, length)
+	for i := range chars {
+		chars[i] = uint16(bs[3+i*2])<<8 | uint16(bs[4+i*2])
+	}
+	return string(utf16.Decode(chars))
+}
+
+func TestWriteLegacyPingResponse(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	assert.NoError(t, writeLegacyPingResponse(buf, statusTestStatus, true))
+	assert.Equal(t, "§1\x00754\x001.16.5\x00Hello world\x001\x0020", decodeLegacyReply(t, buf.Bytes()))
+
+	buf.Reset()
+	assert.NoError(t, writeLegacyPingResponse(buf, statusTestStatus, false))
+	assert.Equal(t, "Hello world§1§20", decodeLegacyReply(t, buf.Bytes()))
+}
+
+func TestReadLegacyPing(t *testing.T) {
+	tests := []struct {
+		name     string
+		opening  []byte
+		legacy   bool
+		extended bool
+	}{
+		{name: "Beta", opening: []byte{0xfe}, legacy: true},
+		{name: "1.4", opening: []byte{0xfe, 0x01}, legacy: true, extended: true},
+		{name: "1.6", opening: []byte{0xfe, 0x01, 0xfa}, legacy: true, extended: true},
+		{name: "Handshake", opening: []byte{0x10, 0x00}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			defer server.Close()
+			go func() {
+				_, _ = client.Write(test.opening)
+				_ = client.Close()
+			}()
+			conn := NewConn(server)
+			// Let the whole opening arrive, like it does in one TCP segment
+			_, _ = conn.buffered.Peek(len(test.opening))
+
+			legacy, extended, err := conn.ReadLegacyPing()
+			assert.NoError(t, err)
+			assert.Equal(t, test.legacy, legacy)
+			assert.Equal(t, test.extended, extended)
+		})
+	}
+}
+
+func TestChatComponentPlainText(t *testing.T) {
+	component := ChatComponent{Text: "a", Extra: []ChatComponent{{Text: "b", Extra: []ChatComponent{{Text: "c"}}}}}
+	assert.Equal(t, "abc", component.PlainText())
+	assert.True(t, strings.HasPrefix(component.JSON(), `{"text":"a"`))
+}
@@ -125,13 +125,12 @@ func (s *Short) ReadFrom(reader io.Reader) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	*s = Short(int16(ba[0])>>8 | int16(ba[1]))
+	*s = Short(int16(ba[0])<<8 | int16(ba[1]))
 	return int64(nn), nil
 }
 
 func (s Short) WriteTo(writer io.Writer) (int64, error) {
-	// TODO: Do we need to map to uint16 for this << logic?
-	nn, err := writer.Write([]byte{byte(s << 8), byte(s)})
+	nn, err := writer.Write([]byte{byte(s >> 8), byte(s)})
 	return int64(nn), err
 }
 
@@ -141,12 +140,12 @@ func (s *UnsignedShort) ReadFrom(reader io.Reader) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	*s = UnsignedShort(int16(ba[0])>>8 | int16(ba[1]))
+	*s = UnsignedShort(uint16(ba[0])<<8 | uint16(ba[1]))
 	return int64(nn), nil
 }
 
 func (s UnsignedShort) WriteTo(writer io.Writer) (int64, error) {
-	nn, err := writer.Write([]byte{byte(s << 8), byte(s)})
+	nn, err := writer.Write([]byte{byte(s >> 8), byte(s)})
 	return int64(nn), err
 }
 
@@ -198,18 +197,17 @@ func (f Float) WriteTo(writer io.Writer) (int64, error) {
 }
 
 func (f *Double) ReadFrom(reader io.Reader) (int64, error) {
-	var i Int
-	nn, err := i.ReadFrom(reader)
+	var l Long
+	nn, err := l.ReadFrom(reader)
 	if err != nil {
 		return 0, err
 	}
-	*f = Double(math.Float64frombits(uint64(i)))
+	*f = Double(math.Float64frombits(uint64(l)))
 	return nn, nil
 }
 
 func (f Double) WriteTo(writer io.Writer) (int64, error) {
-	// TODO: Should be Long?
-	return Int(math.Float64bits(float64(f))).WriteTo(writer)
+	return Long(math.Float64bits(float64(f))).WriteTo(writer)
 }
 
 func (f *Position) ReadFrom(reader io.Reader) (int64, error) {
@@ -337,15 +335,25 @@ func (s *UShort) ReadFrom(reader io.Reader) (byteCount int64, err error) {
 	return
 }
 
+// ReadFrom reads a string of up to MaxStringLength characters
 func (s *String) ReadFrom(reader io.Reader) (int64, error) {
-	var ba ByteArray
-	byteCount, err := ba.ReadFrom(reader)
+	var l VarInt
+	lenBytes, err := l.ReadFrom(reader)
 	if err != nil {
 		return 0, err
 	}
-
-	*s = String(ba)
-	return byteCount, nil
+	if err = checkLength("String", int64(l), maxStringBytes); err != nil {
+		return lenBytes, err
+	}
+	bs, err := readBytes(reader, int64(l))
+	if err != nil {
+		return lenBytes + int64(len(bs)), err
+	}
+	if err = checkStringLength("String", string(bs), MaxStringLength); err != nil {
+		return lenBytes + int64(len(bs)), err
+	}
+	*s = String(bs)
+	return lenBytes + int64(len(bs)), nil
 }
 
 func (s String) WriteTo(writer io.Writer) (int64, error) {
@@ -389,14 +397,16 @@ func ByteArrayFromLenReader(l VarInt, reader io.Reader) (ByteArray, error) {
 // ReadFrom creates a []byte, io.Reader needs to have a VarInt prefixing the byte data
 func (b *ByteArray) ReadFrom(reader io.Reader) (int64, error) {
 	var l VarInt
-	_, err := l.ReadFrom(reader)
+	lenBytes, err := l.ReadFrom(reader)
 	if err != nil {
 		return 0, err
 	}
-	bs := make([]byte, l)
-	nn, err := io.ReadFull(reader, bs)
+	if err = checkLength("ByteArray", int64(l), MaxFrameSize); err != nil {
+		return lenBytes, err
+	}
+	bs, err := readBytes(reader, int64(l))
 	*b = bs
-	return int64(nn), err
+	return lenBytes + int64(len(bs)), err
 }
 
 func (b ByteArray) WriteTo(writer io.Writer) (int64, error) {
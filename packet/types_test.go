@@ -3,6 +3,7 @@ package packet
 import (
 	"bytes"
 	"github.com/stretchr/testify/assert"
+	"reflect"
 	"testing"
 )
 
@@ -102,6 +103,38 @@ func TestLong_ReadFrom(t *testing.T) {
 	assert.Equal(t, l, readLong)
 }
 
+func TestFixedWidth_RoundTrip(t *testing.T) {
+	tests := []struct {
+		Value FieldEncoder
+		Read  FieldDecoder
+		Bytes []byte
+	}{
+		{Value: Short(-2), Read: new(Short), Bytes: []byte{0xff, 0xfe}},
+		{Value: UnsignedShort(25565), Read: new(UnsignedShort), Bytes: []byte{0x63, 0xdd}},
+		{Value: Double(1.5), Read: new(Double), Bytes: []byte{0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+	}
+	for _, test := range tests {
+		buf := bytes.NewBuffer(nil)
+		_, err := test.Value.WriteTo(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, test.Bytes, buf.Bytes())
+		_, err = test.Read.ReadFrom(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, test.Value, reflect.ValueOf(test.Read).Elem().Interface())
+	}
+}
+
+func TestString_ReadFromCountsPrefix(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	written, err := String("hello").WriteTo(buf)
+	assert.NoError(t, err)
+	var s String
+	read, err := s.ReadFrom(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), read)
+	assert.Equal(t, written, read)
+}
+
 func TestPosition_ReadFrom(t *testing.T) {
 	pos := &Position{
 		X: 200,
@@ -0,0 +1,249 @@
+package packet
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// height17 is the world height sent to 1.17 clients, which need it spelled out in each dimension type. It's the
+	// fixed height 1.16 clients assume
+	height17 = 256
+)
+
+type (
+	// Version is a protocol version the server speaks, with the packet IDs and layouts it uses
+	Version struct {
+		Protocol int32
+		// Name is the newest release using the protocol, shown in the server list
+		Name     string
+		Registry *Registry
+	}
+
+	// UnsupportedVersionError is returned for a handshake from a protocol version with no registry
+	UnsupportedVersionError struct {
+		Protocol int32
+	}
+
+	// joinGame17 is JoinGame as 1.17 sends it, with a height in every dimension type
+	joinGame17 struct {
+		EntityID            int32
+		IsHardcore          bool
+		Gamemode            uint8
+		PreviousGamemode    int8
+		WorldCount          int32               `pkt_type:"VarInt"`
+		WorldNames          []string            `pkt_len:"WorldCount"`
+		DimensionCodec      dimensionCodec17NBT `pkt_type:"nbt"`
+		Dimension           dimensionType17NBT  `pkt_type:"nbt"`
+		WorldName           string
+		HashedSeed          int64
+		MaxPlayers          int32 `pkt_type:"VarInt"`
+		ViewDistance        int32 `pkt_type:"VarInt"`
+		ReducedDebugInfo    bool
+		EnableRespawnScreen bool
+		IsDebug             bool
+		IsFlat              bool
+	}
+
+	dimensionCodec17NBT struct {
+		DimensionType struct {
+			Type  string
+			Value []dimensionTypeEntry17NBT
+		} `nbt:"minecraft:dimension_type"`
+		Biome BiomeRegistryNBT `nbt:"minecraft:worldgen/biome"`
+	}
+	dimensionTypeEntry17NBT struct {
+		Name    string
+		Id      int32
+		Element dimensionType17NBT
+	}
+	dimensionType17NBT struct {
+		PiglinSafe         bool `nbt:"piglin_safe"`
+		Natural            bool
+		AmbientLight       float32 `nbt:"ambient_light"`
+		FixedTime          int64   `nbt:"fixed_time" nbt_opt:"true"`
+		Infiniburn         string
+		RespawnAnchorWorks bool `nbt:"respawn_anchor_works"`
+		HasSkylight        bool `nbt:"has_skylight"`
+		BedWorks           bool `nbt:"bed_works"`
+		Effects            string
+		HasRaids           bool    `nbt:"has_raids"`
+		MinY               int32   `nbt:"min_y"`
+		Height             int32   `nbt:"height"`
+		LogicalHeight      int32   `nbt:"logical_height"`
+		CoordinateScale    float32 `nbt:"coordinate_scale"`
+		Ultrawarm          bool
+		HasCeiling         bool `nbt:"has_ceiling"`
+	}
+)
+
+var (
+	Version1_16_5 = &Version{Protocol: 754, Name: "1.16.5", Registry: DefaultRegistry}
+	Version1_17   = &Version{Protocol: 755, Name: "1.17", Registry: newRegistry17()}
+	// 1.17.1 only changed packets the server doesn't send yet
+	Version1_17_1 = &Version{Protocol: 756, Name: "1.17.1", Registry: Version1_17.Registry}
+
+	// SupportedVersions is every version a client can join with, oldest first
+	SupportedVersions = []*Version{Version1_16_5, Version1_17, Version1_17_1}
+)
+
+// newRegistry17 holds the packets of protocols 755 and 756, 1.17 added Sculk Vibration Signal at 0x05 and moved
+// everything after it along
+func newRegistry17() *Registry {
+	r := NewRegistry()
+	registerCommon(r)
+
+	r.Register(Play, Clientbound, 0x00, SpawnEntity{})
+	r.Register(Play, Clientbound, 0x01, SpawnExperienceOrb{})
+	r.Register(Play, Clientbound, 0x02, SpawnLivingEntity{})
+	r.Register(Play, Clientbound, 0x03, SpawnPainting{})
+	r.Register(Play, Clientbound, 0x04, SpawnPlayer{})
+	r.Register(Play, Clientbound, 0x06, EntityAnimation{})
+	r.Register(Play, Clientbound, 0x07, Statistics{})
+	r.Register(Play, Clientbound, 0x08, AcknowledgePlayerDigging{})
+	r.Register(Play, Clientbound, 0x09, BlockBreakAnimation{})
+	r.Register(Play, Clientbound, 0x1A, Disconnect{})
+	r.Register(Play, Clientbound, 0x21, KeepAlive{})
+	r.RegisterLayout(Play, Clientbound, 0x26, JoinGame{}, Layout{
+		Wire:     joinGame17{},
+		ToWire:   func(payload interface{}) interface{} { return joinGameTo17(payload.(JoinGame)) },
+		FromWire: func(wire interface{}) interface{} { return joinGameFrom17(wire.(joinGame17)) },
+	})
+
+	// Window Confirmation was dropped from serverbound, taking KeepAlive down one
+	r.Register(Play, Serverbound, 0x0F, KeepAliveResponse{})
+	return r
+}
+
+// LookupVersion finds the version for the protocol number sent in a handshake
+func LookupVersion(protocol int32) (*Version, error) {
+	for _, version := range SupportedVersions {
+		if version.Protocol == protocol {
+			return version, nil
+		}
+	}
+	return nil, &UnsupportedVersionError{Protocol: protocol}
+}
+
+// LatestVersion is the newest supported version, advertised to clients the server can't talk to
+func LatestVersion() *Version {
+	return SupportedVersions[len(SupportedVersions)-1]
+}
+
+func (e *UnsupportedVersionError) Error() string {
+	return fmt.Sprintf("unsupported protocol version %v", e.Protocol)
+}
+
+// Disconnect is the message shown to the client, naming the versions it could use instead
+func (e *UnsupportedVersionError) Disconnect() *Disconnect {
+	names := make([]string, len(SupportedVersions))
+	for i, version := range SupportedVersions {
+		names[i] = version.Name
+	}
+	list := names[0]
+	if len(names) > 1 {
+		list = strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+	}
+	return &Disconnect{Reason: TextComponent(fmt.Sprintf("Please use %v", list)).JSON()}
+}
+
+func joinGameTo17(j JoinGame) joinGame17 {
+	wire := joinGame17{
+		EntityID:            j.EntityID,
+		IsHardcore:          j.IsHardcore,
+		Gamemode:            j.Gamemode,
+		PreviousGamemode:    j.PreviousGamemode,
+		WorldCount:          j.WorldCount,
+		WorldNames:          j.WorldNames,
+		Dimension:           dimensionTypeTo17(j.Dimension),
+		WorldName:           j.WorldName,
+		HashedSeed:          j.HashedSeed,
+		MaxPlayers:          j.MaxPlayers,
+		ViewDistance:        j.ViewDistance,
+		ReducedDebugInfo:    j.ReducedDebugInfo,
+		EnableRespawnScreen: j.EnableRespawnScreen,
+		IsDebug:             j.IsDebug,
+		IsFlat:              j.IsFlat,
+	}
+	wire.DimensionCodec.DimensionType.Type = j.DimensionCodec.DimensionType.Type
+	for _, entry := range j.DimensionCodec.DimensionType.Value {
+		wire.DimensionCodec.DimensionType.Value = append(wire.DimensionCodec.DimensionType.Value, dimensionTypeEntry17NBT{
+			Name:    entry.Name,
+			Id:      entry.Id,
+			Element: dimensionTypeTo17(entry.Element),
+		})
+	}
+	wire.DimensionCodec.Biome = j.DimensionCodec.Biome
+	return wire
+}
+
+func joinGameFrom17(wire joinGame17) JoinGame {
+	j := JoinGame{
+		EntityID:            wire.EntityID,
+		IsHardcore:          wire.IsHardcore,
+		Gamemode:            wire.Gamemode,
+		PreviousGamemode:    wire.PreviousGamemode,
+		WorldCount:          wire.WorldCount,
+		WorldNames:          wire.WorldNames,
+		Dimension:           dimensionTypeFrom17(wire.Dimension),
+		WorldName:           wire.WorldName,
+		HashedSeed:          wire.HashedSeed,
+		MaxPlayers:          wire.MaxPlayers,
+		ViewDistance:        wire.ViewDistance,
+		ReducedDebugInfo:    wire.ReducedDebugInfo,
+		EnableRespawnScreen: wire.EnableRespawnScreen,
+		IsDebug:             wire.IsDebug,
+		IsFlat:              wire.IsFlat,
+	}
+	j.DimensionCodec.DimensionType.Type = wire.DimensionCodec.DimensionType.Type
+	for _, entry := range wire.DimensionCodec.DimensionType.Value {
+		j.DimensionCodec.DimensionType.Value = append(j.DimensionCodec.DimensionType.Value, DimensionTypeEntryNBT{
+			Name:    entry.Name,
+			Id:      entry.Id,
+			Element: dimensionTypeFrom17(entry.Element),
+		})
+	}
+	j.DimensionCodec.Biome = wire.DimensionCodec.Biome
+	return j
+}
+
+func dimensionTypeTo17(d DimensionTypeNBT) dimensionType17NBT {
+	return dimensionType17NBT{
+		PiglinSafe:         d.PiglinSafe,
+		Natural:            d.Natural,
+		AmbientLight:       d.AmbientLight,
+		FixedTime:          d.FixedTime,
+		Infiniburn:         d.Infiniburn,
+		RespawnAnchorWorks: d.RespawnAnchorWorks,
+		HasSkylight:        d.HasSkylight,
+		BedWorks:           d.BedWorks,
+		Effects:            d.Effects,
+		HasRaids:           d.HasRaids,
+		MinY:               0,
+		Height:             height17,
+		LogicalHeight:      d.LogicalHeight,
+		CoordinateScale:    d.CoordinateScale,
+		Ultrawarm:          d.Ultrawarm,
+		HasCeiling:         d.HasCeiling,
+	}
+}
+
+// dimensionTypeFrom17 drops the height, the server only builds 1.16 sized worlds
+func dimensionTypeFrom17(d dimensionType17NBT) DimensionTypeNBT {
+	return DimensionTypeNBT{
+		PiglinSafe:         d.PiglinSafe,
+		Natural:            d.Natural,
+		AmbientLight:       d.AmbientLight,
+		FixedTime:          d.FixedTime,
+		Infiniburn:         d.Infiniburn,
+		RespawnAnchorWorks: d.RespawnAnchorWorks,
+		HasSkylight:        d.HasSkylight,
+		BedWorks:           d.BedWorks,
+		Effects:            d.Effects,
+		HasRaids:           d.HasRaids,
+		LogicalHeight:      d.LogicalHeight,
+		CoordinateScale:    d.CoordinateScale,
+		Ultrawarm:          d.Ultrawarm,
+		HasCeiling:         d.HasCeiling,
+	}
+}
@@ -0,0 +1,185 @@
+package packet
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"minecraftServer/nbt"
+	"testing"
+)
+
+func testJoinGame() JoinGame {
+	overworld := DimensionTypeNBT{
+		Natural:         true,
+		Infiniburn:      "minecraft:infiniburn_overworld",
+		HasSkylight:     true,
+		BedWorks:        true,
+		Effects:         "minecraft:overworld",
+		HasRaids:        true,
+		LogicalHeight:   256,
+		CoordinateScale: 1,
+	}
+	joinGame := JoinGame{
+		EntityID:            7,
+		Gamemode:            1,
+		PreviousGamemode:    -1,
+		WorldCount:          1,
+		WorldNames:          []string{"minecraft:overworld"},
+		Dimension:           overworld,
+		WorldName:           "minecraft:overworld",
+		HashedSeed:          -42,
+		MaxPlayers:          20,
+		ViewDistance:        10,
+		EnableRespawnScreen: true,
+		IsFlat:              true,
+	}
+	joinGame.DimensionCodec.DimensionType.Type = "minecraft:dimension_type"
+	joinGame.DimensionCodec.DimensionType.Value = []DimensionTypeEntryNBT{
+		{Name: "minecraft:overworld", Id: 0, Element: overworld},
+	}
+	joinGame.DimensionCodec.Biome.Type = "minecraft:worldgen/biome"
+	joinGame.DimensionCodec.Biome.Value.Name = "minecraft:plains"
+	joinGame.DimensionCodec.Biome.Value.Id = 1
+	return joinGame
+}
+
+func TestJoinGameTranslation(t *testing.T) {
+	tests := []struct {
+		version *Version
+		id      VarInt
+		// height is the dimension type's height, nil when the version doesn't send one
+		height interface{}
+	}{
+		{version: Version1_16_5, id: 0x24, height: nil},
+		{version: Version1_17, id: 0x26, height: int32(height17)},
+		{version: Version1_17_1, id: 0x26, height: int32(height17)},
+	}
+	for _, test := range tests {
+		joinGame := testJoinGame()
+		pkt, err := test.version.Registry.Encode(Play, &joinGame)
+		assert.NoError(t, err)
+		assert.Equal(t, test.id, pkt.ID(), test.version.Name)
+
+		reader, err := readBack(t, pkt).DataReader()
+		assert.NoError(t, err)
+		data, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		buf := bytes.NewReader(data)
+
+		var (
+			entityID                 Int
+			hardcore                 Boolean
+			gamemode                 UnsignedByte
+			previousGamemode         Byte
+			worldCount               VarInt
+			worldName                String
+			codec, dimension         map[string]interface{}
+			currentWorld             String
+			hashedSeed               Long
+			maxPlayers, viewDistance VarInt
+			reducedDebug, respawn    Boolean
+			isDebug, isFlat          Boolean
+		)
+		assert.NoError(t, ReadFields(buf, &entityID, &hardcore, &gamemode, &previousGamemode, &worldCount, &worldName))
+		decoder := nbt.NewDecoder(buf)
+		assert.NoError(t, decoder.Decode(&codec))
+		assert.NoError(t, decoder.Decode(&dimension))
+		assert.NoError(t, ReadFields(buf, &currentWorld, &hashedSeed, &maxPlayers, &viewDistance, &reducedDebug,
+			&respawn, &isDebug, &isFlat))
+		assert.Equal(t, 0, buf.Len(), test.version.Name)
+
+		assert.Equal(t, Int(7), entityID)
+		assert.Equal(t, Byte(-1), previousGamemode)
+		assert.Equal(t, String("minecraft:overworld"), worldName)
+		assert.Equal(t, Long(-42), hashedSeed)
+		assert.Equal(t, VarInt(10), viewDistance)
+		assert.Equal(t, Boolean(true), isFlat)
+
+		assert.Equal(t, test.height, dimension["height"], test.version.Name)
+		entries := codec["minecraft:dimension_type"].(map[string]interface{})["value"].([]interface{})
+		element := entries[0].(map[string]interface{})["element"].(map[string]interface{})
+		assert.Equal(t, test.height, element["height"], test.version.Name)
+		assert.Equal(t, int32(256), element["logical_height"], test.version.Name)
+	}
+}
+
+func TestJoinGameLayoutRoundTrip(t *testing.T) {
+	joinGame := testJoinGame()
+	wire := joinGameTo17(joinGame)
+	assert.Equal(t, int32(0), wire.Dimension.MinY)
+	assert.Equal(t, int32(height17), wire.DimensionCodec.DimensionType.Value[0].Element.Height)
+	assert.Equal(t, joinGame, joinGameFrom17(wire))
+}
+
+func TestLookupVersion(t *testing.T) {
+	version, err := LookupVersion(754)
+	assert.NoError(t, err)
+	assert.Equal(t, Version1_16_5, version)
+	version, err = LookupVersion(756)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.17.1", version.Name)
+	assert.Equal(t, Version1_17_1, LatestVersion())
+
+	_, err = LookupVersion(47)
+	var unsupported *UnsupportedVersionError
+	assert.True(t, errors.As(err, &unsupported))
+	assert.Equal(t, "unsupported protocol version 47", err.Error())
+	assert.Equal(t, `{"text":"Please use 1.16.5, 1.17 or 1.17.1"}`, unsupported.Disconnect().Reason)
+}
+
+func TestVersionRegistries(t *testing.T) {
+	// Login hasn't changed, so both versions agree on it
+	for _, version := range SupportedVersions {
+		id, direction, err := version.Registry.ID(Login, &LoginSuccess{})
+		assert.NoError(t, err)
+		assert.Equal(t, VarInt(0x02), id)
+		assert.Equal(t, Clientbound, direction)
+	}
+	tests := []struct {
+		payload   interface{}
+		direction Direction
+		id        VarInt
+	}{
+		{payload: Disconnect{}, direction: Clientbound, id: 0x1A},
+		{payload: KeepAlive{}, direction: Clientbound, id: 0x21},
+		{payload: KeepAliveResponse{}, direction: Serverbound, id: 0x0F},
+	}
+	for _, test := range tests {
+		id, direction, err := Version1_17.Registry.ID(Play, test.payload)
+		assert.NoError(t, err)
+		assert.Equal(t, test.id, id)
+		assert.Equal(t, test.direction, direction)
+	}
+}
+
+func TestRegistryDecodesLayout(t *testing.T) {
+	type pongV2 struct {
+		Payload int32
+	}
+	r := NewRegistry()
+	r.RegisterLayout(Status, Clientbound, 0x01, Pong{}, Layout{
+		Wire:     pongV2{},
+		ToWire:   func(payload interface{}) interface{} { return pongV2{Payload: int32(payload.(Pong).Payload)} },
+		FromWire: func(wire interface{}) interface{} { return Pong{Payload: int64(wire.(pongV2).Payload)} },
+	})
+	pkt, err := r.Encode(Status, &Pong{Payload: 12})
+	assert.NoError(t, err)
+	pkt = readBack(t, pkt)
+	assert.Equal(t, VarInt(4), pkt.DataLength())
+
+	decoded, err := r.Decode(Status, Clientbound, pkt)
+	assert.NoError(t, err)
+	assert.Equal(t, &Pong{Payload: 12}, decoded)
+}
+
+func TestVersionRegistryDecodesJoinGame(t *testing.T) {
+	joinGame := testJoinGame()
+	for _, version := range SupportedVersions {
+		pkt, err := version.Registry.Encode(Play, &joinGame)
+		assert.NoError(t, err)
+		decoded, err := version.Registry.Decode(Play, Clientbound, readBack(t, pkt))
+		assert.NoError(t, err, version.Name)
+		assert.Equal(t, &joinGame, decoded, version.Name)
+	}
+}
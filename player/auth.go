@@ -0,0 +1,78 @@
+package player
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/rotisserie/eris"
+	"minecraftServer/mojang"
+	"strings"
+)
+
+type (
+	// AuthMode picks where a player's identity comes from during Login
+	AuthMode byte
+
+	// ForwardedInfo is what a proxy in BungeeCord forwarding mode appends to the handshake's server address
+	ForwardedInfo struct {
+		Host       string
+		ClientIP   string
+		UUID       uuid.UUID
+		Properties []mojang.ProfileProperty
+	}
+)
+
+const (
+	// Offline trusts the username and derives the UUID from it
+	Offline AuthMode = iota
+	// Online encrypts the connection and checks the session with Mojang
+	Online
+	// ProxyForwarding trusts the identity a proxy forwards in the handshake, the proxy does the authentication
+	ProxyForwarding
+)
+
+var authModeNames = []string{"offline", "online", "proxy"}
+
+func ParseAuthMode(name string) (AuthMode, error) {
+	for i, modeName := range authModeNames {
+		if strings.EqualFold(name, modeName) {
+			return AuthMode(i), nil
+		}
+	}
+	return Offline, eris.Errorf("unknown auth mode '%v', expected one of %v", name, strings.Join(authModeNames, ", "))
+}
+
+func (m AuthMode) String() string {
+	return authModeNames[m]
+}
+
+// OfflineUUID derives the UUID vanilla gives a player in offline mode, a version 3 UUID of "OfflinePlayer:<name>"
+func OfflineUUID(name string) uuid.UUID {
+	id := uuid.UUID(md5.Sum([]byte("OfflinePlayer:" + name)))
+	id[6] = id[6]&0x0f | 0x30
+	id[8] = id[8]&0x3f | 0x80
+	return id
+}
+
+// ParseForwardedAddress splits a handshake server address of the form host\x00client ip\x00uuid\x00properties json
+func ParseForwardedAddress(address string) (*ForwardedInfo, error) {
+	parts := strings.Split(address, "\x00")
+	if len(parts) != 3 && len(parts) != 4 {
+		return nil, eris.New("handshake has no forwarded player info, is the proxy set to forward it?")
+	}
+	id, err := uuid.Parse(parts[2])
+	if err != nil {
+		return nil, eris.Wrapf(err, "invalid forwarded uuid '%v'", parts[2])
+	}
+	info := &ForwardedInfo{
+		Host:     parts[0],
+		ClientIP: parts[1],
+		UUID:     id,
+	}
+	if len(parts) == 4 {
+		if err = json.Unmarshal([]byte(parts[3]), &info.Properties); err != nil {
+			return nil, eris.Wrap(err, "invalid forwarded properties")
+		}
+	}
+	return info, nil
+}
@@ -0,0 +1,51 @@
+package player
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"minecraftServer/mojang"
+	"testing"
+)
+
+func TestOfflineUUID(t *testing.T) {
+	id := OfflineUUID("Notch")
+	assert.Equal(t, uuid.MustParse("b50ad385-829d-3141-a216-7e7d7539ba7f"), id)
+	assert.Equal(t, uuid.Version(3), id.Version())
+	assert.Equal(t, uuid.RFC4122, id.Variant())
+	assert.Equal(t, id, OfflineUUID("Notch"))
+	assert.NotEqual(t, id, OfflineUUID("notch"))
+}
+
+func TestParseAuthMode(t *testing.T) {
+	for _, mode := range []AuthMode{Offline, Online, ProxyForwarding} {
+		parsed, err := ParseAuthMode(mode.String())
+		assert.NoError(t, err)
+		assert.Equal(t, mode, parsed)
+	}
+	mode, err := ParseAuthMode("ONLINE")
+	assert.NoError(t, err)
+	assert.Equal(t, Online, mode)
+	_, err = ParseAuthMode("bungee")
+	assert.Error(t, err)
+}
+
+func TestParseForwardedAddress(t *testing.T) {
+	info, err := ParseForwardedAddress("play.example.com\x00203.0.113.7\x00069a79f444e94726a5befca90e38aaf5\x00" +
+		`[{"name":"textures","value":"e30=","signature":"c2ln"}]`)
+	assert.NoError(t, err)
+	assert.Equal(t, &ForwardedInfo{
+		Host:       "play.example.com",
+		ClientIP:   "203.0.113.7",
+		UUID:       uuid.MustParse("069a79f4-44e9-4726-a5be-fca90e38aaf5"),
+		Properties: []mojang.ProfileProperty{{Name: "textures", Value: "e30=", Signature: "c2ln"}},
+	}, info)
+
+	info, err = ParseForwardedAddress("localhost\x00127.0.0.1\x00069a79f444e94726a5befca90e38aaf5")
+	assert.NoError(t, err)
+	assert.Empty(t, info.Properties)
+
+	_, err = ParseForwardedAddress("localhost")
+	assert.Error(t, err)
+	_, err = ParseForwardedAddress("localhost\x00127.0.0.1\x00not-a-uuid")
+	assert.Error(t, err)
+}
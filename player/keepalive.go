@@ -0,0 +1,76 @@
+package player
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+type (
+	// KeepAlive tracks the KeepAlive a player has to answer, only one is outstanding at a time
+	KeepAlive struct {
+		mu      sync.Mutex
+		pending bool
+		id      int64
+		sentAt  time.Time
+	}
+
+	// InvalidKeepAliveError is returned for a response that doesn't match the KeepAlive that was sent
+	InvalidKeepAliveError struct {
+		// Expected is nil if no KeepAlive was outstanding
+		Expected *int64
+		Got      int64
+	}
+)
+
+// Next picks a random ID for a KeepAlive sent at now, returning false while the last one is unanswered
+func (k *KeepAlive) Next(now time.Time) (int64, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.pending {
+		return 0, false
+	}
+	k.pending = true
+	k.id = rand.Int63()
+	k.sentAt = now
+	return k.id, true
+}
+
+// Cancel forgets the outstanding KeepAlive if it's still id, for one that never reached the client
+func (k *KeepAlive) Cancel(id int64) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.pending && k.id == id {
+		k.pending = false
+	}
+}
+
+// Expired reports whether the outstanding KeepAlive has waited longer than timeout for its response
+func (k *KeepAlive) Expired(now time.Time, timeout time.Duration) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.pending && now.Sub(k.sentAt) > timeout
+}
+
+// Answer checks a response received at now, returning how long the round trip took
+func (k *KeepAlive) Answer(id int64, now time.Time) (time.Duration, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if !k.pending {
+		return 0, &InvalidKeepAliveError{Got: id}
+	}
+	if id != k.id {
+		expected := k.id
+		return 0, &InvalidKeepAliveError{Expected: &expected, Got: id}
+	}
+	k.pending = false
+	return now.Sub(k.sentAt), nil
+}
+
+func (e *InvalidKeepAliveError) Error() string {
+	if e.Expected == nil {
+		return fmt.Sprintf("unexpected KeepAlive %v", e.Got)
+	}
+	return fmt.Sprintf("expected KeepAlive %v, got %v", *e.Expected, e.Got)
+}
@@ -0,0 +1,52 @@
+package player
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestKeepAlive(t *testing.T) {
+	var k KeepAlive
+	start := time.Unix(1000, 0)
+
+	_, err := k.Answer(1, start)
+	var invalid *InvalidKeepAliveError
+	assert.True(t, errors.As(err, &invalid))
+	assert.Nil(t, invalid.Expected)
+
+	id, ok := k.Next(start)
+	assert.True(t, ok)
+	_, ok = k.Next(start.Add(time.Second))
+	assert.False(t, ok, "only one KeepAlive is outstanding at a time")
+	assert.False(t, k.Expired(start.Add(30*time.Second), 30*time.Second))
+	assert.True(t, k.Expired(start.Add(31*time.Second), 30*time.Second))
+
+	_, err = k.Answer(id+1, start.Add(time.Second))
+	assert.True(t, errors.As(err, &invalid))
+	assert.Equal(t, id, *invalid.Expected)
+
+	roundTrip, err := k.Answer(id, start.Add(80*time.Millisecond))
+	assert.NoError(t, err)
+	assert.Equal(t, 80*time.Millisecond, roundTrip)
+	assert.False(t, k.Expired(start.Add(time.Minute), 30*time.Second))
+	id, ok = k.Next(start.Add(15 * time.Second))
+	assert.True(t, ok)
+
+	// A dropped KeepAlive can't be answered, so it isn't waited on
+	k.Cancel(id + 1)
+	assert.True(t, k.Expired(start.Add(time.Minute), 30*time.Second), "only the outstanding ID is cancelled")
+	k.Cancel(id)
+	assert.False(t, k.Expired(start.Add(time.Minute), 30*time.Second))
+	_, ok = k.Next(start.Add(time.Minute))
+	assert.True(t, ok)
+}
+
+func TestRecordLatency(t *testing.T) {
+	p := NewPlayer(nil)
+	p.RecordLatency(100 * time.Millisecond)
+	assert.Equal(t, 100*time.Millisecond, p.Latency())
+	p.RecordLatency(200 * time.Millisecond)
+	assert.Equal(t, 125*time.Millisecond, p.Latency())
+}
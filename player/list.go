@@ -0,0 +1,77 @@
+package player
+
+import (
+	"github.com/google/uuid"
+	"math/rand"
+	"minecraftServer/packet"
+	"sync"
+)
+
+type (
+	// List tracks the players who are logged in
+	List struct {
+		mu      sync.RWMutex
+		players map[uuid.UUID]*Player
+	}
+)
+
+func NewList() *List {
+	return &List{
+		players: map[uuid.UUID]*Player{},
+	}
+}
+
+// Add records a player as online, returning false if a player with the same UUID already is
+func (l *List) Add(p *Player) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.players[p.UUID]; ok {
+		return false
+	}
+	l.players[p.UUID] = p
+	return true
+}
+
+// Remove takes a player out of the list, if they're the one recorded under their UUID
+func (l *List) Remove(p *Player) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.players[p.UUID] == p {
+		delete(l.players, p.UUID)
+	}
+}
+
+func (l *List) Count() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.players)
+}
+
+// Sample returns up to n random online players, like the server list shows when hovering the player count
+func (l *List) Sample(n int) []*Player {
+	l.mu.RLock()
+	sample := make([]*Player, 0, len(l.players))
+	for _, p := range l.players {
+		sample = append(sample, p)
+	}
+	l.mu.RUnlock()
+
+	rand.Shuffle(len(sample), func(i, j int) {
+		sample[i], sample[j] = sample[j], sample[i]
+	})
+	if len(sample) > n {
+		sample = sample[:n]
+	}
+	return sample
+}
+
+// QueueStats reports the outbound queue of every online player by name
+func (l *List) QueueStats() map[string]packet.QueueStats {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	stats := make(map[string]packet.QueueStats, len(l.players))
+	for _, p := range l.players {
+		stats[p.Username] = p.QueueStats()
+	}
+	return stats
+}
@@ -0,0 +1,31 @@
+package player
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestList(t *testing.T) {
+	list := NewList()
+	var players []*Player
+	for i := 0; i < 5; i++ {
+		p := &Player{Username: fmt.Sprint("player", i)}
+		p.UUID = OfflineUUID(p.Username)
+		assert.True(t, list.Add(p))
+		players = append(players, p)
+	}
+	assert.Equal(t, 5, list.Count())
+
+	duplicate := &Player{Username: "player0", UUID: players[0].UUID}
+	assert.False(t, list.Add(duplicate))
+	// Removing the rejected duplicate leaves the original online
+	list.Remove(duplicate)
+	assert.Equal(t, 5, list.Count())
+
+	assert.Len(t, list.Sample(3), 3)
+	assert.ElementsMatch(t, players, list.Sample(10))
+
+	list.Remove(players[0])
+	assert.Equal(t, 4, list.Count())
+}
@@ -1,17 +1,82 @@
 package player
 
-import "net"
+import (
+	"github.com/google/uuid"
+	"github.com/rotisserie/eris"
+	"minecraftServer/mojang"
+	"minecraftServer/packet"
+	"sync/atomic"
+	"time"
+)
 
 type Player struct {
 	// TODO: How should we manage all of these connections? -> The player probably doesn't need it directly
-	conn            *net.TCPConn
+	conn            *packet.Conn
 	State           State
 	ProtocolVersion uint16
 	Username        string
-	Compression     CompressionState
+	UUID            uuid.UUID
+	// Properties hold the skin and cape from the session server
+	Properties  []mojang.ProfileProperty
+	Compression CompressionState
+	KeepAlive   KeepAlive
+
+	// latency is the smoothed KeepAlive round trip in nanoseconds, accessed atomically since the tab list reads it
+	// from other goroutines
+	latency int64
 }
 
 type CompressionState struct {
 	Enabled   bool
 	Threshold uint64
 }
+
+func NewPlayer(conn *packet.Conn) *Player {
+	return &Player{
+		conn:  conn,
+		State: Handshaking,
+	}
+}
+
+func (p *Player) Conn() *packet.Conn {
+	return p.conn
+}
+
+// QueueStats reports how far behind the client is with the packets sent to it
+func (p *Player) QueueStats() packet.QueueStats {
+	return p.conn.QueueStats()
+}
+
+// Latency is the player's ping as shown in the tab list
+func (p *Player) Latency() time.Duration {
+	return time.Duration(atomic.LoadInt64(&p.latency))
+}
+
+// RecordLatency averages in a KeepAlive round trip, weighting the new one by a quarter like vanilla so one slow
+// response doesn't make the ping jump
+func (p *Player) RecordLatency(roundTrip time.Duration) {
+	latency := time.Duration(atomic.LoadInt64(&p.latency))
+	if latency == 0 {
+		latency = roundTrip
+	} else {
+		latency = (latency*3 + roundTrip) / 4
+	}
+	atomic.StoreInt64(&p.latency, int64(latency))
+}
+
+// EnableCompression sends SetCompression to the client and switches the connection to compressed framing,
+// which has to happen during Login before LoginSuccess
+func (p *Player) EnableCompression(threshold int) error {
+	if threshold < 0 {
+		return eris.Errorf("invalid compression threshold %v", threshold)
+	}
+	if err := p.conn.Send(Login, &packet.SetCompression{Threshold: int32(threshold)}); err != nil {
+		return eris.Wrap(err, "failed to send SetCompression")
+	}
+	p.conn.SetCompression(threshold)
+	p.Compression = CompressionState{
+		Enabled:   true,
+		Threshold: uint64(threshold),
+	}
+	return nil
+}
@@ -1,29 +1,48 @@
 package player
 
 import (
-	"encoding/json"
+	"fmt"
 	"minecraftServer/packet"
 )
 
 type (
-	State byte
+	// State is the connection state, shared with the packet registry
+	State = packet.State
+
+	// InvalidTransitionError is returned for a state change the protocol doesn't have, like Status to Play
+	InvalidTransitionError struct {
+		From, To State
+	}
 )
 
 const (
-	Handshaking State = iota
-	Status
-	Login
-	Play
+	Handshaking = packet.Handshaking
+	Status      = packet.Status
+	Login       = packet.Login
+	Play        = packet.Play
 )
 
-func StateFromVarInt(varInt packet.VarInt) State {
-	return []State{Handshaking, Status, Login, Play}[varInt]
+// transitions lists the states each state can move on to, Status and Play are only left by disconnecting
+var transitions = map[State][]State{
+	Handshaking: {Status, Login},
+	Login:       {Play},
+}
+
+// StateFromVarInt converts the next state of a Handshake, which can only be Status or Login
+func StateFromVarInt(varInt packet.VarInt) (State, error) {
+	return packet.StateFromVarInt(varInt)
 }
 
-func (s State) String() string {
-	return []string{"Handshaking", "Status", "Login", "Play"}[s]
+// Transition checks that a connection in from can move to to
+func Transition(from, to State) error {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+	return &InvalidTransitionError{From: from, To: to}
 }
 
-func (s State) MarshalJSON() ([]byte, error) {
-	return json.Marshal(s.String())
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("can't move from %v to %v", e.From, e.To)
 }
@@ -0,0 +1,34 @@
+package player
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTransition(t *testing.T) {
+	tests := []struct {
+		from, to State
+		valid    bool
+	}{
+		{from: Handshaking, to: Status, valid: true},
+		{from: Handshaking, to: Login, valid: true},
+		{from: Login, to: Play, valid: true},
+		{from: Handshaking, to: Play},
+		{from: Status, to: Login},
+		{from: Status, to: Play},
+		{from: Login, to: Status},
+		{from: Play, to: Login},
+		{from: Play, to: Handshaking},
+	}
+	for _, test := range tests {
+		err := Transition(test.from, test.to)
+		if test.valid {
+			assert.NoError(t, err, "%v to %v", test.from, test.to)
+			continue
+		}
+		var invalid *InvalidTransitionError
+		assert.True(t, errors.As(err, &invalid), "%v to %v", test.from, test.to)
+	}
+	assert.EqualError(t, Transition(Status, Play), "can't move from Status to Play")
+}
@@ -0,0 +1,389 @@
+package region
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/rotisserie/eris"
+	"io"
+	"minecraftServer/nbt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// https://minecraft.fandom.com/wiki/Region_file_format
+type (
+	// Region is an open Anvil r.X.Z.mca file holding a 32x32 area of chunks
+	Region struct {
+		// Compression used by WriteChunk, one of nbt.GzipCompressed, nbt.ZLibCompressed or nbt.Uncompressed
+		Compression byte
+
+		mu         sync.Mutex
+		path       string
+		file       *os.File
+		locations  [ChunksPerRegion]location
+		timestamps [ChunksPerRegion]uint32
+	}
+
+	// location is a chunk's position in the file, in 4KiB sectors
+	location struct {
+		Offset uint32
+		Count  uint8
+	}
+)
+
+const (
+	SectorSize      = 4096
+	ChunksPerRegion = 32 * 32
+	// headerSectors covers the location table followed by the timestamp table
+	headerSectors = 2
+	// chunkHeaderSize is the big endian length followed by the compression type
+	chunkHeaderSize = 5
+	maxSectorCount  = 255
+	maxSectorOffset = 1<<24 - 1
+
+	compressionGzip = byte(1)
+	compressionZLib = byte(2)
+	compressionNone = byte(3)
+)
+
+// FileName returns the name of the region file holding the chunk at the given chunk coordinates
+func FileName(chunkX, chunkZ int) string {
+	return fmt.Sprintf("r.%v.%v.mca", chunkX>>5, chunkZ>>5)
+}
+
+// ParseFileName returns the region coordinates from an r.X.Z.mca file name
+func ParseFileName(name string) (x, z int, err error) {
+	if _, err = fmt.Sscanf(filepath.Base(name), "r.%d.%d.mca", &x, &z); err != nil {
+		return 0, 0, eris.Wrapf(err, "invalid region file name '%v'", name)
+	}
+	return
+}
+
+// Open opens the region file at path, creating an empty one if it doesn't exist
+func Open(path string) (*Region, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, eris.Wrapf(err, "failed to open region '%v'", path)
+	}
+	r := &Region{
+		Compression: nbt.ZLibCompressed,
+		path:        path,
+		file:        file,
+	}
+	if err = r.readHeader(); err != nil {
+		file.Close()
+		return nil, eris.Wrapf(err, "failed to read header of region '%v'", path)
+	}
+	return r, nil
+}
+
+func (r *Region) readHeader() error {
+	info, err := r.file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 {
+		return r.writeHeader()
+	}
+	if info.Size() < headerSectors*SectorSize {
+		return eris.Errorf("file is %v bytes, shorter than the header", info.Size())
+	}
+
+	header := make([]byte, headerSectors*SectorSize)
+	if _, err = r.file.ReadAt(header, 0); err != nil {
+		return err
+	}
+	for i := 0; i < ChunksPerRegion; i++ {
+		entry := binary.BigEndian.Uint32(header[i*4:])
+		r.locations[i] = location{
+			Offset: entry >> 8,
+			Count:  uint8(entry),
+		}
+		r.timestamps[i] = binary.BigEndian.Uint32(header[SectorSize+i*4:])
+	}
+	return nil
+}
+
+func (r *Region) writeHeader() error {
+	_, err := r.file.WriteAt(r.header(), 0)
+	return err
+}
+
+func (r *Region) header() []byte {
+	header := make([]byte, headerSectors*SectorSize)
+	for i := 0; i < ChunksPerRegion; i++ {
+		binary.BigEndian.PutUint32(header[i*4:], r.locations[i].Offset<<8|uint32(r.locations[i].Count))
+		binary.BigEndian.PutUint32(header[SectorSize+i*4:], r.timestamps[i])
+	}
+	return header
+}
+
+// index maps chunk coordinates to their slot in the header, world chunk coordinates are accepted too
+func index(x, z int) int {
+	return (x & 31) + (z&31)*32
+}
+
+// HasChunk reports whether the chunk has been generated
+func (r *Region) HasChunk(x, z int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.locations[index(x, z)].Offset != 0
+}
+
+// Timestamp returns when the chunk was last written
+func (r *Region) Timestamp(x, z int) time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return time.Unix(int64(r.timestamps[index(x, z)]), 0)
+}
+
+// ReadChunk decodes the chunk into a struct or *nbt.Compound
+func (r *Region) ReadChunk(x, z int, v interface{}) error {
+	r.mu.Lock()
+	data, err := r.readRaw(index(x, z))
+	r.mu.Unlock()
+	if err != nil {
+		return eris.Wrapf(err, "failed to read chunk %v,%v", x, z)
+	}
+
+	var compression byte
+	switch data[0] {
+	case compressionGzip:
+		compression = nbt.GzipCompressed
+	case compressionZLib:
+		compression = nbt.ZLibCompressed
+	case compressionNone:
+		compression = nbt.Uncompressed
+	default:
+		return eris.Errorf("unknown compression type %v for chunk %v,%v", data[0], x, z)
+	}
+
+	reader, err := nbt.CompressWrapReader(compression, bytes.NewReader(data[1:]))
+	if err != nil {
+		return eris.Wrapf(err, "failed to decompress chunk %v,%v", x, z)
+	}
+	defer reader.Close()
+	if err = nbt.UnmarshalFrom(reader, v); err != nil {
+		return eris.Wrapf(err, "failed to decode chunk %v,%v", x, z)
+	}
+	return nil
+}
+
+// readRaw returns the compression type byte followed by the compressed chunk data
+func (r *Region) readRaw(i int) ([]byte, error) {
+	loc := r.locations[i]
+	if loc.Offset == 0 {
+		return nil, eris.New("chunk has not been generated")
+	}
+
+	header := make([]byte, chunkHeaderSize)
+	if _, err := r.file.ReadAt(header, int64(loc.Offset)*SectorSize); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header)
+	if length == 0 || int64(length)+4 > int64(loc.Count)*SectorSize {
+		return nil, eris.Errorf("chunk length %v doesn't fit in %v sectors", length, loc.Count)
+	}
+
+	data := make([]byte, length)
+	if _, err := r.file.ReadAt(data, int64(loc.Offset)*SectorSize+4); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// WriteChunk encodes a struct or *nbt.Compound as the chunk, reusing its sectors when it still fits
+func (r *Region) WriteChunk(x, z int, v interface{}) error {
+	var compression byte
+	switch r.Compression {
+	case nbt.GzipCompressed:
+		compression = compressionGzip
+	case nbt.ZLibCompressed:
+		compression = compressionZLib
+	case nbt.Uncompressed:
+		compression = compressionNone
+	default:
+		return eris.Errorf("unknown compression %v", r.Compression)
+	}
+
+	buf := bytes.NewBuffer(make([]byte, chunkHeaderSize))
+	if err := nbt.Write(buf, v, r.Compression); err != nil {
+		return eris.Wrapf(err, "failed to encode chunk %v,%v", x, z)
+	}
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data, uint32(len(data)-4))
+	data[4] = compression
+
+	sectors := (len(data) + SectorSize - 1) / SectorSize
+	if sectors > maxSectorCount {
+		return eris.Errorf("chunk %v,%v needs %v sectors, more than the %v a region can hold", x, z, sectors, maxSectorCount)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := index(x, z)
+	offset, err := r.allocate(i, sectors)
+	if err != nil {
+		return err
+	}
+	// Pad to a whole sector so the file size stays a multiple of SectorSize
+	padded := make([]byte, sectors*SectorSize)
+	copy(padded, data)
+	if _, err = r.file.WriteAt(padded, int64(offset)*SectorSize); err != nil {
+		return eris.Wrapf(err, "failed to write chunk %v,%v", x, z)
+	}
+
+	r.locations[i] = location{Offset: offset, Count: uint8(sectors)}
+	r.timestamps[i] = uint32(time.Now().Unix())
+	return r.writeHeader()
+}
+
+// allocate finds room for sectors, preferring the chunk's current space, then the first free gap, then the end of the file
+func (r *Region) allocate(i int, sectors int) (uint32, error) {
+	if loc := r.locations[i]; loc.Offset != 0 && int(loc.Count) >= sectors {
+		return loc.Offset, nil
+	}
+
+	used := r.usedSectors(i)
+	run := 0
+	for sector := uint32(headerSectors); sector < uint32(len(used)); sector++ {
+		if used[sector] {
+			run = 0
+			continue
+		}
+		run++
+		if run == sectors {
+			return sector - uint32(sectors) + 1, nil
+		}
+	}
+
+	offset := uint32(len(used) - run)
+	if offset < headerSectors {
+		offset = headerSectors
+	}
+	if offset+uint32(sectors) > maxSectorOffset {
+		return 0, eris.New("region file is full")
+	}
+	return offset, nil
+}
+
+// usedSectors marks every sector occupied by the header or a chunk other than skip
+func (r *Region) usedSectors(skip int) []bool {
+	end := uint32(headerSectors)
+	for i, loc := range r.locations {
+		if i != skip && loc.Offset != 0 && loc.Offset+uint32(loc.Count) > end {
+			end = loc.Offset + uint32(loc.Count)
+		}
+	}
+	used := make([]bool, end)
+	for s := 0; s < headerSectors; s++ {
+		used[s] = true
+	}
+	for i, loc := range r.locations {
+		if i == skip || loc.Offset == 0 {
+			continue
+		}
+		for s := loc.Offset; s < loc.Offset+uint32(loc.Count); s++ {
+			used[s] = true
+		}
+	}
+	return used
+}
+
+// DeleteChunk removes the chunk, its sectors are reused by later writes or reclaimed by Compact
+func (r *Region) DeleteChunk(x, z int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := index(x, z)
+	r.locations[i] = location{}
+	r.timestamps[i] = 0
+	return r.writeHeader()
+}
+
+// Compact rewrites the region with every chunk packed after the header, dropping unused sectors
+func (r *Region) Compact() (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".tmp")
+	if err != nil {
+		return eris.Wrapf(err, "failed to create temporary file for '%v'", r.path)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	if err = tmp.Chmod(0644); err != nil {
+		return err
+	}
+
+	// Keep chunks in their current file order so reads stay mostly sequential
+	order := make([]int, 0, ChunksPerRegion)
+	for i, loc := range r.locations {
+		if loc.Offset != 0 {
+			order = append(order, i)
+		}
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return r.locations[order[a]].Offset < r.locations[order[b]].Offset
+	})
+
+	locations := r.locations
+	offset := uint32(headerSectors)
+	for _, i := range order {
+		loc := r.locations[i]
+		sector := io.NewSectionReader(r.file, int64(loc.Offset)*SectorSize, int64(loc.Count)*SectorSize)
+		if _, err = tmp.Seek(int64(offset)*SectorSize, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err = io.Copy(tmp, sector); err != nil {
+			return eris.Wrapf(err, "failed to copy chunk at sector %v", loc.Offset)
+		}
+		locations[i].Offset = offset
+		offset += uint32(loc.Count)
+	}
+
+	old := r.locations
+	r.locations = locations
+	_, err = tmp.WriteAt(r.header(), 0)
+	if err == nil {
+		err = tmp.Truncate(int64(offset) * SectorSize)
+	}
+	if err != nil {
+		r.locations = old
+		return eris.Wrap(err, "failed to write compacted region")
+	}
+
+	if err = os.Rename(tmp.Name(), r.path); err != nil {
+		r.locations = old
+		return eris.Wrapf(err, "failed to replace '%v'", r.path)
+	}
+	r.file.Close()
+	r.file = tmp
+	return nil
+}
+
+// Size returns the size of the region file in bytes
+func (r *Region) Size() (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	info, err := r.file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (r *Region) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
@@ -0,0 +1,144 @@
+package region
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"minecraftServer/nbt"
+	"path/filepath"
+	"testing"
+)
+
+type testChunk struct {
+	DataVersion int32 `nbt:"DataVersion"`
+	Level       struct {
+		XPos   int32  `nbt:"xPos"`
+		ZPos   int32  `nbt:"zPos"`
+		Status string `nbt:"Status"`
+		Blocks []byte `nbt:"Blocks"`
+	} `nbt:"Level"`
+}
+
+func makeChunk(x, z int32, size int) testChunk {
+	var chunk testChunk
+	chunk.DataVersion = 2586
+	chunk.Level.XPos = x
+	chunk.Level.ZPos = z
+	chunk.Level.Status = "full"
+	chunk.Level.Blocks = make([]byte, size)
+	// Random data so compression can't shrink it below the requested size
+	rand.New(rand.NewSource(int64(size))).Read(chunk.Level.Blocks)
+	return chunk
+}
+
+func TestFileName(t *testing.T) {
+	assert.Equal(t, "r.0.0.mca", FileName(0, 31))
+	assert.Equal(t, "r.-1.1.mca", FileName(-1, 32))
+
+	x, z, err := ParseFileName("/world/region/r.-3.12.mca")
+	assert.NoError(t, err)
+	assert.Equal(t, -3, x)
+	assert.Equal(t, 12, z)
+
+	_, _, err = ParseFileName("level.dat")
+	assert.Error(t, err)
+}
+
+func TestRegionWriteRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "r.0.0.mca")
+	r, err := Open(path)
+	assert.NoError(t, err)
+
+	for _, compression := range []byte{nbt.ZLibCompressed, nbt.GzipCompressed, nbt.Uncompressed} {
+		r.Compression = compression
+		chunk := makeChunk(1, 2, 100)
+		assert.NoError(t, r.WriteChunk(1, 2, &chunk))
+		assert.True(t, r.HasChunk(1, 2))
+		assert.False(t, r.HasChunk(2, 1))
+		assert.False(t, r.Timestamp(1, 2).IsZero())
+
+		var decoded testChunk
+		assert.NoError(t, r.ReadChunk(1, 2, &decoded))
+		assert.Equal(t, chunk, decoded)
+
+		var tree nbt.Compound
+		assert.NoError(t, r.ReadChunk(1, 2, &tree))
+		status, err := tree.GetPath("Level.Status")
+		assert.NoError(t, err)
+		assert.Equal(t, nbt.String("full"), *status.(*nbt.String))
+	}
+
+	// World chunk coordinates map onto the same slot
+	var decoded testChunk
+	assert.NoError(t, r.ReadChunk(33, 34, &decoded))
+	assert.Error(t, r.ReadChunk(3, 3, &decoded))
+	assert.NoError(t, r.Close())
+
+	r, err = Open(path)
+	assert.NoError(t, err)
+	defer r.Close()
+	assert.NoError(t, r.ReadChunk(1, 2, &decoded))
+	assert.Equal(t, int32(2), decoded.Level.ZPos)
+}
+
+func TestRegionRelocateAndCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "r.0.0.mca")
+	r, err := Open(path)
+	assert.NoError(t, err)
+	defer r.Close()
+
+	chunks := map[[2]int]testChunk{}
+	for i := 0; i < 4; i++ {
+		chunk := makeChunk(int32(i), 0, 5000)
+		chunks[[2]int{i, 0}] = chunk
+		assert.NoError(t, r.WriteChunk(i, 0, &chunk))
+	}
+
+	// Growing a chunk moves it past the others, leaving a gap behind
+	grown := makeChunk(0, 0, 20000)
+	chunks[[2]int{0, 0}] = grown
+	assert.NoError(t, r.WriteChunk(0, 0, &grown))
+	assert.NoError(t, r.DeleteChunk(2, 0))
+	delete(chunks, [2]int{2, 0})
+	assert.False(t, r.HasChunk(2, 0))
+
+	before, err := r.Size()
+	assert.NoError(t, err)
+	assert.Zero(t, before%SectorSize)
+
+	// Freed sectors are reused before the file grows
+	small := makeChunk(5, 0, 10)
+	chunks[[2]int{5, 0}] = small
+	assert.NoError(t, r.WriteChunk(5, 0, &small))
+	size, err := r.Size()
+	assert.NoError(t, err)
+	assert.Equal(t, before, size)
+
+	assert.NoError(t, r.Compact())
+	after, err := r.Size()
+	assert.NoError(t, err)
+	assert.Less(t, after, before)
+	assert.Zero(t, after%SectorSize)
+
+	for pos, chunk := range chunks {
+		var decoded testChunk
+		assert.NoError(t, r.ReadChunk(pos[0], pos[1], &decoded))
+		assert.Equal(t, chunk, decoded)
+	}
+
+	// Compaction leaves no gaps, so the next chunk goes on the end
+	another := makeChunk(6, 0, 10)
+	assert.NoError(t, r.WriteChunk(6, 0, &another))
+	size, err = r.Size()
+	assert.NoError(t, err)
+	assert.Equal(t, after+SectorSize, size)
+}
+
+func TestRegionTooLarge(t *testing.T) {
+	r, err := Open(filepath.Join(t.TempDir(), "r.0.0.mca"))
+	assert.NoError(t, err)
+	defer r.Close()
+
+	r.Compression = nbt.Uncompressed
+	chunk := makeChunk(0, 0, maxSectorCount*SectorSize)
+	assert.Error(t, r.WriteChunk(0, 0, &chunk))
+}
@@ -0,0 +1,490 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/rotisserie/eris"
+	"log"
+	"minecraftServer/mojang"
+	"minecraftServer/packet"
+	"minecraftServer/player"
+	"net"
+	"reflect"
+	"runtime/debug"
+	"time"
+)
+
+// internalErrorReason is shown to clients disconnected by something that isn't their fault, or that they don't
+// need the details of
+const internalErrorReason = "Internal server error"
+
+// Reasons vanilla gives for timeouts, a wrong KeepAlive counts as one too, and Bukkit for logging in too often
+const (
+	loginTimedOutReason = "Took too long to log in"
+	timedOutReason      = "Timed out"
+	throttledReason     = "Connection throttled! Please wait before reconnecting."
+)
+
+type (
+	// session is one client connection, moving from Handshaking to Status or Login and on to Play
+	session struct {
+		conn      *packet.Conn
+		state     player.State
+		handshake *packet.Handshake
+		// version is what the handshake asked for, or the latest version when it asked for one we don't speak
+		version *packet.Version
+		player  *player.Player
+		// addr is what logins and failures are throttled by, the forwarded client's IP behind a proxy
+		addr net.Addr
+		// forwarded is the identity a proxy forwarded in the handshake
+		forwarded *player.ForwardedInfo
+		// release gives back the forwarded client's connection slot, which main can't take for it
+		release func()
+		// stopKeepAlive is closed to stop sending KeepAlives once the session is over
+		stopKeepAlive chan struct{}
+	}
+
+	// handler deals with one serverbound payload in a state
+	handler func(s *session, payload interface{}) error
+
+	// handlerTable maps each payload type a state accepts to its handler
+	handlerTable map[reflect.Type]handler
+
+	// UnexpectedPacketError is returned for a payload the current state has no handler for
+	UnexpectedPacketError struct {
+		State player.State
+		Type  reflect.Type
+	}
+
+	// DisconnectError ends a session, telling the client why
+	DisconnectError struct {
+		Reason string
+		// Err is what went wrong, for the log, if there's more to it than Reason
+		Err error
+	}
+
+	// disconnecter is implemented by errors with a reason to show the client, like DisconnectError or
+	// packet.UnsupportedVersionError
+	disconnecter interface {
+		Disconnect() *packet.Disconnect
+	}
+)
+
+// errSessionDone is returned by handlers once the client has everything it wanted, like after a Pong
+var errSessionDone = eris.New("session done")
+
+var handlers map[player.State]handlerTable
+
+func init() {
+	// Set up here since the handlers refer back to the table through dispatch
+	handlers = map[player.State]handlerTable{
+		player.Handshaking: {
+			payloadType(packet.Handshake{}): (*session).handleHandshake,
+		},
+		player.Status: {
+			payloadType(packet.StatusRequest{}): (*session).handleStatusRequest,
+			payloadType(packet.Ping{}):          (*session).handlePing,
+		},
+		player.Login: {
+			payloadType(packet.LoginStart{}): (*session).handleLoginStart,
+		},
+		player.Play: {
+			payloadType(packet.KeepAliveResponse{}): (*session).handleKeepAliveResponse,
+		},
+	}
+}
+
+func newSession(conn *packet.Conn) *session {
+	return &session{
+		conn:    conn,
+		state:   player.Handshaking,
+		version: packet.LatestVersion(),
+		addr:    conn.RemoteAddr(),
+	}
+}
+
+// run serves the connection until it closes or fails, errors and panics only end this session
+func (s *session) run() {
+	defer s.close()
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("%v: panic: %v\n%s", s.conn.RemoteAddr(), r, debug.Stack())
+			s.disconnect(internalErrorReason)
+		}
+	}()
+
+	if err := s.conn.StartWriter(config.SendQueueSize, config.SendQueuePolicy, config.KeepAliveTimeout); err != nil {
+		s.fail(err)
+		return
+	}
+	if config.AuthMode == player.ProxyForwarding {
+		// The forwarded identity, skin included, comes appended to the handshake's address
+		s.conn.SetMaxServerAddressLength(packet.MaxStringLength)
+	}
+	s.setDeadline(config.HandshakeTimeout)
+	if legacy, extended, err := s.conn.ReadLegacyPing(); err != nil || legacy {
+		if legacy {
+			s.fail(s.conn.WriteLegacyPingResponse(serverStatus(s.version), extended))
+		}
+		return
+	}
+	for {
+		payload, err := s.conn.ReadPayload(s.state)
+		var unknown *packet.UnknownPacketError
+		if s.state == player.Play && errors.As(err, &unknown) {
+			// Play isn't implemented yet, so most of what the client sends has nowhere to go
+			continue
+		}
+		if err == nil {
+			err = s.dispatch(payload)
+		}
+		if errors.Is(err, errSessionDone) {
+			return
+		}
+		if err != nil {
+			s.fail(s.timedOut(err))
+			return
+		}
+	}
+}
+
+// dispatch passes a payload to the handler for it in the current state
+func (s *session) dispatch(payload interface{}) error {
+	typ := payloadType(payload)
+	handle, ok := handlers[s.state][typ]
+	if !ok {
+		return &UnexpectedPacketError{State: s.state, Type: typ}
+	}
+	return handle(s, payload)
+}
+
+// transition moves the session, and its player once there is one, to another state. The client gets a deadline to
+// log in by, and KeepAlives once they have
+func (s *session) transition(to player.State) error {
+	if err := player.Transition(s.state, to); err != nil {
+		return err
+	}
+	s.state = to
+	if s.player != nil {
+		s.player.State = to
+	}
+	switch to {
+	case player.Login:
+		s.setDeadline(config.LoginTimeout)
+	case player.Play:
+		// Only KeepAlives can time out from here, players can go quiet for as long as they like
+		if err := s.conn.SetReadDeadline(time.Time{}); err != nil {
+			return err
+		}
+		s.stopKeepAlive = make(chan struct{})
+		go s.keepAlive(s.player, config.KeepAliveInterval, config.KeepAliveTimeout, s.stopKeepAlive)
+	}
+	return nil
+}
+
+func (s *session) setDeadline(timeout time.Duration) {
+	if err := s.conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		log.Printf("%v: failed to set deadline: %v", s.conn.RemoteAddr(), err)
+	}
+}
+
+// keepAlive sends a KeepAlive every interval until stop is closed. Once one goes unanswered for longer than timeout it
+// cuts the read short, which the reading goroutine turns into a disconnect
+func (s *session) keepAlive(pl *player.Player, interval, timeout time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			if pl.KeepAlive.Expired(now, timeout) {
+				_ = s.conn.SetReadDeadline(now)
+				return
+			}
+			id, ok := pl.KeepAlive.Next(now)
+			if !ok {
+				continue
+			}
+			err := s.conn.SendDroppable(player.Play, &packet.KeepAlive{KeepAliveID: id})
+			if errors.Is(err, packet.ErrDropped) {
+				// The client is too far behind to answer on time, try again next tick
+				pl.KeepAlive.Cancel(id)
+				continue
+			}
+			if err != nil {
+				// The connection is going, the reader will find out
+				return
+			}
+		}
+	}
+}
+
+// timedOut turns a passed read deadline into a disconnect with the reason vanilla gives
+func (s *session) timedOut(err error) error {
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		return err
+	}
+	reason := timedOutReason
+	if s.state == player.Login {
+		reason = loginTimedOutReason
+	}
+	return &DisconnectError{Reason: reason, Err: err}
+}
+
+// fail logs an error and disconnects the client, giving them the reason when it's one they should see
+func (s *session) fail(err error) {
+	if err == nil || IsConnectionClosedErr(err) {
+		return
+	}
+	log.Printf("%v: %v", s.conn.RemoteAddr(), err)
+	// Until the handshake forwards a client IP addr is the proxy's, banning it would turn everyone away
+	unforwarded := config.AuthMode == player.ProxyForwarding && s.forwarded == nil
+	if s.state != player.Play && !unforwarded && isHandshakeFailure(err) && throttler.Fail(s.addr) {
+		log.Printf("%v: banned for %v after repeated handshake failures", s.addr, config.Throttle.BanDuration)
+	}
+	var reason disconnecter
+	if errors.As(err, &reason) {
+		s.sendDisconnect(reason.Disconnect())
+		return
+	}
+	s.disconnect(internalErrorReason)
+}
+
+// disconnect tells the client why it's being disconnected, in the states that have a packet for it
+func (s *session) disconnect(reason string) {
+	s.sendDisconnect(&packet.Disconnect{Reason: packet.TextComponent(reason).JSON()})
+}
+
+func (s *session) sendDisconnect(disconnect *packet.Disconnect) {
+	if s.state != player.Login && s.state != player.Play {
+		return
+	}
+	if err := s.conn.Send(s.state, disconnect); err != nil && !IsConnectionClosedErr(err) {
+		log.Printf("%v: failed to send Disconnect: %v", s.conn.RemoteAddr(), err)
+	}
+}
+
+func (s *session) close() {
+	if s.stopKeepAlive != nil {
+		close(s.stopKeepAlive)
+	}
+	if s.release != nil {
+		s.release()
+	}
+	if s.player != nil {
+		players.Remove(s.player)
+		stats := s.conn.QueueStats()
+		log.Printf("%v: %v left, %v packets sent and %v dropped", s.conn.RemoteAddr(), s.player.Username, stats.Written, stats.Dropped)
+	}
+	_ = s.conn.Close()
+}
+
+func (s *session) handleHandshake(payload interface{}) error {
+	handshake := payload.(*packet.Handshake)
+	next, err := player.StateFromVarInt(packet.VarInt(handshake.NextState))
+	if err != nil {
+		return err
+	}
+	if err = s.transition(next); err != nil {
+		return err
+	}
+	s.handshake = handshake
+	if next == player.Login {
+		if config.AuthMode == player.ProxyForwarding {
+			if err = s.forwardedClient(); err != nil {
+				return err
+			}
+		}
+		if err = throttler.Login(s.addr); err != nil {
+			return &DisconnectError{Reason: throttledReason, Err: err}
+		}
+	}
+
+	version, err := packet.LookupVersion(handshake.ProtocolVersion)
+	if err != nil {
+		// The server list shows the client which version to use, Login has to be told
+		if next == player.Login {
+			return err
+		}
+		return nil
+	}
+	s.version = version
+	s.conn.SetRegistry(version.Registry)
+	return nil
+}
+
+// forwardedClient takes the player's identity from the handshake of a proxy in forwarding mode. Every connection comes
+// from the proxy, so the client's IP is throttled instead
+func (s *session) forwardedClient() error {
+	info, err := player.ParseForwardedAddress(s.handshake.ServerAddress)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(info.ClientIP)
+	if ip == nil {
+		return eris.Errorf("invalid forwarded client IP '%v'", info.ClientIP)
+	}
+	s.forwarded = info
+	s.addr = &net.IPAddr{IP: ip}
+	if s.release, err = throttler.Accept(s.addr); err != nil {
+		return &DisconnectError{Reason: throttledReason, Err: err}
+	}
+	return nil
+}
+
+func (s *session) handleStatusRequest(interface{}) error {
+	response, err := serverStatus(s.version).StatusResponse()
+	if err != nil {
+		return err
+	}
+	// The server list asks again, a client too far behind to take it can go without
+	if err = s.conn.SendDroppable(player.Status, response); err != nil && !errors.Is(err, packet.ErrDropped) {
+		return err
+	}
+	return nil
+}
+
+func (s *session) handlePing(payload interface{}) error {
+	err := s.conn.SendDroppable(player.Status, &packet.Pong{Payload: payload.(*packet.Ping).Payload})
+	if err != nil && !errors.Is(err, packet.ErrDropped) {
+		return err
+	}
+	// The client closes the connection once it has the Pong
+	return errSessionDone
+}
+
+// handleLoginStart runs the rest of Login: authentication for the configured auth mode, compression and
+// LoginSuccess
+func (s *session) handleLoginStart(payload interface{}) error {
+	username := payload.(*packet.LoginStart).Name
+	pl := player.NewPlayer(s.conn)
+	pl.State = s.state
+	pl.ProtocolVersion = uint16(s.handshake.ProtocolVersion)
+	pl.Username = username
+
+	switch config.AuthMode {
+	case player.Offline:
+		pl.UUID = player.OfflineUUID(username)
+	case player.Online:
+		if err := authenticate(pl); err != nil {
+			return err
+		}
+	case player.ProxyForwarding:
+		pl.UUID = s.forwarded.UUID
+		pl.Properties = s.forwarded.Properties
+	}
+
+	if !players.Add(pl) {
+		return &DisconnectError{Reason: fmt.Sprintf("%v is already logged in", pl.Username)}
+	}
+	// From here close removes them again
+	s.player = pl
+	if config.CompressionThreshold >= 0 {
+		if err := pl.EnableCompression(config.CompressionThreshold); err != nil {
+			return err
+		}
+	}
+	loginSuccess := &packet.LoginSuccess{
+		UUID:     pl.UUID,
+		Username: pl.Username,
+	}
+	if err := s.conn.Send(player.Login, loginSuccess); err != nil {
+		return eris.Wrap(err, "failed to send LoginSuccess")
+	}
+	if err := s.transition(player.Play); err != nil {
+		return err
+	}
+	log.Printf("%v: %v logged in as %v", s.conn.RemoteAddr(), pl.Username, pl.UUID)
+	return nil
+}
+
+func (s *session) handleKeepAliveResponse(payload interface{}) error {
+	roundTrip, err := s.player.KeepAlive.Answer(payload.(*packet.KeepAliveResponse).KeepAliveID, time.Now())
+	if err != nil {
+		return &DisconnectError{Reason: timedOutReason, Err: err}
+	}
+	s.player.RecordLatency(roundTrip)
+	return nil
+}
+
+// authenticate encrypts the connection and checks the player's session with Mojang, taking their UUID, name and
+// skin from the profile it returns
+func authenticate(pl *player.Player) error {
+	conn := pl.Conn()
+	verifyToken, err := packet.NewVerifyToken()
+	if err != nil {
+		return err
+	}
+	if err = conn.Send(player.Login, serverKey.EncryptionRequest("", verifyToken)); err != nil {
+		return eris.Wrap(err, "failed to send EncryptionRequest")
+	}
+	payload, err := conn.ReadPayload(player.Login)
+	if err != nil {
+		return err
+	}
+	response, ok := payload.(*packet.EncryptionResponse)
+	if !ok {
+		return &UnexpectedPacketError{State: player.Login, Type: payloadType(payload)}
+	}
+	sharedSecret, err := serverKey.DecryptResponse(response, verifyToken)
+	if err != nil {
+		return err
+	}
+	if err = conn.EnableEncryption(sharedSecret); err != nil {
+		return err
+	}
+
+	profile, err := sessionVerifier.HasJoined(pl.Username, mojang.ServerHash("", sharedSecret, serverKey.PublicKey()), "")
+	if errors.Is(err, mojang.ErrNotAuthenticated) {
+		return &DisconnectError{Reason: "Failed to verify username!"}
+	}
+	if err != nil {
+		return eris.Wrapf(err, "failed to verify session for '%v'", pl.Username)
+	}
+	if pl.UUID, err = profile.UUID(); err != nil {
+		return err
+	}
+	pl.Username = profile.Name
+	pl.Properties = profile.Properties
+	return nil
+}
+
+func (e *UnexpectedPacketError) Error() string {
+	return fmt.Sprintf("unexpected %v in %v", e.Type, e.State)
+}
+
+func (e *DisconnectError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("disconnecting with '%v': %v", e.Reason, e.Err)
+	}
+	return "disconnecting: " + e.Reason
+}
+
+func (e *DisconnectError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DisconnectError) Disconnect() *packet.Disconnect {
+	return &packet.Disconnect{Reason: packet.TextComponent(e.Reason).JSON()}
+}
+
+// isHandshakeFailure is true for errors that count towards a ban, which only a misbehaving client causes. Timeouts,
+// failed session checks and clients that just go away aren't the client's doing, or aren't worth banning for
+func isHandshakeFailure(err error) bool {
+	var limit *packet.LimitError
+	var nextState *packet.InvalidNextStateError
+	var transition *player.InvalidTransitionError
+	var decode *packet.DecodeError
+	return errors.As(err, &limit) || errors.As(err, &nextState) || errors.As(err, &transition) ||
+		errors.As(err, &decode) || errors.Is(err, packet.ErrVerifyTokenMismatch)
+}
+
+func payloadType(payload interface{}) reflect.Type {
+	typ := reflect.TypeOf(payload)
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
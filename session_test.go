@@ -0,0 +1,438 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"github.com/rotisserie/eris"
+	"github.com/stretchr/testify/assert"
+	"minecraftServer/mojang"
+	"minecraftServer/packet"
+	"minecraftServer/player"
+	"minecraftServer/throttle"
+	"net"
+	"testing"
+	"time"
+)
+
+// startSession serves one end of a pipe with an offline mode config, returning the client's end and a channel
+// closed once the session is over
+func startSession(t *testing.T) (*packet.Conn, <-chan struct{}) {
+	return startSessionWith(t, func(*Config) {})
+}
+
+// startSessionWith is startSession with some of the config changed
+func startSessionWith(t *testing.T, configure func(*Config)) (*packet.Conn, <-chan struct{}) {
+	config = &Config{
+		AuthMode:             player.Offline,
+		CompressionThreshold: -1,
+		MaxPlayers:           20,
+		SendQueueSize:        16,
+		SendQueuePolicy:      packet.BlockWhenFull,
+		HandshakeTimeout:     time.Second,
+		LoginTimeout:         time.Second,
+		KeepAliveInterval:    time.Second,
+		KeepAliveTimeout:     time.Second,
+	}
+	configure(config)
+	throttler = throttle.New(config.Throttle)
+	players = player.NewList()
+	server, client := net.Pipe()
+	t.Cleanup(func() { _ = client.Close() })
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		newSession(packet.NewConn(server)).run()
+	}()
+	return packet.NewConn(client), done
+}
+
+func handshake(protocol int32, next player.State) *packet.Handshake {
+	return &packet.Handshake{ProtocolVersion: protocol, ServerAddress: "localhost", ServerPort: 25565, NextState: int32(next)}
+}
+
+func readClientbound(t *testing.T, conn *packet.Conn, state player.State) interface{} {
+	pkt, err := conn.ReadPacket()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	payload, err := packet.Decode(state, packet.Clientbound, pkt)
+	assert.NoError(t, err)
+	return payload
+}
+
+func TestSessionStatus(t *testing.T) {
+	client, done := startSession(t)
+	assert.NoError(t, client.Send(player.Handshaking, handshake(packet.Version1_16_5.Protocol, player.Status)))
+	assert.NoError(t, client.Send(player.Status, &packet.StatusRequest{}))
+	assert.IsType(t, &packet.StatusResponse{}, readClientbound(t, client, player.Status))
+	assert.NoError(t, client.Send(player.Status, &packet.Ping{Payload: 42}))
+	assert.Equal(t, &packet.Pong{Payload: 42}, readClientbound(t, client, player.Status))
+	<-done
+}
+
+func TestSessionLogin(t *testing.T) {
+	client, done := startSession(t)
+	assert.NoError(t, client.Send(player.Handshaking, handshake(packet.Version1_16_5.Protocol, player.Login)))
+	assert.NoError(t, client.Send(player.Login, &packet.LoginStart{Name: "Steve"}))
+	success := readClientbound(t, client, player.Login).(*packet.LoginSuccess)
+	assert.Equal(t, player.OfflineUUID("Steve"), success.UUID)
+	assert.Equal(t, 1, players.Count())
+
+	assert.NoError(t, client.Close())
+	<-done
+	assert.Equal(t, 0, players.Count())
+}
+
+func TestSessionDisconnects(t *testing.T) {
+	tests := []struct {
+		name   string
+		send   func(client *packet.Conn) error
+		reason string
+	}{
+		{
+			name: "unsupported version",
+			send: func(client *packet.Conn) error {
+				return client.Send(player.Handshaking, handshake(47, player.Login))
+			},
+			reason: (&packet.UnsupportedVersionError{}).Disconnect().Reason,
+		},
+		{
+			name: "username too long",
+			send: func(client *packet.Conn) error {
+				if err := client.Send(player.Handshaking, handshake(packet.Version1_16_5.Protocol, player.Login)); err != nil {
+					return err
+				}
+				return client.Send(player.Login, &packet.LoginStart{Name: "SeventeenLetters_"})
+			},
+			reason: (&packet.LimitError{Field: "LoginStart.Name", Length: 17, Max: packet.MaxUsernameLength}).Disconnect().Reason,
+		},
+		{
+			name: "unexpected packet",
+			send: func(client *packet.Conn) error {
+				if err := client.Send(player.Handshaking, handshake(packet.Version1_16_5.Protocol, player.Login)); err != nil {
+					return err
+				}
+				// EncryptionResponse only makes sense after an EncryptionRequest
+				return client.Send(player.Login, &packet.EncryptionResponse{})
+			},
+			reason: packet.TextComponent(internalErrorReason).JSON(),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, done := startSession(t)
+			assert.NoError(t, test.send(client))
+			assert.Equal(t, &packet.Disconnect{Reason: test.reason}, readClientbound(t, client, player.Login))
+			<-done
+		})
+	}
+}
+
+func TestSessionDuplicateLogin(t *testing.T) {
+	client, done := startSession(t)
+	existing := player.NewPlayer(nil)
+	existing.Username = "Steve"
+	existing.UUID = player.OfflineUUID("Steve")
+	players.Add(existing)
+
+	assert.NoError(t, client.Send(player.Handshaking, handshake(packet.Version1_16_5.Protocol, player.Login)))
+	assert.NoError(t, client.Send(player.Login, &packet.LoginStart{Name: "Steve"}))
+	disconnect := readClientbound(t, client, player.Login).(*packet.Disconnect)
+	assert.Equal(t, packet.TextComponent("Steve is already logged in").JSON(), disconnect.Reason)
+	<-done
+	// The session never owned the existing player, so it stays online
+	assert.Equal(t, 1, players.Count())
+}
+
+func TestSessionRejectsInvalidTransition(t *testing.T) {
+	client, done := startSession(t)
+	assert.NoError(t, client.Send(player.Handshaking, handshake(packet.Version1_16_5.Protocol, player.Play)))
+	// Nothing is sent in Handshaking, the connection is just closed
+	_, err := client.ReadPacket()
+	assert.Error(t, err)
+	<-done
+}
+
+func TestSessionRecoversPanics(t *testing.T) {
+	handlers[player.Login][payloadType(packet.LoginStart{})] = func(*session, interface{}) error {
+		panic("handler bug")
+	}
+	defer func() {
+		handlers[player.Login][payloadType(packet.LoginStart{})] = (*session).handleLoginStart
+	}()
+
+	client, done := startSession(t)
+	assert.NoError(t, client.Send(player.Handshaking, handshake(packet.Version1_16_5.Protocol, player.Login)))
+	assert.NoError(t, client.Send(player.Login, &packet.LoginStart{Name: "Steve"}))
+	disconnect := readClientbound(t, client, player.Login).(*packet.Disconnect)
+	assert.Equal(t, packet.TextComponent(internalErrorReason).JSON(), disconnect.Reason)
+	<-done
+}
+
+func TestSessionTimeouts(t *testing.T) {
+	short := func(cfg *Config) {
+		cfg.HandshakeTimeout = 20 * time.Millisecond
+		cfg.LoginTimeout = 20 * time.Millisecond
+	}
+
+	t.Run("handshake", func(t *testing.T) {
+		client, done := startSessionWith(t, short)
+		// There's no Disconnect in Handshaking, the connection is just closed
+		_, err := client.ReadPacket()
+		assert.Error(t, err)
+		<-done
+	})
+
+	t.Run("login", func(t *testing.T) {
+		client, done := startSessionWith(t, short)
+		assert.NoError(t, client.Send(player.Handshaking, handshake(packet.Version1_16_5.Protocol, player.Login)))
+		disconnect := readClientbound(t, client, player.Login).(*packet.Disconnect)
+		assert.Equal(t, packet.TextComponent(loginTimedOutReason).JSON(), disconnect.Reason)
+		<-done
+	})
+}
+
+// login plays a client through to Play, returning the player the session made
+func login(t *testing.T, client *packet.Conn) *player.Player {
+	assert.NoError(t, client.Send(player.Handshaking, handshake(packet.Version1_16_5.Protocol, player.Login)))
+	assert.NoError(t, client.Send(player.Login, &packet.LoginStart{Name: "Steve"}))
+	assert.IsType(t, &packet.LoginSuccess{}, readClientbound(t, client, player.Login))
+	pl := players.Sample(1)
+	if !assert.Len(t, pl, 1) {
+		t.FailNow()
+	}
+	return pl[0]
+}
+
+func TestSessionKeepAlive(t *testing.T) {
+	frequent := func(cfg *Config) {
+		cfg.KeepAliveInterval = 5 * time.Millisecond
+		cfg.KeepAliveTimeout = 20 * time.Millisecond
+	}
+
+	t.Run("answered", func(t *testing.T) {
+		client, done := startSessionWith(t, frequent)
+		pl := login(t, client)
+		for i := 0; i < 3; i++ {
+			keepAlive := readClientbound(t, client, player.Play).(*packet.KeepAlive)
+			assert.NoError(t, client.Send(player.Play, &packet.KeepAliveResponse{KeepAliveID: keepAlive.KeepAliveID}))
+		}
+		assert.Eventually(t, func() bool { return pl.Latency() > 0 }, time.Second, time.Millisecond)
+		assert.NoError(t, client.Close())
+		<-done
+	})
+
+	t.Run("wrong ID", func(t *testing.T) {
+		client, done := startSessionWith(t, frequent)
+		login(t, client)
+		keepAlive := readClientbound(t, client, player.Play).(*packet.KeepAlive)
+		assert.NoError(t, client.Send(player.Play, &packet.KeepAliveResponse{KeepAliveID: keepAlive.KeepAliveID + 1}))
+		disconnect := readClientbound(t, client, player.Play).(*packet.Disconnect)
+		assert.Equal(t, packet.TextComponent(timedOutReason).JSON(), disconnect.Reason)
+		<-done
+	})
+
+	t.Run("unanswered", func(t *testing.T) {
+		client, done := startSessionWith(t, frequent)
+		login(t, client)
+		assert.IsType(t, &packet.KeepAlive{}, readClientbound(t, client, player.Play))
+		disconnect := readClientbound(t, client, player.Play).(*packet.Disconnect)
+		assert.Equal(t, packet.TextComponent(timedOutReason).JSON(), disconnect.Reason)
+		<-done
+		assert.Equal(t, 0, players.Count())
+	})
+}
+
+func TestSessionThrottle(t *testing.T) {
+	t.Run("login rate", func(t *testing.T) {
+		client, done := startSessionWith(t, func(cfg *Config) {
+			cfg.Throttle = throttle.Config{LoginRate: 1, LoginBurst: 1}
+		})
+		// The only login the pipe has saved up goes before the session's
+		assert.NoError(t, throttler.Login(client.RemoteAddr()))
+		assert.NoError(t, client.Send(player.Handshaking, handshake(packet.Version1_16_5.Protocol, player.Login)))
+		disconnect := readClientbound(t, client, player.Login).(*packet.Disconnect)
+		assert.Equal(t, packet.TextComponent(throttledReason).JSON(), disconnect.Reason)
+		<-done
+	})
+
+	t.Run("ban", func(t *testing.T) {
+		client, done := startSessionWith(t, func(cfg *Config) {
+			cfg.Throttle = throttle.Config{FailureLimit: 1, FailureWindow: time.Minute, BanDuration: time.Minute}
+		})
+		assert.NoError(t, client.Send(player.Handshaking, handshake(packet.Version1_16_5.Protocol, player.Play)))
+		<-done
+		_, err := throttler.Accept(client.RemoteAddr())
+		var banned *throttle.BannedError
+		assert.True(t, errors.As(err, &banned))
+	})
+
+	t.Run("unsupported versions aren't failures", func(t *testing.T) {
+		client, done := startSessionWith(t, func(cfg *Config) {
+			cfg.Throttle = throttle.Config{FailureLimit: 1, FailureWindow: time.Minute, BanDuration: time.Minute}
+		})
+		assert.NoError(t, client.Send(player.Handshaking, handshake(47, player.Login)))
+		assert.IsType(t, &packet.Disconnect{}, readClientbound(t, client, player.Login))
+		<-done
+		_, err := throttler.Accept(client.RemoteAddr())
+		assert.NoError(t, err)
+	})
+}
+
+// failingVerifier stands in for a session server that's down
+type failingVerifier struct{}
+
+func (failingVerifier) HasJoined(string, string, string) (*mojang.Profile, error) {
+	return nil, eris.New("session server unavailable")
+}
+
+// encryptionResponse answers an EncryptionRequest the way the vanilla client does, with the given verify token
+func encryptionResponse(t *testing.T, request *packet.EncryptionRequest, sharedSecret, verifyToken []byte) *packet.EncryptionResponse {
+	parsed, err := x509.ParsePKIXPublicKey(request.PublicKey)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	publicKey := parsed.(*rsa.PublicKey)
+	encryptedSecret, err := rsa.EncryptPKCS1v15(rand.Reader, publicKey, sharedSecret)
+	assert.NoError(t, err)
+	encryptedToken, err := rsa.EncryptPKCS1v15(rand.Reader, publicKey, verifyToken)
+	assert.NoError(t, err)
+	return &packet.EncryptionResponse{
+		SharedSecretLength: int32(len(encryptedSecret)),
+		SharedSecret:       encryptedSecret,
+		VerifyTokenLength:  int32(len(encryptedToken)),
+		VerifyToken:        encryptedToken,
+	}
+}
+
+func TestSessionThrottleOnlyCountsClientFailures(t *testing.T) {
+	key, err := packet.GenerateServerKey()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer func(previous *packet.ServerKey, verifier mojang.SessionVerifier) {
+		serverKey, sessionVerifier = previous, verifier
+	}(serverKey, sessionVerifier)
+	serverKey, sessionVerifier = key, failingVerifier{}
+
+	tests := []struct {
+		name string
+		// tamper sends the wrong verify token
+		tamper bool
+		banned bool
+	}{
+		{name: "session server down", tamper: false, banned: false},
+		{name: "verify token mismatch", tamper: true, banned: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, done := startSessionWith(t, func(cfg *Config) {
+				cfg.AuthMode = player.Online
+				cfg.Throttle = throttle.Config{FailureLimit: 1, FailureWindow: time.Minute, BanDuration: time.Minute}
+			})
+			assert.NoError(t, client.Send(player.Handshaking, handshake(packet.Version1_16_5.Protocol, player.Login)))
+			assert.NoError(t, client.Send(player.Login, &packet.LoginStart{Name: "Steve"}))
+			request := readClientbound(t, client, player.Login).(*packet.EncryptionRequest)
+
+			sharedSecret := make([]byte, packet.SharedSecretLength)
+			verifyToken := request.VerifyToken
+			if test.tamper {
+				verifyToken = []byte{0, 0, 0, 0}
+			}
+			assert.NoError(t, client.Send(player.Login, encryptionResponse(t, request, sharedSecret, verifyToken)))
+			if !test.tamper {
+				assert.NoError(t, client.EnableEncryption(sharedSecret))
+			}
+			assert.IsType(t, &packet.Disconnect{}, readClientbound(t, client, player.Login))
+			<-done
+
+			_, err := throttler.Accept(client.RemoteAddr())
+			var banned *throttle.BannedError
+			assert.Equal(t, test.banned, errors.As(err, &banned), "%v", err)
+		})
+	}
+}
+
+func TestSessionThrottlesForwardedClients(t *testing.T) {
+	forwarded := func(clientIP string) *packet.Handshake {
+		shake := handshake(packet.Version1_16_5.Protocol, player.Login)
+		shake.ServerAddress = "localhost\x00" + clientIP + "\x00" + player.OfflineUUID("Steve").String()
+		return shake
+	}
+	proxied := func(cfg *Config) {
+		cfg.AuthMode = player.ProxyForwarding
+		cfg.Throttle = throttle.Config{LoginRate: 1, LoginBurst: 1, FailureLimit: 1, FailureWindow: time.Minute, BanDuration: time.Minute}
+	}
+
+	t.Run("login rate", func(t *testing.T) {
+		client, done := startSessionWith(t, proxied)
+		// The proxy's own bucket is empty, the client's isn't
+		assert.NoError(t, throttler.Login(client.RemoteAddr()))
+		assert.NoError(t, client.Send(player.Handshaking, forwarded("203.0.113.1")))
+		assert.NoError(t, client.Send(player.Login, &packet.LoginStart{Name: "Steve"}))
+		assert.IsType(t, &packet.LoginSuccess{}, readClientbound(t, client, player.Login))
+		assert.Error(t, throttler.Login(&net.IPAddr{IP: net.ParseIP("203.0.113.1")}))
+		assert.NoError(t, client.Close())
+		<-done
+	})
+
+	t.Run("ban", func(t *testing.T) {
+		client, done := startSessionWith(t, proxied)
+		assert.NoError(t, client.Send(player.Handshaking, forwarded("203.0.113.1")))
+		assert.NoError(t, client.Send(player.Login, &packet.LoginStart{Name: "SeventeenLetters_"}))
+		assert.IsType(t, &packet.Disconnect{}, readClientbound(t, client, player.Login))
+		<-done
+
+		var banned *throttle.BannedError
+		assert.True(t, errors.As(throttler.Login(&net.IPAddr{IP: net.ParseIP("203.0.113.1")}), &banned))
+		assert.NoError(t, throttler.Login(client.RemoteAddr()), "the proxy isn't banned for its client")
+	})
+
+	t.Run("connections", func(t *testing.T) {
+		limited := func(cfg *Config) {
+			proxied(cfg)
+			cfg.Throttle.MaxConnectionsPerIP = 1
+		}
+		client, done := startSessionWith(t, limited)
+		clientAddr := &net.IPAddr{IP: net.ParseIP("203.0.113.1")}
+		release, err := throttler.Accept(clientAddr)
+		assert.NoError(t, err)
+		assert.NoError(t, client.Send(player.Handshaking, forwarded("203.0.113.1")))
+		disconnect := readClientbound(t, client, player.Login).(*packet.Disconnect)
+		assert.Equal(t, packet.TextComponent(throttledReason).JSON(), disconnect.Reason)
+		<-done
+
+		release()
+
+		// The slot a session takes is given back when it ends
+		client, done = startSessionWith(t, limited)
+		assert.NoError(t, client.Send(player.Handshaking, forwarded("203.0.113.1")))
+		assert.NoError(t, client.Send(player.Login, &packet.LoginStart{Name: "Steve"}))
+		assert.IsType(t, &packet.LoginSuccess{}, readClientbound(t, client, player.Login))
+		_, err = throttler.Accept(clientAddr)
+		assert.Error(t, err)
+		assert.NoError(t, client.Close())
+		<-done
+		release, err = throttler.Accept(clientAddr)
+		assert.NoError(t, err)
+		release()
+	})
+
+	t.Run("failure before forwarding", func(t *testing.T) {
+		client, done := startSessionWith(t, proxied)
+		assert.NoError(t, client.Send(player.Handshaking, &packet.Handshake{ProtocolVersion: packet.Version1_16_5.Protocol, NextState: 3}))
+		<-done
+		assert.NoError(t, throttler.Login(client.RemoteAddr()), "the proxy isn't banned for a bad handshake")
+	})
+}
+
+func TestSessionVerifierTimesOut(t *testing.T) {
+	// Logins wait on the session server outside the connection's deadlines, so the client has to give up by itself
+	client, ok := sessionVerifier.(*mojang.ApiClient)
+	if assert.True(t, ok) {
+		assert.Equal(t, mojang.DefaultTimeout, client.Client.Timeout)
+	}
+}
@@ -0,0 +1,201 @@
+package throttle
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often hosts with nothing left to track are forgotten
+const sweepInterval = time.Minute
+
+type (
+	// Config sets the limits of a Throttle, a zero limit turns that check off
+	Config struct {
+		// MaxConnectionsPerIP caps how many connections an address can hold open at once
+		MaxConnectionsPerIP int
+		// LoginRate is how many logins per second an address earns, it can save up LoginBurst of them
+		LoginRate  float64
+		LoginBurst int
+		// FailureLimit handshake failures within FailureWindow ban an address for BanDuration
+		FailureLimit  int
+		FailureWindow time.Duration
+		BanDuration   time.Duration
+	}
+
+	// Throttle turns away addresses that connect too often, hold too many connections or keep failing their
+	// handshake
+	Throttle struct {
+		config Config
+		now    func() time.Time
+
+		mu        sync.Mutex
+		hosts     map[string]*host
+		lastSweep time.Time
+	}
+
+	host struct {
+		connections int
+		// tokens is what was left in the login bucket at refilled
+		tokens      float64
+		refilled    time.Time
+		failures    []time.Time
+		bannedUntil time.Time
+	}
+
+	// BannedError is returned for an address banned after too many handshake failures
+	BannedError struct {
+		Until time.Time
+	}
+
+	// TooManyConnectionsError is returned for an address already holding the most connections it can
+	TooManyConnectionsError struct {
+		Limit int
+	}
+
+	// RateLimitedError is returned for a login attempted before the address has earned one
+	RateLimitedError struct {
+		RetryAfter time.Duration
+	}
+)
+
+func New(config Config) *Throttle {
+	return &Throttle{
+		config: config,
+		now:    time.Now,
+		hosts:  map[string]*host{},
+	}
+}
+
+// Accept checks a new connection, the release it returns has to be called once the connection is closed
+func (t *Throttle) Accept(addr net.Addr) (release func(), err error) {
+	ip := hostOf(addr)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	h := t.host(ip, now)
+	if now.Before(h.bannedUntil) {
+		return nil, &BannedError{Until: h.bannedUntil}
+	}
+	if t.config.MaxConnectionsPerIP > 0 && h.connections >= t.config.MaxConnectionsPerIP {
+		return nil, &TooManyConnectionsError{Limit: t.config.MaxConnectionsPerIP}
+	}
+	h.connections++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			h.connections--
+		})
+	}, nil
+}
+
+// Login takes a login from the address's bucket. Banned addresses don't get one, for callers that don't Accept every
+// connection themselves
+func (t *Throttle) Login(addr net.Addr) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	h := t.host(hostOf(addr), now)
+	if now.Before(h.bannedUntil) {
+		return &BannedError{Until: h.bannedUntil}
+	}
+	if t.config.LoginRate <= 0 {
+		return nil
+	}
+	h.refill(now, t.config)
+	if h.tokens < 1 {
+		wait := (1 - h.tokens) / t.config.LoginRate
+		return &RateLimitedError{RetryAfter: time.Duration(wait * float64(time.Second))}
+	}
+	h.tokens--
+	return nil
+}
+
+// Fail records a failed handshake, returning true if it got the address banned
+func (t *Throttle) Fail(addr net.Addr) bool {
+	if t.config.FailureLimit <= 0 {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	h := t.host(hostOf(addr), now)
+	h.forgetFailures(now, t.config.FailureWindow)
+	h.failures = append(h.failures, now)
+	if len(h.failures) < t.config.FailureLimit {
+		return false
+	}
+	h.failures = nil
+	h.bannedUntil = now.Add(t.config.BanDuration)
+	return true
+}
+
+// host finds or starts tracking an address, which starts with a full login bucket. Every lookup can sweep, callers
+// that skip Accept would otherwise never forget anyone
+func (t *Throttle) host(ip string, now time.Time) *host {
+	if now.Sub(t.lastSweep) >= sweepInterval {
+		t.sweep(now)
+	}
+	h, ok := t.hosts[ip]
+	if !ok {
+		h = &host{tokens: float64(t.config.LoginBurst), refilled: now}
+		t.hosts[ip] = h
+	}
+	return h
+}
+
+// sweep forgets hosts that would be tracked from scratch the same way
+func (t *Throttle) sweep(now time.Time) {
+	t.lastSweep = now
+	for ip, h := range t.hosts {
+		h.refill(now, t.config)
+		h.forgetFailures(now, t.config.FailureWindow)
+		if h.connections == 0 && len(h.failures) == 0 && !now.Before(h.bannedUntil) &&
+			(t.config.LoginRate <= 0 || h.tokens >= float64(t.config.LoginBurst)) {
+			delete(t.hosts, ip)
+		}
+	}
+}
+
+func (h *host) refill(now time.Time, config Config) {
+	h.tokens += now.Sub(h.refilled).Seconds() * config.LoginRate
+	if burst := float64(config.LoginBurst); h.tokens > burst {
+		h.tokens = burst
+	}
+	h.refilled = now
+}
+
+func (h *host) forgetFailures(now time.Time, window time.Duration) {
+	recent := h.failures[:0]
+	for _, failure := range h.failures {
+		if now.Sub(failure) < window {
+			recent = append(recent, failure)
+		}
+	}
+	h.failures = recent
+}
+
+// hostOf drops the port, every connection from an IP counts against it
+func hostOf(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+func (e *BannedError) Error() string {
+	return fmt.Sprintf("banned until %v for failing too many handshakes", e.Until.Format(time.RFC3339))
+}
+
+func (e *TooManyConnectionsError) Error() string {
+	return fmt.Sprintf("already holding %v connections", e.Limit)
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("logging in too often, retry in %v", e.RetryAfter.Round(time.Millisecond))
+}
@@ -0,0 +1,166 @@
+package throttle
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestThrottle(config Config) (*Throttle, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1600000000, 0)}
+	t := New(config)
+	t.now = clock.Now
+	return t, clock
+}
+
+func addr(ip string, port int) net.Addr {
+	return &net.TCPAddr{IP: net.ParseIP(ip), Port: port}
+}
+
+func TestAcceptCapsConnectionsPerIP(t *testing.T) {
+	throttle, _ := newTestThrottle(Config{MaxConnectionsPerIP: 2})
+	first, err := throttle.Accept(addr("10.0.0.1", 1000))
+	assert.NoError(t, err)
+	_, err = throttle.Accept(addr("10.0.0.1", 1001))
+	assert.NoError(t, err)
+
+	_, err = throttle.Accept(addr("10.0.0.1", 1002))
+	var tooMany *TooManyConnectionsError
+	assert.True(t, errors.As(err, &tooMany))
+	assert.Equal(t, 2, tooMany.Limit)
+	_, err = throttle.Accept(addr("10.0.0.2", 1000))
+	assert.NoError(t, err, "other addresses have their own cap")
+
+	first()
+	first()
+	_, err = throttle.Accept(addr("10.0.0.1", 1003))
+	assert.NoError(t, err)
+	_, err = throttle.Accept(addr("10.0.0.1", 1004))
+	assert.Error(t, err, "releasing twice only frees one connection")
+}
+
+func TestLoginRate(t *testing.T) {
+	throttle, clock := newTestThrottle(Config{LoginRate: 0.5, LoginBurst: 2})
+	client := addr("10.0.0.1", 1000)
+	assert.NoError(t, throttle.Login(client))
+	assert.NoError(t, throttle.Login(client))
+
+	err := throttle.Login(client)
+	var limited *RateLimitedError
+	assert.True(t, errors.As(err, &limited))
+	assert.Equal(t, 2*time.Second, limited.RetryAfter)
+	assert.NoError(t, throttle.Login(addr("10.0.0.2", 1000)))
+
+	clock.Advance(time.Second)
+	assert.True(t, errors.As(throttle.Login(client), &limited))
+	assert.Equal(t, time.Second, limited.RetryAfter)
+	clock.Advance(time.Second)
+	assert.NoError(t, throttle.Login(client))
+
+	// Saving up stops at the burst
+	clock.Advance(time.Hour)
+	assert.NoError(t, throttle.Login(client))
+	assert.NoError(t, throttle.Login(client))
+	assert.Error(t, throttle.Login(client))
+}
+
+func TestFailuresBan(t *testing.T) {
+	throttle, clock := newTestThrottle(Config{FailureLimit: 3, FailureWindow: time.Minute, BanDuration: 10 * time.Minute})
+	client := addr("10.0.0.1", 1000)
+
+	assert.False(t, throttle.Fail(client))
+	clock.Advance(30 * time.Second)
+	assert.False(t, throttle.Fail(client))
+	// The first failure falls out of the window
+	clock.Advance(30 * time.Second)
+	assert.False(t, throttle.Fail(client))
+	_, err := throttle.Accept(client)
+	assert.NoError(t, err)
+
+	assert.True(t, throttle.Fail(client))
+	_, err = throttle.Accept(addr("10.0.0.1", 1001))
+	var banned *BannedError
+	assert.True(t, errors.As(err, &banned))
+	assert.Equal(t, clock.Now().Add(10*time.Minute), banned.Until)
+	assert.True(t, errors.As(throttle.Login(client), &banned), "banned addresses can't log in either")
+
+	clock.Advance(10 * time.Minute)
+	_, err = throttle.Accept(addr("10.0.0.1", 1002))
+	assert.NoError(t, err)
+	assert.False(t, throttle.Fail(client), "the ban starts the count again")
+}
+
+func TestDisabledLimits(t *testing.T) {
+	throttle, _ := newTestThrottle(Config{})
+	client := addr("10.0.0.1", 1000)
+	for i := 0; i < 100; i++ {
+		_, err := throttle.Accept(client)
+		assert.NoError(t, err)
+		assert.NoError(t, throttle.Login(client))
+		assert.False(t, throttle.Fail(client))
+	}
+}
+
+func TestSweepForgetsIdleHosts(t *testing.T) {
+	throttle, clock := newTestThrottle(Config{
+		MaxConnectionsPerIP: 1,
+		LoginRate:           1,
+		LoginBurst:          1,
+		FailureLimit:        2,
+		FailureWindow:       time.Minute,
+		BanDuration:         time.Hour,
+	})
+	idle := addr("10.0.0.1", 1000)
+	connected := addr("10.0.0.2", 1000)
+	banned := addr("10.0.0.3", 1000)
+
+	release, err := throttle.Accept(idle)
+	assert.NoError(t, err)
+	assert.NoError(t, throttle.Login(idle))
+	release()
+	_, err = throttle.Accept(connected)
+	assert.NoError(t, err)
+	throttle.Fail(banned)
+	throttle.Fail(banned)
+
+	clock.Advance(sweepInterval)
+	_, err = throttle.Accept(addr("10.0.0.4", 1000))
+	assert.NoError(t, err)
+	assert.NotContains(t, throttle.hosts, "10.0.0.1")
+	assert.Contains(t, throttle.hosts, "10.0.0.2")
+	assert.Contains(t, throttle.hosts, "10.0.0.3")
+}
+
+func TestSweepWithoutAccept(t *testing.T) {
+	throttle, clock := newTestThrottle(Config{LoginRate: 1, LoginBurst: 1, FailureLimit: 2, FailureWindow: time.Minute})
+	// Behind a proxy only Login and Fail see the client addresses
+	assert.NoError(t, throttle.Login(addr("10.0.0.1", 1000)))
+	throttle.Fail(addr("10.0.0.2", 1000))
+
+	clock.Advance(sweepInterval)
+	throttle.Fail(addr("10.0.0.3", 1000))
+	assert.NotContains(t, throttle.hosts, "10.0.0.1")
+	assert.NotContains(t, throttle.hosts, "10.0.0.2")
+	assert.Contains(t, throttle.hosts, "10.0.0.3")
+}
+
+func TestHostOf(t *testing.T) {
+	assert.Equal(t, "10.0.0.1", hostOf(addr("10.0.0.1", 25565)))
+	assert.Equal(t, "::1", hostOf(addr("::1", 25565)))
+	pipe, _ := net.Pipe()
+	assert.Equal(t, "pipe", hostOf(pipe.RemoteAddr()))
+}